@@ -0,0 +1,282 @@
+// This file contains the chart widgets of the dashboard. The charts are drawn with
+// Fyne canvas primitives (rectangles, lines and texts) so no extra dependency is needed.
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"image/color"
+	. "project/main/stats"
+	"strconv"
+)
+
+var weekdayNames = []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+/*
+chartRenderer is the renderer shared by all charts. The canvas objects are
+
+recreated by draw every time the chart is laid out or refreshed.
+*/
+type chartRenderer struct {
+	chart   fyne.Widget
+	draw    func(size fyne.Size) []fyne.CanvasObject
+	minSize fyne.Size
+	objects []fyne.CanvasObject
+}
+
+func (r *chartRenderer) Layout(size fyne.Size) {
+	r.objects = r.draw(size)
+}
+
+func (r *chartRenderer) MinSize() fyne.Size {
+	return r.minSize
+}
+
+func (r *chartRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *chartRenderer) Refresh() {
+	r.Layout(r.chart.Size())
+	canvas.Refresh(r.chart)
+}
+
+func (r *chartRenderer) Destroy() {}
+
+// barChart is a horizontal bar chart with one labelled bar per count
+type barChart struct {
+	widget.BaseWidget
+	title  string
+	counts []Count
+}
+
+func newBarChart(title string) *barChart {
+	chart := &barChart{title: title}
+	chart.ExtendBaseWidget(chart)
+	return chart
+}
+
+// setCounts replaces the bars of the chart
+func (b *barChart) setCounts(counts []Count) {
+	b.counts = counts
+	b.Refresh()
+}
+
+func (b *barChart) CreateRenderer() fyne.WidgetRenderer {
+	return &chartRenderer{chart: b, draw: b.draw, minSize: fyne.NewSize(300, 200)}
+}
+
+func (b *barChart) draw(size fyne.Size) []fyne.CanvasObject {
+	title := chartTitle(b.title)
+	objects := []fyne.CanvasObject{title}
+	if len(b.counts) == 0 {
+		return append(objects, emptyChartText(size))
+	}
+
+	top := title.MinSize().Height + theme.Padding()
+	rowHeight := (size.Height - top) / float32(len(b.counts))
+	labelWidth := size.Width * 0.35
+	valueWidth := float32(40)
+	barSpace := size.Width - labelWidth - valueWidth - 2*theme.Padding()
+	max := b.counts[0].Value
+	for _, count := range b.counts {
+		if count.Value > max {
+			max = count.Value
+		}
+	}
+
+	for i, count := range b.counts {
+		y := top + float32(i)*rowHeight
+
+		label := canvas.NewText(count.Key, theme.ForegroundColor())
+		label.TextSize = theme.CaptionTextSize()
+		label.Move(fyne.NewPos(0, y))
+		label.Resize(fyne.NewSize(labelWidth, rowHeight))
+
+		bar := canvas.NewRectangle(theme.PrimaryColor())
+		bar.Move(fyne.NewPos(labelWidth+theme.Padding(), y+rowHeight*0.15))
+		bar.Resize(fyne.NewSize(barSpace*float32(count.Value)/float32(max), rowHeight*0.7))
+
+		value := canvas.NewText(strconv.Itoa(count.Value), theme.ForegroundColor())
+		value.TextSize = theme.CaptionTextSize()
+		value.Move(fyne.NewPos(bar.Position().X+bar.Size().Width+theme.Padding(), y))
+		value.Resize(fyne.NewSize(valueWidth, rowHeight))
+
+		objects = append(objects, label, bar, value)
+	}
+	return objects
+}
+
+// lineChart is a time series with one point per count, the keys are used as x axis labels
+type lineChart struct {
+	widget.BaseWidget
+	title  string
+	counts []Count
+}
+
+func newLineChart(title string) *lineChart {
+	chart := &lineChart{title: title}
+	chart.ExtendBaseWidget(chart)
+	return chart
+}
+
+// setCounts replaces the points of the chart
+func (l *lineChart) setCounts(counts []Count) {
+	l.counts = counts
+	l.Refresh()
+}
+
+func (l *lineChart) CreateRenderer() fyne.WidgetRenderer {
+	return &chartRenderer{chart: l, draw: l.draw, minSize: fyne.NewSize(300, 200)}
+}
+
+func (l *lineChart) draw(size fyne.Size) []fyne.CanvasObject {
+	title := chartTitle(l.title)
+	objects := []fyne.CanvasObject{title}
+	if len(l.counts) == 0 {
+		return append(objects, emptyChartText(size))
+	}
+
+	axisHeight := theme.CaptionTextSize() + theme.Padding()
+	top := title.MinSize().Height + theme.Padding()
+	plotHeight := size.Height - top - axisHeight
+	max := 1
+	for _, count := range l.counts {
+		if count.Value > max {
+			max = count.Value
+		}
+	}
+
+	// Position of point i in the plot area
+	point := func(i int) fyne.Position {
+		x := size.Width / 2
+		if len(l.counts) > 1 {
+			x = size.Width * float32(i) / float32(len(l.counts)-1)
+		}
+		y := top + plotHeight*(1-float32(l.counts[i].Value)/float32(max))
+		return fyne.NewPos(x, y)
+	}
+
+	axis := canvas.NewLine(theme.DisabledColor())
+	axis.Position1 = fyne.NewPos(0, top+plotHeight)
+	axis.Position2 = fyne.NewPos(size.Width, top+plotHeight)
+	maxLabel := canvas.NewText(strconv.Itoa(max), theme.DisabledColor())
+	maxLabel.TextSize = theme.CaptionTextSize()
+	maxLabel.Move(fyne.NewPos(0, top))
+	objects = append(objects, axis, maxLabel)
+
+	for i := 1; i < len(l.counts); i++ {
+		segment := canvas.NewLine(theme.PrimaryColor())
+		segment.StrokeWidth = 2
+		segment.Position1 = point(i - 1)
+		segment.Position2 = point(i)
+		objects = append(objects, segment)
+	}
+	for i := range l.counts {
+		dot := canvas.NewCircle(theme.PrimaryColor())
+		dot.Move(point(i).Subtract(fyne.NewPos(3, 3)))
+		dot.Resize(fyne.NewSize(6, 6))
+		objects = append(objects, dot)
+	}
+
+	first := canvas.NewText(l.counts[0].Key, theme.ForegroundColor())
+	first.TextSize = theme.CaptionTextSize()
+	first.Move(fyne.NewPos(0, top+plotHeight))
+	last := canvas.NewText(l.counts[len(l.counts)-1].Key, theme.ForegroundColor())
+	last.TextSize = theme.CaptionTextSize()
+	last.Alignment = fyne.TextAlignTrailing
+	last.Move(fyne.NewPos(0, top+plotHeight))
+	last.Resize(fyne.NewSize(size.Width, axisHeight))
+	return append(objects, first, last)
+}
+
+// heatmap shows the number of events per weekday (rows) and hour of day (columns)
+type heatmap struct {
+	widget.BaseWidget
+	title string
+	grid  [7][24]int
+}
+
+func newHeatmap(title string) *heatmap {
+	chart := &heatmap{title: title}
+	chart.ExtendBaseWidget(chart)
+	return chart
+}
+
+// setGrid replaces the cells of the heatmap
+func (h *heatmap) setGrid(grid [7][24]int) {
+	h.grid = grid
+	h.Refresh()
+}
+
+func (h *heatmap) CreateRenderer() fyne.WidgetRenderer {
+	return &chartRenderer{chart: h, draw: h.draw, minSize: fyne.NewSize(400, 200)}
+}
+
+func (h *heatmap) draw(size fyne.Size) []fyne.CanvasObject {
+	title := chartTitle(h.title)
+	objects := []fyne.CanvasObject{title}
+
+	labelWidth := float32(40)
+	top := title.MinSize().Height + theme.Padding()
+	axisHeight := theme.CaptionTextSize() + theme.Padding()
+	cellWidth := (size.Width - labelWidth) / 24
+	cellHeight := (size.Height - top - axisHeight) / 7
+	max := 0
+	for _, row := range h.grid {
+		for _, value := range row {
+			if value > max {
+				max = value
+			}
+		}
+	}
+
+	for day, row := range h.grid {
+		label := canvas.NewText(weekdayNames[day], theme.ForegroundColor())
+		label.TextSize = theme.CaptionTextSize()
+		label.Move(fyne.NewPos(0, top+float32(day)*cellHeight))
+		objects = append(objects, label)
+
+		for hour, value := range row {
+			intensity := float32(0)
+			if max > 0 {
+				intensity = float32(value) / float32(max)
+			}
+			cell := canvas.NewRectangle(withAlpha(theme.PrimaryColor(), 0.08+0.92*intensity))
+			cell.Move(fyne.NewPos(labelWidth+float32(hour)*cellWidth, top+float32(day)*cellHeight))
+			cell.Resize(fyne.NewSize(cellWidth-1, cellHeight-1))
+			objects = append(objects, cell)
+		}
+	}
+	for hour := 0; hour < 24; hour += 3 {
+		label := canvas.NewText(strconv.Itoa(hour), theme.ForegroundColor())
+		label.TextSize = theme.CaptionTextSize()
+		label.Move(fyne.NewPos(labelWidth+float32(hour)*cellWidth, top+7*cellHeight))
+		objects = append(objects, label)
+	}
+	return objects
+}
+
+// chartTitle creates the bold heading that is drawn above every chart
+func chartTitle(text string) *canvas.Text {
+	title := canvas.NewText(text, theme.ForegroundColor())
+	title.TextStyle = fyne.TextStyle{Bold: true}
+	return title
+}
+
+// emptyChartText creates the centered message that is drawn when a chart has no data
+func emptyChartText(size fyne.Size) *canvas.Text {
+	text := canvas.NewText("No events match the filter", theme.DisabledColor())
+	text.Alignment = fyne.TextAlignCenter
+	text.Move(fyne.NewPos(0, size.Height/2))
+	text.Resize(fyne.NewSize(size.Width, theme.TextSize()))
+	return text
+}
+
+// withAlpha returns c with its opacity replaced by alpha, a value between 0 and 1
+func withAlpha(c color.Color, alpha float32) color.Color {
+	r, g, b, _ := c.RGBA()
+	return color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(alpha * 255)}
+}
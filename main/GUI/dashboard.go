@@ -0,0 +1,113 @@
+// This file contains the dashboard tab of the main window, which shows charts
+// of the events that match the currently active filter
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"log"
+	"project/main/digest"
+	. "project/main/event"
+	. "project/main/stats"
+	"strconv"
+)
+
+// Number of bars shown in the type and location charts
+const dashboardTopCount = 10
+
+// summaries caches the scraped extended summaries, it is nil if the cache can not be read
+var summaries = openSummaryCache()
+
+// dashboard holds the widgets of the dashboard tab so they can be updated when the filter changes,
+// the map of the events is shown in a tab of its own but follows the same filter
+type dashboard struct {
+	content     fyne.CanvasObject
+	eventMap    *eventMap
+	events      []Event
+	filter      Filter
+	digests     digest.Mode
+	filterLabel *widget.Label
+	anomalies   *widget.Label
+	categories  *barChart
+	types       *barChart
+	locations   *barChart
+	perDay      *lineChart
+	hours       *heatmap
+}
+
+// newDashboard creates the dashboard tab, onClear is called when the user clears the active filter
+func newDashboard(onClear func()) *dashboard {
+	d := &dashboard{
+		filterLabel: widget.NewLabel(""),
+		anomalies:   widget.NewLabel(""),
+		eventMap:    newEventMap("Events and hotspots"),
+		categories:  newBarChart("Categories"),
+		types:       newBarChart("Top types"),
+		locations:   newBarChart("Top locations"),
+		perDay:      newLineChart("Events per day"),
+		hours:       newHeatmap("Events per weekday and hour"),
+	}
+	clearButton := widget.NewButton("Clear filter", onClear)
+	header := container.NewHBox(d.filterLabel, clearButton)
+	d.digests = digest.Include
+	if summaries != nil {
+		// The digests are split with the summaries that have been scraped, see the scrape button of an event
+		modes := make([]string, len(digest.Modes))
+		for i, mode := range digest.Modes {
+			modes[i] = string(mode)
+		}
+		digestSelect := widget.NewSelect(modes, func(selected string) {
+			d.digests = digest.Mode(selected)
+			d.update(d.events, d.filter)
+		})
+		digestSelect.SetSelected(string(d.digests))
+		header.Add(widget.NewLabel("Digests:"))
+		header.Add(digestSelect)
+	}
+	charts := container.NewGridWithRows(2,
+		container.NewGridWithColumns(3, d.categories, d.types, d.locations),
+		container.NewGridWithColumns(2, d.perDay, d.hours))
+	d.anomalies.Wrapping = fyne.TextWrapWord
+	d.content = container.NewBorder(container.NewVBox(header, d.anomalies), nil, nil, nil, charts)
+	return d
+}
+
+// update redraws every chart from the events that match the filter, merged duplicates are left out
+func (d *dashboard) update(events []Event, filter Filter) {
+	d.events, d.filter = events, filter
+	if duplicateDecisions != nil {
+		events = duplicateDecisions.RemoveMerged(events)
+	}
+	if d.digests != digest.Include {
+		var err error
+		if events, err = digest.Apply(events, d.digests, summaries, false); err != nil {
+			log.Println(err)
+		}
+	}
+	filtered := filter.Apply(events)
+	d.filterLabel.SetText("Filter: " + filter.String() + " (" + strconv.Itoa(len(filtered)) + " events)")
+	d.categories.setCounts(CountByCategory(filtered))
+	d.types.setCounts(Top(CountByType(filtered), dashboardTopCount))
+	d.locations.setCounts(Top(CountByLocation(filtered), dashboardTopCount))
+	d.perDay.setCounts(PerDay(filtered))
+	d.hours.setGrid(HourWeekday(filtered))
+	d.eventMap.setEvents(filtered)
+	d.anomalies.SetText(describeAnomalies(filtered))
+}
+
+// describeAnomalies lists the anomalous (location, type) pairs of the last day of the events
+func describeAnomalies(events []Event) string {
+	anomalies := DetectAnomalies(events, Newest(events), DefaultAnomalyOptions)
+	if len(anomalies) == 0 {
+		return "Anomalies in the last day: none"
+	}
+	text := "Anomalies in the last day:"
+	for i, anomaly := range anomalies {
+		if i == dashboardTopCount {
+			break
+		}
+		text += "\n" + anomaly.String()
+	}
+	return text
+}
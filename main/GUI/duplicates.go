@@ -0,0 +1,86 @@
+// This file contains the dialog where the likely duplicate events are reviewed
+package gui
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"log"
+	"project/main/duplicate"
+	. "project/main/event"
+)
+
+// duplicateDecisions holds the reviewed pairs of duplicates, it is nil if they can not be read
+var duplicateDecisions = openDecisions()
+
+func openDecisions() *duplicate.Decisions {
+	decisions, err := duplicate.LoadDecisions(duplicate.DecisionsPath)
+	if err != nil {
+		log.Println("The reviewed duplicates could not be read:", err)
+		return nil
+	}
+	return decisions
+}
+
+// reviewDuplicates shows the likely duplicates that have not been reviewed one pair at a time. A pair
+// is merged, dismissed or skipped, onReviewed is called after a pair has been merged or dismissed.
+func reviewDuplicates(window fyne.Window, events []Event, onReviewed func()) {
+	if duplicateDecisions == nil {
+		dialog.ShowInformation("Duplicates", "The reviewed duplicates could not be read", window)
+		return
+	}
+	pending := duplicateDecisions.Pending(duplicate.Detect(events, duplicate.DefaultOptions))
+	if len(pending) == 0 {
+		dialog.ShowInformation("Duplicates", "There are no likely duplicates to review", window)
+		return
+	}
+
+	position := 0
+	progress := widget.NewLabel("")
+	first := widget.NewLabel("")
+	first.Wrapping = fyne.TextWrapWord
+	second := widget.NewLabel("")
+	second.Wrapping = fyne.TextWrapWord
+
+	var review dialog.Dialog
+	show := func() {
+		if position >= len(pending) {
+			review.Hide()
+			return
+		}
+		pair := pending[position]
+		progress.SetText(fmt.Sprintf("Pair %d of %d, %.0f%% similar. Merged events are left out of the statistics.",
+			position+1, len(pending), 100*pair.Similarity))
+		first.SetText(describeDuplicate(pair.A))
+		second.SetText(describeDuplicate(pair.B))
+	}
+	decide := func(decision duplicate.Decision) {
+		if err := duplicateDecisions.Set(pending[position], decision); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		onReviewed()
+		position++
+		show()
+	}
+
+	buttons := container.NewHBox(
+		widget.NewButton("Merge", func() { decide(duplicate.Merged) }),
+		widget.NewButton("Dismiss", func() { decide(duplicate.Dismissed) }),
+		widget.NewButton("Skip", func() {
+			position++
+			show()
+		}),
+	)
+	content := container.NewBorder(progress, buttons, nil, nil, container.NewGridWithColumns(2, first, second))
+	review = dialog.NewCustom("Review duplicates", "Close", content, window)
+	review.Resize(fyne.NewSize(700, 400))
+	show()
+	review.Show()
+}
+
+func describeDuplicate(event Event) string {
+	return fmt.Sprintf("ID: %d\n%s\n%s\n\n%s", event.Id, event.Datetime, event.Name, event.Summary)
+}
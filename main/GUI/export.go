@@ -0,0 +1,41 @@
+// This file contains the "Export" action of the main window, which saves the events
+// matching the active filter in one of the export formats
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	. "project/main/event"
+	"project/main/export"
+)
+
+// exportMenu creates a menu with one item per export format, an item opens a save dialog for the events
+func exportMenu(window fyne.Window, events func() []Event) *fyne.Menu {
+	var items []*fyne.MenuItem
+	for _, name := range export.FormatNames() {
+		format := export.Formats[name]
+		items = append(items, fyne.NewMenuItem(format.Name, func() {
+			saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+				if err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				// The dialog was cancelled
+				if writer == nil {
+					return
+				}
+				defer writer.Close()
+				data, err := format.Write(events())
+				if err == nil {
+					_, err = writer.Write(data)
+				}
+				if err != nil {
+					dialog.ShowError(err, window)
+				}
+			}, window)
+			saveDialog.SetFileName("events" + format.Extension)
+			saveDialog.Show()
+		}))
+	}
+	return fyne.NewMenu("Export", items...)
+}
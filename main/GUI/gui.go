@@ -4,13 +4,19 @@
 package gui
 
 import (
+	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"log"
+	"os"
+	"project/main/alert"
 	. "project/main/event"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -59,7 +65,12 @@ func mainWindow(app fyne.App) {
 	mainWindow.Resize(fyne.NewSize(1200, 700))
 	mainWindow.CenterOnScreen()
 
-	allEvents := AllEventsSlice()
+	// Without the API the archive is still shown, the locations are those of the shown events
+	allEvents, err := AllEvents()
+	if err != nil {
+		log.Println("An error occurred while fetching events from the API, showing the archive:", err)
+	}
+	locationKeys := GetLocationKeys(allEvents)
 	allEventsList := eventListView(allEvents)
 
 	/*
@@ -77,6 +88,18 @@ func mainWindow(app fyne.App) {
 	displayEventInfo := container.NewVBox(eventInfo, extensiveSummary, openInBrowserButton, scrapeBrowserButton)
 	allEventsList.OnSelected = eventOnSelection(allEvents, eventInfo, extensiveSummary, openInBrowserButton, scrapeBrowserButton)
 
+	/*
+		The dashboard shows charts of the events matching the active filter, the filter is
+		set by the type and location searches and cleared from the dashboard
+	*/
+	var activeFilter Filter
+	var eventsDashboard *dashboard
+	eventsDashboard = newDashboard(func() {
+		activeFilter = Filter{}
+		eventsDashboard.update(allEvents, activeFilter)
+	})
+	eventsDashboard.update(allEvents, activeFilter)
+
 	///////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 	/*
 		Segment holds script for the submenu "Type" under "Search" toolbar option
@@ -131,6 +154,8 @@ func mainWindow(app fyne.App) {
 		} else {
 			typeKey = filteredTypeOptions[id]
 		}
+		activeFilter.Type = typeKey
+		eventsDashboard.update(allEvents, activeFilter)
 		// Creating and editing the window that pops up when a type has been chosen
 		subCatWindow := app.NewWindow(typeKey)
 		subCatWindow.Resize(fyne.NewSize(400, 400))
@@ -153,11 +178,48 @@ func mainWindow(app fyne.App) {
 
 	///////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+	/*
+		Segment holds script for the submenu "Unknown types" under "Search" toolbar option, it lists the
+		types of the events that are missing from TypeKeys together with when they were first and last seen
+	*/
+
+	unknownTypes := Unknown(TypeUsage(allEvents))
+	unknownTypeLabels := make([]string, len(unknownTypes))
+	for i, usage := range unknownTypes {
+		unknownTypeLabels[i] = usage.Key + " (" + strconv.Itoa(usage.Count) + ", " +
+			usage.FirstSeen.Format("2006-01-02") + " – " + usage.LastSeen.Format("2006-01-02") + ")"
+	}
+	unknownTypeOptions := keysListview(unknownTypeLabels)
+	unknownTypePopUp := widget.NewPopUp(unknownTypeOptions, mainWindow.Canvas())
+	unknownTypePopUp.Resize(fyne.NewSize(350, 250))
+	unknownTypeOptions.OnSelected = func(id widget.ListItemID) {
+		unknownTypePopUp.Hide()
+		typeKey := unknownTypes[id].Key
+		activeFilter.Type = typeKey
+		eventsDashboard.update(allEvents, activeFilter)
+
+		subCatWindow := app.NewWindow(typeKey)
+		subCatWindow.Resize(fyne.NewSize(400, 400))
+		subCatWindow.CenterOnScreen()
+		subCatEvents := SubCatType(allEvents, typeKey)
+		subCatEventsListView := eventListView(subCatEvents)
+		subCatEventsListView.OnSelected = eventOnSelection(subCatEvents, eventInfo, extensiveSummary, openInBrowserButton, scrapeBrowserButton)
+		subCatWindow.SetContent(subCatEventsListView)
+		subCatWindow.Show()
+	}
+	unknownTypeSearch := fyne.NewMenuItem("Unknown types", func() {
+		unknownTypeOptions.UnselectAll()
+		unknownTypePopUp.Show()
+	})
+	unknownTypeSearch.Disabled = len(unknownTypes) == 0
+
+	///////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 	/*
 		Segment holds script for the submenu "Location" under "Search" toolbar option, identical to type search menu see earlier segemt
 	*/
 
-	locationMenuOptions := keysListview(LocationKeys)
+	locationMenuOptions := keysListview(locationKeys)
 	filteredLocationOptions := make([]string, 0)
 
 	// Entry widget for search query
@@ -170,10 +232,10 @@ func mainWindow(app fyne.App) {
 
 		// If search query is empty, show all items
 		if query == "" {
-			filteredLocationElements = LocationKeys
+			filteredLocationElements = locationKeys
 		} else {
 			// Filter items based on search query
-			for _, item := range LocationKeys {
+			for _, item := range locationKeys {
 				if containsIgnoreCase(item, query) {
 					filteredLocationElements = append(filteredLocationElements, item)
 				}
@@ -197,15 +259,17 @@ func mainWindow(app fyne.App) {
 	locationMenuOptions.OnSelected = func(id widget.ListItemID) {
 		var locationKey string
 		if len(filteredLocationOptions) == 0 {
-			locationKey = LocationKeys[id]
+			locationKey = locationKeys[id]
 		} else {
 			locationKey = filteredLocationOptions[id]
 		}
+		activeFilter.Location = locationKey
+		eventsDashboard.update(allEvents, activeFilter)
 		// Creating and editing the window that pops up when a type has been chosen
 		subCatWindow := app.NewWindow(locationKey)
 		subCatWindow.Resize(fyne.NewSize(400, 400))
 		subCatWindow.CenterOnScreen()
-		subCatEvents := SubCatLocation(AllEventsSlice(), locationKey)
+		subCatEvents := SubCatLocation(allEvents, locationKey)
 		subCatEventsListView := eventListView(subCatEvents)
 		subCatEventsListView.OnSelected = eventOnSelection(subCatEvents, eventInfo, extensiveSummary, openInBrowserButton, scrapeBrowserButton)
 		subCatWindow.SetContent(subCatEventsListView)
@@ -223,24 +287,124 @@ func mainWindow(app fyne.App) {
 
 	///////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
-	searchMenu := fyne.NewMenu("Search", typeSearch, locationSearch)
-	searchMenuPopUp := widget.NewPopUpMenu(searchMenu, mainWindow.Canvas())
+	/*
+		Segment holds script for the submenu "Near point" under "Search" toolbar option, the point is
+		either lat,lon or the name of a kommun or län, so "near me" is the user's own kommun
+	*/
+
+	nearPointEntry := widget.NewEntry()
+	nearPointEntry.SetPlaceHolder("lat,lon or kommun")
+	nearRadiusEntry := widget.NewEntry()
+	nearRadiusEntry.SetText("5km")
+	nearErrorLabel := widget.NewLabel("")
+	var nearPopUp *widget.PopUp
+	nearSearchButton := widget.NewButton("Search", func() {
+		circle, err := ParseCircle(nearPointEntry.Text, nearRadiusEntry.Text)
+		if err != nil {
+			nearErrorLabel.SetText(err.Error())
+			return
+		}
+		nearErrorLabel.SetText("")
+		nearPopUp.Hide()
+		activeFilter.Near = circle
+		eventsDashboard.update(allEvents, activeFilter)
+
+		nearFilter := Filter{Near: circle}
+		subCatWindow := app.NewWindow(nearFilter.String())
+		subCatWindow.Resize(fyne.NewSize(400, 400))
+		subCatWindow.CenterOnScreen()
+		subCatEvents := nearFilter.Apply(allEvents)
+		subCatEventsListView := eventListView(subCatEvents)
+		subCatEventsListView.OnSelected = eventOnSelection(subCatEvents, eventInfo, extensiveSummary, openInBrowserButton, scrapeBrowserButton)
+		subCatWindow.SetContent(subCatEventsListView)
+		subCatWindow.Show()
+	})
+	nearPopUp = widget.NewPopUp(container.NewVBox(
+		widget.NewLabel("Point"), nearPointEntry,
+		widget.NewLabel("Radius"), nearRadiusEntry,
+		nearSearchButton, nearErrorLabel), mainWindow.Canvas())
+	nearPopUp.Resize(fyne.NewSize(250, 250))
+	nearSearch := fyne.NewMenuItem("Near point", func() {
+		nearPopUp.Show()
+	})
+
+	///////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+	/*
+		Segment holds script for the submenu "Category" under "Search" toolbar option, the categories
+		and the types they contain are read from the taxonomy
+	*/
 
-	saveButton := widget.NewButton("Save", func() {
-		SaveInArchive(AllEventsSlice())
+	categories := GetTaxonomy().Categories
+	categoryLabels := make([]string, len(categories))
+	for i, category := range categories {
+		categoryLabels[i] = category.Label
+	}
+	categoryMenuOptions := keysListview(categoryLabels)
+	categoryMenuOptionsPopUp := widget.NewPopUp(categoryMenuOptions, mainWindow.Canvas())
+	categoryMenuOptionsPopUp.Resize(fyne.NewSize(250, 250))
+	categoryMenuOptions.OnSelected = func(id widget.ListItemID) {
+		categoryMenuOptionsPopUp.Hide()
+		activeFilter.Category = categories[id].ID
+		eventsDashboard.update(allEvents, activeFilter)
+
+		categoryFilter := Filter{Category: categories[id].ID}
+		subCatWindow := app.NewWindow(categories[id].Label)
+		subCatWindow.Resize(fyne.NewSize(400, 400))
+		subCatWindow.CenterOnScreen()
+		subCatEvents := categoryFilter.Apply(allEvents)
+		subCatEventsListView := eventListView(subCatEvents)
+		subCatEventsListView.OnSelected = eventOnSelection(subCatEvents, eventInfo, extensiveSummary, openInBrowserButton, scrapeBrowserButton)
+		subCatWindow.SetContent(subCatEventsListView)
+		subCatWindow.Show()
+	}
+	categorySearch := fyne.NewMenuItem("Category", func() {
+		categoryMenuOptions.UnselectAll()
+		categoryMenuOptionsPopUp.Show()
+	})
+
+	///////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+	searchMenu := fyne.NewMenu("Search", typeSearch, unknownTypeSearch, categorySearch, locationSearch, nearSearch)
+	searchMenuPopUp := widget.NewPopUpMenu(searchMenu, mainWindow.Canvas())
+
+	alertEngine := newAlertEngine(app)
+	if alertEngine != nil {
+		// Deliver the queued alerts while the application runs, as the watch command does before every poll
+		go func() {
+			for range time.Tick(time.Minute) {
+				alertEngine.Retry()
+			}
+		}()
+	}
+	saveButton := widget.NewButton("Save", nil)
+	saveButton.OnTapped = func() {
 		notificationMessage := widget.NewLabel("Updating archive...")
 		saveNotification := widget.NewPopUp(notificationMessage, mainWindow.Canvas())
 		saveNotification.Show()
-		select {
-		case <-time.After(2 * time.Second):
-		}
-		notificationMessage.SetText("Archive has been updated")
-		select {
-		case <-time.After(2 * time.Second):
-		}
-		saveNotification.Hide()
-	})
+		saveButton.Disable()
+		// Fetching, scraping facts and evaluating the alerts take a while, so they are done outside the
+		// event handler. The widgets and dialogs of Fyne 2.3 are safe to update from this goroutine,
+		// their changes are drawn by the render loop.
+		go func() {
+			defer saveButton.Enable()
+			eventsToSave, archived, err := eventsToArchive()
+			if err != nil {
+				saveNotification.Hide()
+				dialog.ShowError(err, mainWindow)
+				return
+			}
+			EnrichFacts(eventsToSave, summaries)
+			if alertEngine != nil {
+				alertEngine.Evaluate(NewEvents(archived, eventsToSave))
+				alertEngine.EvaluateAnomalies(eventsToSave)
+			}
+			SaveInArchive(eventsToSave)
+			notificationMessage.SetText("Archive has been updated")
+			time.Sleep(2 * time.Second)
+			saveNotification.Hide()
+		}()
+	}
 
 	saveMessage := widget.NewLabel("Click 'Save' to update current archive")
 	savePopUpContainer := container.NewVBox(saveMessage, saveButton)
@@ -290,6 +454,11 @@ func mainWindow(app fyne.App) {
 	settingsMenu := fyne.NewMenu("Setting", themeOption, sizeOption)
 	settingsMenuPopUp := widget.NewPopUpMenu(settingsMenu, mainWindow.Canvas())
 
+	// Exports the events matching the active filter
+	exportMenuPopUp := widget.NewPopUpMenu(exportMenu(mainWindow, func() []Event {
+		return activeFilter.Apply(allEvents)
+	}), mainWindow.Canvas())
+
 	verticalToolbar := widget.NewToolbar(
 		widget.NewToolbarAction(theme.SearchIcon(), func() {
 			searchMenuPopUp.Show()
@@ -298,19 +467,66 @@ func mainWindow(app fyne.App) {
 			savePopUp.Resize(fyne.NewSize(200, 100))
 			savePopUp.Show()
 		}),
+		widget.NewToolbarAction(theme.DownloadIcon(), func() {
+			exportMenuPopUp.Show()
+		}),
+		widget.NewToolbarAction(theme.ContentCopyIcon(), func() {
+			reviewDuplicates(mainWindow, allEvents, func() {
+				eventsDashboard.update(allEvents, activeFilter)
+			})
+		}),
 		widget.NewToolbarAction(theme.SettingsIcon(), func() {
 			settingsMenuPopUp.Show()
 		}),
 	)
 
 	eventsListAndInfoDisplay := container.NewHSplit(allEventsList, container.NewMax(displayEventInfo))
-	mainWindowContainer := container.NewVSplit(verticalToolbar, eventsListAndInfoDisplay)
+	tabs := container.NewAppTabs(
+		container.NewTabItem("Events", eventsListAndInfoDisplay),
+		container.NewTabItem("Dashboard", eventsDashboard.content),
+		container.NewTabItem("Map", eventsDashboard.eventMap),
+		container.NewTabItem("Incidents", incidentsView(allEvents)),
+	)
+	mainWindowContainer := container.NewVSplit(verticalToolbar, tabs)
 	mainWindowContainer.SetOffset(0.05)
 
 	mainWindow.SetContent(mainWindowContainer)
 	mainWindow.Show()
 }
 
+// eventsToArchive returns the archived events merged with the events fetched from the API, sorted by
+// datetime, and the archived events. Unlike AllEventsSlice it returns the errors instead of exiting.
+func eventsToArchive() (merged []Event, archived []Event, err error) {
+	archived, err = LoadArchive()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("the archive could not be read: %w", err)
+	}
+	fetched, err := FetchNewEvents()
+	if err != nil {
+		return nil, nil, fmt.Errorf("the events could not be fetched from the API, the archive is not updated: %w", err)
+	}
+	merged, _ = MergeEvents(archived, fetched)
+	sort.Sort(ByDatetime(merged))
+	return merged, archived, nil
+}
+
+// newAlertEngine loads the alert rules, alerts are disabled if the configuration is missing or invalid
+func newAlertEngine(app fyne.App) *alert.Engine {
+	config, err := alert.LoadConfig(alert.DefaultConfigPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("Alerts are disabled:", err)
+		}
+		return nil
+	}
+	engine, err := alert.NewEngine(config, app)
+	if err != nil {
+		log.Println("Alerts are disabled:", err)
+		return nil
+	}
+	return engine
+}
+
 /*
 	By providing a slice of valid searchKeys the method provides a listview of the keys
 
@@ -340,9 +556,16 @@ to the program
 func eventOnSelection(events []Event, eventInfo *widget.Label, extensiveSummary *widget.Label, openInBrowserButton *widget.Button, scrapeBrowserButton *widget.Button) func(id widget.ListItemID) {
 	return func(id widget.ListItemID) {
 		info := "ID: " + strconv.Itoa(events[id].Id) +
-			"\nLocation: " + events[id].Location.Name +
-			"\nType: " + events[id].Type +
+			"\nLocation: " + events[id].Location.Name + " (" + events[id].Region().String() + ")" +
+			"\nType: " + events[id].Type + " (" + events[id].Category().Label + ", severity " + strconv.Itoa(events[id].Severity()) + ")" +
 			"\nSummary: " + events[id].Summary
+		if facts := events[id].ExtractedFacts().String(); facts != "" {
+			info += "\nFacts: " + facts
+		}
+		if events[id].LocationMismatch() {
+			located, _ := events[id].LocatedRegion()
+			info += "\nNote: the coordinates of the event are in " + located.String()
+		}
 		eventInfo.SetText(info)
 		extensiveSummary.SetText("")
 		openInBrowserButton.Show()
@@ -351,11 +574,30 @@ func eventOnSelection(events []Event, eventInfo *widget.Label, extensiveSummary
 		}
 		scrapeBrowserButton.Show()
 		scrapeBrowserButton.OnTapped = func() {
-			extensiveSummary.SetText(GetExtendedSummary(events[id].Url))
+			if summaries == nil {
+				extensiveSummary.SetText(GetExtendedSummary(events[id].Url))
+				return
+			}
+			summary, err := summaries.Fetch(events[id])
+			if err != nil {
+				extensiveSummary.SetText("The summary could not be scraped: " + err.Error())
+				return
+			}
+			extensiveSummary.SetText(summary)
 		}
 	}
 }
 
+// openSummaryCache opens the cache of scraped summaries, the GUI scrapes without a cache if it can not be read
+func openSummaryCache() *SummaryCache {
+	cache, err := OpenSummaryCache(SummaryCachePath)
+	if err != nil {
+		log.Println("The cached summaries could not be read:", err)
+		return nil
+	}
+	return cache
+}
+
 // EventListView creates and returns a Fyne List widget that displays the names of the given events.
 func eventListView(events []Event) *widget.List {
 	eventsList := widget.NewList(
@@ -0,0 +1,73 @@
+// This file contains the incidents tab of the main window, which lists the incidents that
+// consist of more than one event and shows the timeline of the selected incident
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	. "project/main/event"
+	"project/main/incident"
+)
+
+// incidentsView links the events into incidents and creates the content of the incidents tab
+func incidentsView(events []Event) fyne.CanvasObject {
+	incidents := incident.Linked(incident.Link(events, incident.DefaultOptions))
+	// Newest incident first, like the list of events
+	for i, j := 0, len(incidents)-1; i < j; i, j = i+1, j-1 {
+		incidents[i], incidents[j] = incidents[j], incidents[i]
+	}
+
+	incidentsList := widget.NewList(
+		func() int {
+			return len(incidents)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("Incident")
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			object.(*widget.Label).SetText(incidents[id].Start.Format("2006-01-02 15:04") + "  " + incidents[id].String())
+		},
+	)
+
+	eventInfo := widget.NewLabel("")
+	eventInfo.Wrapping = fyne.TextWrapWord
+	extensiveSummary := widget.NewLabel("")
+	extensiveSummary.Wrapping = fyne.TextWrapWord
+	openInBrowserButton := widget.NewButton("Open in browser", func() {})
+	openInBrowserButton.Hide()
+	scrapeBrowserButton := widget.NewButton("Scrape webpage for summary", func() {})
+	scrapeBrowserButton.Hide()
+	displayEventInfo := container.NewVBox(eventInfo, extensiveSummary, openInBrowserButton, scrapeBrowserButton)
+
+	var timeline []Event
+	timelineList := widget.NewList(
+		func() int {
+			return len(timeline)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("Event")
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			object.(*widget.Label).SetText(timeline[id].Time().Format("15:04") + "  " + timeline[id].Name)
+		},
+	)
+	incidentsList.OnSelected = func(id widget.ListItemID) {
+		timeline = incidents[id].Timeline()
+		timelineList.OnSelected = eventOnSelection(timeline, eventInfo, extensiveSummary, openInBrowserButton, scrapeBrowserButton)
+		timelineList.UnselectAll()
+		timelineList.Refresh()
+		eventInfo.SetText("Select an event of the timeline")
+		extensiveSummary.SetText("")
+		openInBrowserButton.Hide()
+		scrapeBrowserButton.Hide()
+	}
+
+	if len(incidents) == 0 {
+		eventInfo.SetText("No events have been linked into incidents")
+	} else {
+		eventInfo.SetText("Please select an incident")
+	}
+	timelineAndInfo := container.NewVSplit(timelineList, container.NewMax(displayEventInfo))
+	return container.NewHSplit(incidentsList, timelineAndInfo)
+}
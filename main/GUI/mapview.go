@@ -0,0 +1,115 @@
+// This file contains the map of the dashboard, the events are drawn at their coordinates
+// coloured by category, with the hotspots of the events as an overlay
+package gui
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"image/color"
+	"math"
+	. "project/main/event"
+	"project/main/geo"
+	. "project/main/stats"
+)
+
+// Area of Sweden that is drawn, in degrees
+const (
+	mapMinLat = 55.0
+	mapMaxLat = 69.2
+	mapMinLon = 10.8
+	mapMaxLon = 24.3
+)
+
+// The longitudes are scaled by the cosine of the middle latitude so the map is not stretched
+var mapLonScale = math.Cos((mapMinLat + mapMaxLat) / 2 * math.Pi / 180)
+
+// eventMap draws the events and their hotspots on a plain projection of Sweden
+type eventMap struct {
+	widget.BaseWidget
+	title    string
+	events   []Event
+	hotspots []Hotspot
+}
+
+func newEventMap(title string) *eventMap {
+	m := &eventMap{title: title}
+	m.ExtendBaseWidget(m)
+	return m
+}
+
+// setEvents replaces the events of the map and clusters them into hotspots
+func (m *eventMap) setEvents(events []Event) {
+	m.events = events
+	m.hotspots = Hotspots(events, DefaultHotspotOptions)
+	m.Refresh()
+}
+
+func (m *eventMap) CreateRenderer() fyne.WidgetRenderer {
+	return &chartRenderer{chart: m, draw: m.draw, minSize: fyne.NewSize(300, 400)}
+}
+
+func (m *eventMap) draw(size fyne.Size) []fyne.CanvasObject {
+	title := chartTitle(fmt.Sprintf("%s (%d hotspots)", m.title, len(m.hotspots)))
+	objects := []fyne.CanvasObject{title}
+	top := title.MinSize().Height + theme.Padding()
+
+	// Pixels per degree of latitude, as large as the widget allows
+	width := float32((mapMaxLon - mapMinLon) * mapLonScale)
+	height := float32(mapMaxLat - mapMinLat)
+	scale := float32(math.Min(float64(size.Width/width), float64((size.Height-top)/height)))
+	project := func(lat, lon float64) fyne.Position {
+		return fyne.NewPos(float32((lon-mapMinLon)*mapLonScale)*scale, top+float32(mapMaxLat-lat)*scale)
+	}
+
+	frame := canvas.NewRectangle(color.Transparent)
+	frame.StrokeColor = theme.DisabledColor()
+	frame.StrokeWidth = 1
+	frame.Move(fyne.NewPos(0, top))
+	frame.Resize(fyne.NewSize(width*scale, height*scale))
+	objects = append(objects, frame)
+
+	for _, county := range geo.Counties() {
+		label := canvas.NewText(county.Name, theme.DisabledColor())
+		label.TextSize = theme.CaptionTextSize() * 0.8
+		label.Move(project(county.Lat, county.Lon))
+		objects = append(objects, label)
+	}
+	for _, event := range m.events {
+		lat, lon, ok := event.Coordinates()
+		if !ok {
+			continue
+		}
+		dot := canvas.NewCircle(hexColor(event.Category().Color))
+		dot.Move(project(lat, lon).Subtract(fyne.NewPos(2, 2)))
+		dot.Resize(fyne.NewSize(4, 4))
+		objects = append(objects, dot)
+	}
+	for _, hotspot := range m.hotspots {
+		radius := float32(math.Max(hotspot.RadiusKm/111.32*float64(scale), 6))
+		overlay := canvas.NewCircle(color.NRGBA{R: 0xe0, G: 0x31, B: 0x31, A: 0x50})
+		overlay.StrokeColor = color.NRGBA{R: 0xe0, G: 0x31, B: 0x31, A: 0xff}
+		overlay.StrokeWidth = 1
+		overlay.Move(project(hotspot.Lat, hotspot.Lon).Subtract(fyne.NewPos(radius, radius)))
+		overlay.Resize(fyne.NewSize(2*radius, 2*radius))
+		count := canvas.NewText(fmt.Sprint(hotspot.Count()), theme.ForegroundColor())
+		count.TextSize = theme.CaptionTextSize()
+		count.Move(project(hotspot.Lat, hotspot.Lon).Add(fyne.NewPos(radius, -radius)))
+		objects = append(objects, overlay, count)
+	}
+	if len(m.events) == 0 {
+		objects = append(objects, emptyChartText(size))
+	}
+	return objects
+}
+
+// hexColor parses a colour written as #rrggbb, other text gives the primary colour of the theme
+func hexColor(text string) color.Color {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(text, "#%02x%02x%02x", &r, &g, &b); err != nil || len(text) != 7 {
+		return theme.PrimaryColor()
+	}
+	return color.NRGBA{R: r, G: g, B: b, A: 0xff}
+}
@@ -0,0 +1,281 @@
+package alert
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	. "project/main/event"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Security modes of the connection to the SMTP server
+const (
+	// SecurityStartTLS upgrades the connection with STARTTLS when the server offers it, this is the default
+	SecurityStartTLS = "starttls"
+	// SecurityRequireStartTLS fails the delivery when the server does not offer STARTTLS
+	SecurityRequireStartTLS = "require-starttls"
+	// SecurityTLS connects with TLS from the start, usually on port 465
+	SecurityTLS = "tls"
+	// SecurityNone never encrypts the connection, for local servers only
+	SecurityNone = "none"
+)
+
+// Recipient is an address that only receives the events matching its filter, an empty filter matches every event
+type Recipient struct {
+	Address string `json:"address"`
+	Filter  Filter `json:"filter"`
+}
+
+// EmailNotifier sends the matches of the rules as multipart mails with a plain text and an HTML version.
+// Every recipient gets the events that match its filter. With a batch window the matches are collected and
+// each recipient gets one mail per window, they are sent by Retry. Mails that could not be sent are retried
+// when a queue is set.
+type EmailNotifier struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	From       string
+	Recipients []Recipient
+	// Security is one of the Security constants
+	Security string
+	// TLSConfig is used for STARTTLS and TLS, the server name is the host if it is nil
+	TLSConfig *tls.Config
+	// Batch is how long the matches are collected before they are sent, 0 sends every match at once
+	Batch time.Duration
+	Queue *Queue
+
+	mu      sync.Mutex
+	pending []batchedMatch
+	now     func() time.Time
+}
+
+// batchedMatch is a match of a rule that waits for the batch to be sent
+type batchedMatch struct {
+	Rule    string
+	Events  []Event
+	Created time.Time
+}
+
+// queuedMail is the payload of a mail in the retry queue
+type queuedMail struct {
+	To      []string `json:"to"`
+	Message []byte   `json:"message"`
+}
+
+// newConfiguredEmail creates the email notifier described by the configuration, the addresses of To
+// receive every event and the recipients the events that match their filters
+func newConfiguredEmail(config NotifierConfig) (*EmailNotifier, error) {
+	if config.Host == "" || config.From == "" || (len(config.To) == 0 && len(config.Recipients) == 0) {
+		return nil, fmt.Errorf("notifier %q: email needs host, from and to or recipients", config.Name)
+	}
+	notifier := &EmailNotifier{Host: config.Host, Port: config.Port, Username: config.Username,
+		Password: config.Password, From: config.From, Security: config.Security}
+	switch config.Security {
+	case "", SecurityStartTLS, SecurityRequireStartTLS, SecurityTLS, SecurityNone:
+	default:
+		return nil, fmt.Errorf("notifier %q: unknown security %q", config.Name, config.Security)
+	}
+	for _, address := range config.To {
+		notifier.Recipients = append(notifier.Recipients, Recipient{Address: address})
+	}
+	for _, recipient := range config.Recipients {
+		if err := recipient.Filter.Validate(); err != nil {
+			return nil, fmt.Errorf("notifier %q: recipient %s: %w", config.Name, recipient.Address, err)
+		}
+		notifier.Recipients = append(notifier.Recipients, recipient)
+	}
+	if config.Batch != "" {
+		batch, err := time.ParseDuration(config.Batch)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %q: invalid batch %q", config.Name, config.Batch)
+		}
+		notifier.Batch = batch
+	}
+	queue, err := OpenQueue(config.Name)
+	if err != nil {
+		return nil, fmt.Errorf("notifier %q: %w", config.Name, err)
+	}
+	notifier.Queue = queue
+	return notifier, nil
+}
+
+func (n *EmailNotifier) Notify(rule Rule, events []Event) error {
+	match := batchedMatch{Rule: rule.Name, Events: events, Created: n.clock()}
+	if n.Batch == 0 {
+		return n.sendMatches([]batchedMatch{match})
+	}
+	n.mu.Lock()
+	n.pending = append(n.pending, match)
+	n.mu.Unlock()
+	return nil
+}
+
+// Retry sends the batch when the oldest match has waited for the batch window, and the queued mails that are due
+func (n *EmailNotifier) Retry() {
+	n.mu.Lock()
+	var due []batchedMatch
+	if len(n.pending) > 0 && n.clock().Sub(n.pending[0].Created) >= n.Batch {
+		due, n.pending = n.pending, nil
+	}
+	n.mu.Unlock()
+	if len(due) > 0 {
+		// The mails that failed are in the queue, so the error only needs to be reported
+		if err := n.sendMatches(due); err != nil {
+			log.Println("An error occurred while sending the batched alerts:", err)
+		}
+	}
+	if n.Queue != nil {
+		n.Queue.Retry(func(payload []byte) error {
+			var mail queuedMail
+			if err := json.Unmarshal(payload, &mail); err != nil {
+				return err
+			}
+			return n.deliver(mail.To, mail.Message)
+		})
+	}
+}
+
+// SendDocument sends the document to every recipient regardless of their filters
+func (n *EmailNotifier) SendDocument(subject string, text string, html string) error {
+	var to []string
+	for _, recipient := range n.Recipients {
+		to = append(to, recipient.Address)
+	}
+	message, err := buildMessage(n.From, to, subject, text, html, n.clock())
+	if err != nil {
+		return err
+	}
+	return n.send(to, message)
+}
+
+// sendMatches sends every recipient one mail with the events of the matches that pass its filter.
+// Recipients without any such event get no mail.
+func (n *EmailNotifier) sendMatches(matches []batchedMatch) error {
+	var failed error
+	for _, recipient := range n.Recipients {
+		var selected []batchedMatch
+		count := 0
+		for _, match := range matches {
+			events := recipient.Filter.Apply(match.Events)
+			if len(events) > 0 {
+				selected = append(selected, batchedMatch{Rule: match.Rule, Events: events})
+				count += len(events)
+			}
+		}
+		if len(selected) == 0 {
+			continue
+		}
+		subject := fmt.Sprintf("Alert: %s (%d events)", selected[0].Rule, count)
+		if len(selected) > 1 {
+			subject = fmt.Sprintf("Alerts: %d rules matched %d events", len(selected), count)
+		}
+		text, html, err := renderMatches(selected)
+		if err != nil {
+			return err
+		}
+		message, err := buildMessage(n.From, []string{recipient.Address}, subject, text, html, n.clock())
+		if err != nil {
+			return err
+		}
+		if err := n.send([]string{recipient.Address}, message); err != nil {
+			failed = err
+		}
+	}
+	return failed
+}
+
+// send delivers the message and queues it for retry if that fails
+func (n *EmailNotifier) send(to []string, message []byte) error {
+	err := n.deliver(to, message)
+	if err != nil && n.Queue != nil {
+		payload, marshalErr := json.Marshal(queuedMail{To: to, Message: message})
+		if marshalErr != nil {
+			return marshalErr
+		}
+		n.Queue.Add(payload, err)
+		return fmt.Errorf("%w, queued for retry", err)
+	}
+	return err
+}
+
+// deliver connects to the server, encrypts the connection as the security mode says, authenticates
+// if there is a username and sends the message
+func (n *EmailNotifier) deliver(to []string, message []byte) error {
+	port := n.Port
+	if port == 0 {
+		port = 25
+		if n.Security == SecurityTLS {
+			port = 465
+		}
+	}
+	tlsConfig := n.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: n.Host}
+	}
+	address := net.JoinHostPort(n.Host, strconv.Itoa(port))
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if n.Security == SecurityTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, n.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if n.Security != SecurityTLS && n.Security != SecurityNone {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		} else if n.Security == SecurityRequireStartTLS {
+			return fmt.Errorf("%s does not offer STARTTLS", address)
+		}
+	}
+	if n.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.Username, n.Password, n.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.From); err != nil {
+		return err
+	}
+	for _, address := range to {
+		if err := client.Rcpt(address); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (n *EmailNotifier) clock() time.Time {
+	if n.now == nil {
+		return time.Now()
+	}
+	return n.now()
+}
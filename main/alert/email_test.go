@@ -0,0 +1,444 @@
+package alert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	. "project/main/event"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receivedMail is a mail that the test SMTP server accepted
+type receivedMail struct {
+	From string
+	To   []string
+	Data []byte
+	// TLS and User tell whether the session was encrypted and who authenticated
+	TLS  bool
+	User string
+}
+
+// smtpServer is an in-process SMTP server that records the mails it accepts
+type smtpServer struct {
+	listener net.Listener
+	// startTLS is the configuration of STARTTLS, it is not offered if it is nil
+	startTLS *tls.Config
+	username string
+	password string
+
+	mu     sync.Mutex
+	mails  []receivedMail
+	reject int
+}
+
+// newSMTPServer starts a server on a free port of the loopback address, with STARTTLS if offerTLS is set.
+// The returned configuration trusts the certificate of the server.
+func newSMTPServer(t *testing.T, offerTLS bool) (*smtpServer, *tls.Config) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &smtpServer{listener: listener}
+	// The test server of net/http/httptest has a certificate for 127.0.0.1
+	certificates := httptest.NewUnstartedServer(nil)
+	certificates.StartTLS()
+	certificates.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(certificates.Certificate())
+	if offerTLS {
+		server.startTLS = &tls.Config{Certificates: certificates.TLS.Certificates}
+	}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server, &tls.Config{RootCAs: roots, ServerName: "127.0.0.1"}
+}
+
+// setReject makes the server answer MAIL with the code, 0 accepts the mails again
+func (s *smtpServer) setReject(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reject = code
+}
+
+func (s *smtpServer) received() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail(nil), s.mails...)
+}
+
+// port returns the port the server listens on
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.session(conn)
+	}
+}
+
+// session speaks the part of SMTP that net/smtp uses with one client
+func (s *smtpServer) session(conn net.Conn) {
+	defer func() { conn.Close() }()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost ESMTP test")
+	var mail receivedMail
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, argument, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"localhost", "AUTH PLAIN"}
+			if s.startTLS != nil && !mail.TLS {
+				lines = append(lines, "STARTTLS")
+			}
+			for i, l := range lines {
+				separator := "-"
+				if i == len(lines)-1 {
+					separator = " "
+				}
+				text.PrintfLine("250%s%s", separator, l)
+			}
+		case "STARTTLS":
+			if s.startTLS == nil {
+				text.PrintfLine("502 STARTTLS is not offered")
+				continue
+			}
+			text.PrintfLine("220 Ready to start TLS")
+			encrypted := tls.Server(conn, s.startTLS)
+			if err := encrypted.Handshake(); err != nil {
+				return
+			}
+			conn = encrypted
+			text = textproto.NewConn(conn)
+			mail = receivedMail{TLS: true}
+		case "AUTH":
+			mechanism, response, _ := strings.Cut(argument, " ")
+			decoded, err := base64.StdEncoding.DecodeString(response)
+			fields := strings.Split(string(decoded), "\x00")
+			if mechanism != "PLAIN" || err != nil || len(fields) != 3 || fields[1] != s.username || fields[2] != s.password {
+				text.PrintfLine("535 Authentication failed")
+				continue
+			}
+			mail.User = fields[1]
+			text.PrintfLine("235 Authenticated")
+		case "MAIL":
+			s.mu.Lock()
+			reject := s.reject
+			s.mu.Unlock()
+			if reject != 0 {
+				text.PrintfLine("%d Not now", reject)
+				continue
+			}
+			mail.From = strings.Trim(strings.TrimPrefix(argument, "FROM:"), "<>")
+			mail.To = nil
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			mail.To = append(mail.To, strings.Trim(strings.TrimPrefix(argument, "TO:"), "<>"))
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 Send the message")
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			mail.Data = data
+			s.mu.Lock()
+			s.mails = append(s.mails, mail)
+			s.mu.Unlock()
+			text.PrintfLine("250 Accepted")
+		case "RSET", "NOOP":
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			text.PrintfLine("502 Unknown command")
+		}
+	}
+}
+
+// testEmailNotifier returns a notifier that sends through the server to the recipients, with a queue in the test directory
+func testEmailNotifier(t *testing.T, server *smtpServer, recipients ...Recipient) *EmailNotifier {
+	t.Helper()
+	notifier, err := newConfiguredEmail(NotifierConfig{Name: "mail", Host: "127.0.0.1", Port: server.port(),
+		From: "alerts@example.com", To: []string{"a@example.com"}, Security: SecurityNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipients) > 0 {
+		notifier.Recipients = recipients
+	}
+	return notifier
+}
+
+// parts returns the message and the decoded content of its parts by their content type
+func parts(t *testing.T, data []byte) (*mail.Message, map[string]string) {
+	t.Helper()
+	message, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", message.Header.Get("Content-Type"))
+	}
+	contents := make(map[string]string)
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoding := part.Header.Get("Content-Transfer-Encoding"); encoding != "quoted-printable" {
+			t.Errorf("Content-Transfer-Encoding = %q, want quoted-printable", encoding)
+		}
+		content, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[part.Header.Get("Content-Type")] = string(content)
+	}
+	return message, contents
+}
+
+func TestEmailRequireStartTLSWhenNotOffered(t *testing.T) {
+	inTempDir(t)
+	server, _ := newSMTPServer(t, false)
+	notifier := testEmailNotifier(t, server)
+	notifier.Security = SecurityRequireStartTLS
+
+	err := notifier.Notify(Rule{Name: "Bränder"}, []Event{testEvent()})
+	if err == nil || !strings.Contains(err.Error(), "does not offer STARTTLS") {
+		t.Fatalf("Notify = %v, want an error about STARTTLS", err)
+	}
+	if len(server.received()) != 0 {
+		t.Error("the mail was sent without encryption")
+	}
+}
+
+func TestEmailStartTLSAndAuth(t *testing.T) {
+	inTempDir(t)
+	server, clientTLS := newSMTPServer(t, true)
+	server.username, server.password = "alerts", "s3cret"
+	notifier := testEmailNotifier(t, server)
+	notifier.Security = SecurityRequireStartTLS
+	notifier.TLSConfig = clientTLS
+	notifier.Username, notifier.Password = "alerts", "s3cret"
+
+	if err := notifier.Notify(Rule{Name: "Bränder"}, []Event{testEvent()}); err != nil {
+		t.Fatal(err)
+	}
+	mails := server.received()
+	if len(mails) != 1 {
+		t.Fatalf("the server received %d mails, want 1", len(mails))
+	}
+	if !mails[0].TLS || mails[0].User != "alerts" {
+		t.Errorf("TLS = %v and user = %q, want an encrypted session of alerts", mails[0].TLS, mails[0].User)
+	}
+	if mails[0].From != "alerts@example.com" || len(mails[0].To) != 1 || mails[0].To[0] != "a@example.com" {
+		t.Errorf("envelope from %s to %v", mails[0].From, mails[0].To)
+	}
+
+	notifier.Password = "wrong"
+	if err := notifier.Notify(Rule{Name: "Bränder"}, []Event{testEvent()}); err == nil {
+		t.Error("Notify succeeded with the wrong password")
+	}
+}
+
+func TestEmailMultipartBody(t *testing.T) {
+	inTempDir(t)
+	server, _ := newSMTPServer(t, false)
+	notifier := testEmailNotifier(t, server)
+	event := testEvent()
+	event.Summary = "Kosta. Brand i fordon, räddningstjänsten är på plats & släcker."
+
+	if err := notifier.Notify(Rule{Name: "Bränder"}, []Event{event}); err != nil {
+		t.Fatal(err)
+	}
+	mails := server.received()
+	if len(mails) != 1 {
+		t.Fatalf("the server received %d mails, want 1", len(mails))
+	}
+	message, contents := parts(t, mails[0].Data)
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if err != nil || subject != "Alert: Bränder (1 events)" {
+		t.Errorf("Subject = %q, %v", subject, err)
+	}
+	text := contents["text/plain; charset=utf-8"]
+	if !strings.Contains(text, event.Summary) || !strings.Contains(text, "Alert: Bränder") {
+		t.Errorf("the text part is %q", text)
+	}
+	html := contents["text/html; charset=utf-8"]
+	if !strings.Contains(html, `href="`+PageURL(event.Url)+`"`) || !strings.Contains(html, "på plats &amp; släcker") {
+		t.Errorf("the HTML part is %q", html)
+	}
+}
+
+func TestEmailTextBody(t *testing.T) {
+	inTempDir(t)
+	server, _ := newSMTPServer(t, false)
+	notifier := testEmailNotifier(t, server)
+	text := "Veckans sammanfattning: 12 händelser i Kronobergs län."
+
+	if err := notifier.SendDocument("Sammanfattning", text, ""); err != nil {
+		t.Fatal(err)
+	}
+	mails := server.received()
+	if len(mails) != 1 {
+		t.Fatalf("the server received %d mails, want 1", len(mails))
+	}
+	message, err := mail.ReadMessage(strings.NewReader(string(mails[0].Data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType := message.Header.Get("Content-Type"); contentType != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/plain", contentType)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(message.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(body)) != text {
+		t.Errorf("the body is %q, want %q", body, text)
+	}
+}
+
+func TestEmailRecipientFilters(t *testing.T) {
+	inTempDir(t)
+	server, _ := newSMTPServer(t, false)
+	notifier := testEmailNotifier(t, server,
+		Recipient{Address: "all@example.com"},
+		Recipient{Address: "brand@example.com", Filter: Filter{Type: "Brand"}},
+		Recipient{Address: "rån@example.com", Filter: Filter{Type: "Rån"}},
+	)
+	fire := testEvent()
+	theft := testEvent()
+	theft.Id, theft.Type, theft.Name = 2, "Stöld", "25 april 22:10, Stöld, Lessebo"
+
+	if err := notifier.Notify(Rule{Name: "Allt"}, []Event{fire, theft}); err != nil {
+		t.Fatal(err)
+	}
+	byRecipient := make(map[string][]byte)
+	for _, mail := range server.received() {
+		byRecipient[strings.Join(mail.To, ",")] = mail.Data
+	}
+	if len(byRecipient) != 2 {
+		t.Fatalf("mails were sent to %d recipients, want 2: %v", len(byRecipient), byRecipient)
+	}
+	_, all := parts(t, byRecipient["all@example.com"])
+	if text := all["text/plain; charset=utf-8"]; !strings.Contains(text, fire.Name) || !strings.Contains(text, theft.Name) {
+		t.Errorf("all@example.com got %q, want both events", text)
+	}
+	_, fires := parts(t, byRecipient["brand@example.com"])
+	if text := fires["text/plain; charset=utf-8"]; !strings.Contains(text, fire.Name) || strings.Contains(text, theft.Name) {
+		t.Errorf("brand@example.com got %q, want only the fire", text)
+	}
+}
+
+func TestEmailBatch(t *testing.T) {
+	inTempDir(t)
+	server, _ := newSMTPServer(t, false)
+	notifier := testEmailNotifier(t, server)
+	notifier.Batch = 15 * time.Minute
+	now := time.Date(2023, 4, 25, 22, 0, 0, 0, time.UTC)
+	notifier.now = func() time.Time { return now }
+
+	for i, rule := range []string{"Bränder", "Lessebo"} {
+		event := testEvent()
+		event.Id = i + 1
+		if err := notifier.Notify(Rule{Name: rule}, []Event{event}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notifier.Retry()
+	if len(server.received()) != 0 {
+		t.Fatal("the batch was sent before the window had passed")
+	}
+
+	now = now.Add(notifier.Batch)
+	notifier.Retry()
+	mails := server.received()
+	if len(mails) != 1 {
+		t.Fatalf("the server received %d mails, want one batch", len(mails))
+	}
+	message, contents := parts(t, mails[0].Data)
+	if subject := message.Header.Get("Subject"); !strings.Contains(subject, "2 rules matched 2 events") {
+		t.Errorf("Subject = %q", subject)
+	}
+	if text := contents["text/plain; charset=utf-8"]; !strings.Contains(text, "Alert: Bränder") || !strings.Contains(text, "Alert: Lessebo") {
+		t.Errorf("the batch is %q, want both rules", text)
+	}
+
+	notifier.Retry()
+	if len(server.received()) != 1 {
+		t.Error("the batch was sent twice")
+	}
+}
+
+func TestEmailQueuesRejectedMails(t *testing.T) {
+	for _, code := range []int{451, 554} {
+		t.Run(strconv.Itoa(code), func(t *testing.T) {
+			inTempDir(t)
+			server, _ := newSMTPServer(t, false)
+			notifier := testEmailNotifier(t, server)
+			now := time.Date(2023, 4, 25, 22, 0, 0, 0, time.UTC)
+			notifier.Queue.now = func() time.Time { return now }
+
+			server.setReject(code)
+			err := notifier.Notify(Rule{Name: "Bränder"}, []Event{testEvent()})
+			if err == nil || !strings.Contains(err.Error(), fmt.Sprint(code)) || !strings.Contains(err.Error(), "queued for retry") {
+				t.Fatalf("Notify = %v, want the %d queued for retry", err, code)
+			}
+			if notifier.Queue.Len() != 1 {
+				t.Fatalf("the queue has %d mails, want 1", notifier.Queue.Len())
+			}
+
+			// Still rejected, the mail stays in the queue with a longer backoff
+			now = now.Add(notifier.Queue.Backoff)
+			notifier.Retry()
+			if notifier.Queue.Len() != 1 || len(server.received()) != 0 {
+				t.Fatalf("after a rejected retry the queue has %d mails and the server %d", notifier.Queue.Len(), len(server.received()))
+			}
+
+			server.setReject(0)
+			notifier.Retry()
+			if len(server.received()) != 0 {
+				t.Fatal("the mail was retried before the backoff had passed")
+			}
+			now = now.Add(2 * notifier.Queue.Backoff)
+			notifier.Retry()
+			mails := server.received()
+			if len(mails) != 1 || notifier.Queue.Len() != 0 {
+				t.Fatalf("the server received %d mails and the queue has %d, want 1 and 0", len(mails), notifier.Queue.Len())
+			}
+			if mails[0].To[0] != "a@example.com" || !strings.Contains(string(mails[0].Data), "Subject: ") {
+				t.Errorf("the queued mail was delivered as %+v", mails[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"log"
+	"os"
+	. "project/main/event"
+	"project/main/stats"
+	"time"
+)
+
+// DefaultConfigPath is where the alert rules and notifiers are read from
+const DefaultConfigPath = "main/config/alerts.json"
+
+// Config is the content of the alert configuration file
+type Config struct {
+	Notifiers []NotifierConfig `json:"notifiers"`
+	Rules     []Rule           `json:"rules"`
+	// Anomalies enables alerts about (location, type) pairs with far more events than normal
+	Anomalies *AnomalyConfig `json:"anomalies,omitempty"`
+}
+
+// AnomalyConfig describes the anomaly alerts, the zero values use stats.DefaultAnomalyOptions
+type AnomalyConfig struct {
+	// Window is the period that is checked, for example "24h"
+	Window         string   `json:"window,omitempty"`
+	MinCount       int      `json:"min_count,omitempty"`
+	MaxProbability float64  `json:"max_probability,omitempty"`
+	Notifiers      []string `json:"notifiers"`
+}
+
+// NotifierConfig describes one notifier, Kind is one of "stdout", "desktop", "webhook" or "email"
+type NotifierConfig struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Webhook, Format is "json", "slack", "mattermost", "teams" or the path of a template file
+	URL    string `json:"url,omitempty"`
+	Secret string `json:"secret,omitempty"`
+	Format string `json:"format,omitempty"`
+	// Email, To receive every match and Recipients the matches that pass their filters. Security is
+	// "starttls" (default), "require-starttls", "tls" or "none" and Batch collects the matches, for example "15m".
+	Host       string      `json:"host,omitempty"`
+	Port       int         `json:"port,omitempty"`
+	Username   string      `json:"username,omitempty"`
+	Password   string      `json:"password,omitempty"`
+	From       string      `json:"from,omitempty"`
+	To         []string    `json:"to,omitempty"`
+	Recipients []Recipient `json:"recipients,omitempty"`
+	Security   string      `json:"security,omitempty"`
+	Batch      string      `json:"batch,omitempty"`
+}
+
+// LoadConfig reads the alert configuration from a JSON file
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Engine evaluates events against the rules and remembers what has been delivered for deduplication
+type Engine struct {
+	rules     []Rule
+	anomalies *AnomalyConfig
+	options   stats.AnomalyOptions
+	notifiers map[string]Notifier
+	delivered map[string]time.Time
+	now       func() time.Time
+}
+
+// NewEngine creates the notifiers of the configuration and validates the rules.
+// app is used for desktop notifications and may be nil when the GUI is not running, the desktop
+// notifiers are then left out of the rules so that watching headless does not log failed deliveries.
+func NewEngine(config Config, app fyne.App) (*Engine, error) {
+	engine := &Engine{
+		notifiers: make(map[string]Notifier),
+		delivered: make(map[string]time.Time),
+		now:       time.Now,
+	}
+	skipped := make(map[string]bool)
+	for _, notifierConfig := range config.Notifiers {
+		if notifierConfig.Kind == "desktop" && app == nil {
+			log.Println("Skipping the desktop notifier", notifierConfig.Name+", desktop notifications need the GUI to be running")
+			skipped[notifierConfig.Name] = true
+			continue
+		}
+		notifier, err := newNotifier(notifierConfig, app)
+		if err != nil {
+			return nil, err
+		}
+		engine.notifiers[notifierConfig.Name] = notifier
+	}
+	for _, rule := range config.Rules {
+		rule.Notifiers = withoutSkipped(rule.Notifiers, skipped)
+		if err := rule.prepare(); err != nil {
+			return nil, err
+		}
+		for _, name := range rule.Notifiers {
+			if _, ok := engine.notifiers[name]; !ok {
+				return nil, fmt.Errorf("rule %q: unknown notifier %q", rule.Name, name)
+			}
+		}
+		engine.rules = append(engine.rules, rule)
+	}
+	if config.Anomalies != nil {
+		anomalies := *config.Anomalies
+		anomalies.Notifiers = withoutSkipped(anomalies.Notifiers, skipped)
+		if err := engine.prepareAnomalies(anomalies); err != nil {
+			return nil, err
+		}
+	}
+	return engine, nil
+}
+
+// withoutSkipped returns the names of the notifiers that were not skipped
+func withoutSkipped(names []string, skipped map[string]bool) []string {
+	var kept []string
+	for _, name := range names {
+		if !skipped[name] {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+// prepareAnomalies validates the anomaly configuration and fills in the defaults
+func (e *Engine) prepareAnomalies(config AnomalyConfig) error {
+	e.anomalies = &config
+	e.options = stats.DefaultAnomalyOptions
+	if config.Window != "" {
+		window, err := time.ParseDuration(config.Window)
+		if err != nil {
+			return fmt.Errorf("anomalies: invalid window %q", config.Window)
+		}
+		e.options.Window = window
+	}
+	if config.MinCount > 0 {
+		e.options.MinCount = config.MinCount
+	}
+	if config.MaxProbability > 0 {
+		e.options.MaxProbability = config.MaxProbability
+	}
+	for _, name := range config.Notifiers {
+		if _, ok := e.notifiers[name]; !ok {
+			return fmt.Errorf("anomalies: unknown notifier %q", name)
+		}
+	}
+	return nil
+}
+
+// Evaluate matches the newly merged events against every rule and delivers the matches of each rule
+// to its notifiers in one batch. Delivery errors are logged so that one failing notifier does not stop the others.
+func (e *Engine) Evaluate(newEvents []Event) {
+	for i := range e.rules {
+		rule := &e.rules[i]
+		var matches []Event
+		for _, event := range newEvents {
+			if rule.Matches(event) && !e.isDuplicate(rule, event) {
+				matches = append(matches, event)
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		for _, name := range rule.Notifiers {
+			if err := e.notifiers[name].Notify(*rule, matches); err != nil {
+				log.Println("Alert", rule.Name, "could not be delivered by", name+":", err)
+			}
+		}
+	}
+}
+
+// EvaluateAnomalies checks the window that ends now for anomalous (location, type) pairs, using the
+// events as history, and delivers every anomaly to the anomaly notifiers once per window
+func (e *Engine) EvaluateAnomalies(events []Event) {
+	if e.anomalies == nil {
+		return
+	}
+	now := e.now()
+	for _, anomaly := range stats.DetectAnomalies(events, now, e.options) {
+		key := "anomaly|" + anomaly.Type + "|" + anomaly.Location
+		if last, ok := e.delivered[key]; ok && now.Sub(last) < e.options.Window {
+			continue
+		}
+		e.delivered[key] = now
+		rule := Rule{Name: "Anomaly: " + anomaly.String(), Notifiers: e.anomalies.Notifiers}
+		for _, name := range rule.Notifiers {
+			if err := e.notifiers[name].Notify(rule, anomaly.Events); err != nil {
+				log.Println("Anomaly alert", anomaly.Type, "in", anomaly.Location, "could not be delivered by", name+":", err)
+			}
+		}
+	}
+}
+
+// Notifier returns the notifier with the name
+func (e *Engine) Notifier(name string) (Notifier, bool) {
+	notifier, ok := e.notifiers[name]
+	return notifier, ok
+}
+
+// Retry lets every notifier with a retry queue deliver its queued deliveries that are due
+func (e *Engine) Retry() {
+	for _, notifier := range e.notifiers {
+		if retrier, ok := notifier.(Retrier); ok {
+			retrier.Retry()
+		}
+	}
+}
+
+// isDuplicate reports whether an event with the same type and location was delivered for the rule
+// within its dedupe window, and otherwise records the event as delivered
+func (e *Engine) isDuplicate(rule *Rule, event Event) bool {
+	if rule.dedupe == 0 {
+		return false
+	}
+	key := rule.Name + "|" + event.Type + "|" + event.Location.Name
+	now := e.now()
+	if last, ok := e.delivered[key]; ok && now.Sub(last) < rule.dedupe {
+		return true
+	}
+	e.delivered[key] = now
+	return false
+}
@@ -0,0 +1,37 @@
+package alert
+
+import (
+	"testing"
+)
+
+func TestNewEngineWithoutAppSkipsDesktopNotifiers(t *testing.T) {
+	config, err := LoadConfig("../config/alerts.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewEngine(config, nil)
+	if err != nil {
+		t.Fatalf("NewEngine without an app: %v", err)
+	}
+	if _, ok := engine.notifiers["desktop"]; ok {
+		t.Error("the desktop notifier was created without an app")
+	}
+	for _, rule := range engine.rules {
+		for _, name := range rule.Notifiers {
+			if name == "desktop" {
+				t.Errorf("rule %q still delivers to the desktop notifier", rule.Name)
+			}
+		}
+	}
+	if engine.anomalies == nil || len(engine.anomalies.Notifiers) == 0 {
+		t.Fatal("the anomalies have no notifiers")
+	}
+	for _, name := range engine.anomalies.Notifiers {
+		if name == "desktop" {
+			t.Error("the anomalies still deliver to the desktop notifier")
+		}
+	}
+	if config.Rules[0].Notifiers[1] != "desktop" {
+		t.Error("NewEngine changed the configuration")
+	}
+}
@@ -0,0 +1,110 @@
+package alert
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	. "project/main/event"
+	"strings"
+	"time"
+)
+
+// mailTemplate is the HTML version of the alert mails
+var mailTemplate = template.Must(template.New("mail").Funcs(template.FuncMap{"link": PageURL}).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+{{range .}}<h2>Alert: {{.Rule}} ({{len .Events}} events)</h2>
+<ul>
+{{range .Events}}<li><a href="{{link .Url}}">{{.Name}}</a><br>{{.Summary}}</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
+
+// renderMatches renders the matches of the rules as plain text and HTML
+func renderMatches(matches []batchedMatch) (text string, html string, err error) {
+	var plain strings.Builder
+	for _, match := range matches {
+		fmt.Fprintf(&plain, "--- Alert: %s (%d events) ---\n%s", match.Rule, len(match.Events), describeEvents(match.Events))
+	}
+	var buffer bytes.Buffer
+	if err := mailTemplate.Execute(&buffer, matches); err != nil {
+		return "", "", err
+	}
+	return plain.String(), buffer.String(), nil
+}
+
+// buildMessage builds a mail with the headers and a multipart/alternative body of the text and the HTML,
+// the body is only the text if there is no HTML. The parts are encoded as quoted-printable.
+func buildMessage(from string, to []string, subject string, text string, html string, date time.Time) ([]byte, error) {
+	var message bytes.Buffer
+	header := func(name string, value string) {
+		message.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from)
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", messageID(from))
+	header("MIME-Version", "1.0")
+
+	if html == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		message.WriteString("\r\n")
+		if err := writeQuotedPrintable(&message, text); err != nil {
+			return nil, err
+		}
+		return message.Bytes(), nil
+	}
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	message.WriteString("\r\n")
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		writer, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(writer, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+// writeQuotedPrintable writes the content with CRLF line endings encoded as quoted-printable
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, content string) error {
+	encoder := quotedprintable.NewWriter(w)
+	if _, err := encoder.Write([]byte(strings.ReplaceAll(content, "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// messageID returns a unique Message-ID in the domain of the sender
+func messageID(from string) string {
+	random := make([]byte, 12)
+	rand.Read(random)
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">"
+}
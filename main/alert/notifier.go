@@ -0,0 +1,86 @@
+package alert
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"io"
+	"os"
+	. "project/main/event"
+	"strings"
+)
+
+// Notifier delivers the events that matched a rule
+type Notifier interface {
+	Notify(rule Rule, events []Event) error
+}
+
+// Retrier is implemented by notifiers that queue failed deliveries, Retry delivers the queued ones that are due
+type Retrier interface {
+	Retry()
+}
+
+// DocumentSender is implemented by notifiers that can also deliver a document, such as a briefing.
+// The HTML version of the document may be empty.
+type DocumentSender interface {
+	SendDocument(subject string, text string, html string) error
+}
+
+// newNotifier creates the notifier described by the configuration
+func newNotifier(config NotifierConfig, app fyne.App) (Notifier, error) {
+	switch config.Kind {
+	case "stdout":
+		return &WriterNotifier{Writer: os.Stdout}, nil
+	case "desktop":
+		return &DesktopNotifier{App: app}, nil
+	case "webhook":
+		if config.URL == "" {
+			return nil, fmt.Errorf("notifier %q: webhook without url", config.Name)
+		}
+		return newConfiguredWebhook(config)
+	case "email":
+		return newConfiguredEmail(config)
+	default:
+		return nil, fmt.Errorf("notifier %q: unknown kind %q", config.Name, config.Kind)
+	}
+}
+
+// WriterNotifier writes the matches as text, it is used for the "stdout" kind
+type WriterNotifier struct {
+	Writer io.Writer
+}
+
+func (n *WriterNotifier) Notify(rule Rule, events []Event) error {
+	_, err := fmt.Fprintf(n.Writer, "--- Alert: %s (%d events) ---\n%s", rule.Name, len(events), describeEvents(events))
+	return err
+}
+
+func (n *WriterNotifier) SendDocument(subject string, text string, html string) error {
+	_, err := fmt.Fprintf(n.Writer, "--- %s ---\n%s\n", subject, text)
+	return err
+}
+
+// DesktopNotifier shows a notification through the Fyne application
+type DesktopNotifier struct {
+	App fyne.App
+}
+
+func (n *DesktopNotifier) Notify(rule Rule, events []Event) error {
+	if n.App == nil {
+		return fmt.Errorf("desktop notifications need the GUI to be running")
+	}
+	content := events[0].Name
+	if len(events) > 1 {
+		content = fmt.Sprintf("%s and %d more", content, len(events)-1)
+	}
+	n.App.SendNotification(fyne.NewNotification("Alert: "+rule.Name, content))
+	return nil
+}
+
+// describeEvents formats events as text with name, summary and link, separated by blank lines
+func describeEvents(events []Event) string {
+	var text strings.Builder
+	for _, event := range events {
+		fmt.Fprintf(&text, "%s\n%s\n%s\n\n", event.Name, event.Summary, PageURL(event.Url))
+	}
+	return text.String()
+}
@@ -0,0 +1,292 @@
+package alert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// QueueDir is the directory where the retry queues and dead letter logs of the notifiers are stored
+const QueueDir = "main/archive/queue"
+
+// A lock of a queue file that is older than queueLockTimeout was left by a process that stopped
+const queueLockTimeout = time.Minute
+
+// Queue is a persistent list of deliveries that failed. They are retried with exponential backoff
+// and moved to a dead letter log when MaxAttempts is reached. Several processes can use the same queue,
+// every change is merged with the queue file under a lock file.
+type Queue struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+
+	path           string
+	deadLetterPath string
+	mu             sync.Mutex
+	items          []queueItem
+	// delivering holds the deliveries Retry is delivering, they are still saved in case the program stops
+	delivering []queueItem
+	// saved are the deliveries in the queue file when it was last read or written
+	saved []queueItem
+	now   func() time.Time
+}
+
+// queueItem is a failed delivery, the payload is whatever the notifier needs to deliver it again
+type queueItem struct {
+	Payload     []byte    `json:"payload"`
+	Created     time.Time `json:"created"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error"`
+}
+
+// OpenQueue opens the queue with the given name in QueueDir and loads the deliveries that are still pending.
+// The name comes from the configuration, so the file names are made of its letters, digits, '-' and '_' and a hash of it.
+func OpenQueue(name string) (*Queue, error) {
+	if err := os.MkdirAll(QueueDir, 0755); err != nil {
+		return nil, err
+	}
+	name = queueFileName(name)
+	q := &Queue{
+		MaxAttempts:    8,
+		Backoff:        30 * time.Second,
+		MaxBackoff:     6 * time.Hour,
+		path:           filepath.Join(QueueDir, name+".json"),
+		deadLetterPath: filepath.Join(QueueDir, name+".dead.jsonl"),
+		now:            time.Now,
+	}
+	items, err := q.read()
+	if err != nil {
+		return nil, err
+	}
+	q.items, q.saved = items, items
+	return q, nil
+}
+
+// read returns the deliveries in the queue file
+func (q *Queue) read() ([]queueItem, error) {
+	data, err := os.ReadFile(q.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []queueItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("%s: %w", q.path, err)
+	}
+	return items, nil
+}
+
+// Add queues a payload whose first delivery failed with err
+func (q *Queue) Add(payload []byte, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	q.update(func() {
+		q.items = append(q.items, queueItem{
+			Payload:     payload,
+			Created:     now,
+			Attempts:    1,
+			NextAttempt: now.Add(q.backoff(1)),
+			LastError:   err.Error(),
+		})
+	})
+}
+
+// Len returns the number of pending deliveries
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// Retry calls deliver for every payload that is due. Delivered payloads are removed from the queue,
+// payloads that failed MaxAttempts times are moved to the dead letter log. The queue is not locked
+// while the payloads are delivered, so a slow receiver does not hold up Add.
+func (q *Queue) Retry(deliver func(payload []byte) error) {
+	q.mu.Lock()
+	now := q.now()
+	var due []queueItem
+	q.update(func() {
+		var waiting []queueItem
+		for _, item := range q.items {
+			if now.Before(item.NextAttempt) {
+				waiting = append(waiting, item)
+			} else {
+				due = append(due, item)
+			}
+		}
+		// A Retry at the same time does not see the due payloads, so they are not delivered twice. Other
+		// processes see them in the queue file as if the attempt had failed, until it is finished.
+		q.items = waiting
+		for _, item := range due {
+			item.NextAttempt = now.Add(q.backoff(item.Attempts + 1))
+			q.delivering = append(q.delivering, item)
+		}
+	})
+	q.mu.Unlock()
+	if len(due) == 0 {
+		return
+	}
+
+	var failed []queueItem
+	for _, item := range due {
+		err := deliver(item.Payload)
+		if err == nil {
+			continue
+		}
+		item.Attempts++
+		item.LastError = err.Error()
+		if item.Attempts >= q.MaxAttempts {
+			q.deadLetter(item)
+			continue
+		}
+		item.NextAttempt = now.Add(q.backoff(item.Attempts))
+		failed = append(failed, item)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.update(func() {
+		q.delivering = removeItems(q.delivering, due)
+		q.items = append(q.items, failed...)
+	})
+}
+
+// removeItems returns the items without the removed ones, items are the same when they were created at the same time
+func removeItems(items []queueItem, removed []queueItem) []queueItem {
+	var kept []queueItem
+	for _, item := range items {
+		found := false
+		for _, r := range removed {
+			if item.Created.Equal(r.Created) && string(item.Payload) == string(r.Payload) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// queueFileName replaces the characters of a notifier name that are not safe in a file name with '_' and
+// appends a short hash of the name, so that names that differ only in those characters have different files
+func queueFileName(name string) string {
+	safe := []rune(name)
+	for i, r := range safe {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			safe[i] = '_'
+		}
+	}
+	if len(safe) == 0 {
+		safe = []rune("notifier")
+	}
+	hash := sha256.Sum256([]byte(name))
+	return string(safe) + "-" + hex.EncodeToString(hash[:4])
+}
+
+// backoff returns the waiting time after the given number of failed attempts
+func (q *Queue) backoff(attempts int) time.Duration {
+	wait := q.Backoff
+	for i := 1; i < attempts && wait < q.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > q.MaxBackoff {
+		wait = q.MaxBackoff
+	}
+	return wait
+}
+
+// update makes a change to the deliveries while the queue file is locked and saves the result. The deliveries
+// are read from the file first, so that the changes of other processes are kept, except those that are being
+// delivered and those that could not be saved before. It is called with q.mu held.
+func (q *Queue) update(change func()) {
+	unlock, err := q.lock()
+	if err != nil {
+		log.Println("An error occurred while saving the retry queue:", err)
+		change()
+		return
+	}
+	defer unlock()
+	items, err := q.read()
+	if err != nil {
+		log.Println("An error occurred while saving the retry queue:", err)
+		change()
+		return
+	}
+	q.items = append(removeItems(items, q.delivering), removeItems(q.items, q.saved)...)
+	change()
+	q.save()
+}
+
+// lock creates the lock file of the queue, it waits while another process holds the lock
+func (q *Queue) lock() (unlock func(), err error) {
+	path := q.path + ".lock"
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > queueLockTimeout {
+			os.Remove(path)
+			continue
+		}
+		if time.Since(start) > queueLockTimeout {
+			return nil, fmt.Errorf("%s is locked", q.path)
+		}
+	}
+}
+
+// save writes the pending deliveries, with those that are being delivered, to a temporary file that replaces
+// the queue file. It is called while the queue file is locked.
+func (q *Queue) save() {
+	saved := append(append([]queueItem(nil), q.items...), q.delivering...)
+	data, err := json.Marshal(saved)
+	if err != nil {
+		log.Println("An error occurred while saving the retry queue:", err)
+		return
+	}
+	temporary := q.path + ".tmp"
+	if err := os.WriteFile(temporary, data, 0644); err != nil {
+		log.Println("An error occurred while saving the retry queue:", err)
+		return
+	}
+	if err := os.Rename(temporary, q.path); err != nil {
+		log.Println("An error occurred while saving the retry queue:", err)
+		return
+	}
+	q.saved = saved
+}
+
+// deadLetter appends a delivery that will not be retried again to the dead letter log
+func (q *Queue) deadLetter(item queueItem) {
+	line, err := json.Marshal(item)
+	if err != nil {
+		log.Println("An error occurred while writing the dead letter log:", err)
+		return
+	}
+	file, err := os.OpenFile(q.deadLetterPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Println("An error occurred while writing the dead letter log:", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		log.Println("An error occurred while writing the dead letter log:", err)
+	}
+	log.Println("Delivery moved to", q.deadLetterPath, "after", item.Attempts, "attempts:", item.LastError)
+}
@@ -0,0 +1,137 @@
+// This package evaluates newly merged events against user defined alert rules
+// and delivers the matches through notifiers, for example stdout or a webhook
+package alert
+
+import (
+	"fmt"
+	. "project/main/event"
+	"strings"
+	"time"
+)
+
+// Rule describes which events should trigger an alert. Every non-empty criterion has to match,
+// within a criterion it is enough that one of the listed values matches.
+type Rule struct {
+	Name      string   `json:"name"`
+	Types     []string `json:"types,omitempty"`
+	Locations []string `json:"locations,omitempty"`
+	Near      *Circle  `json:"near,omitempty"`
+	Keywords  []string `json:"keywords,omitempty"`
+	// Categories of the taxonomy, for example "violence", and the lowest severity of the event's type
+	Categories  []string `json:"categories,omitempty"`
+	MinSeverity int      `json:"min_severity,omitempty"`
+	// Time of day window as "15:04", a window where From is after To passes midnight
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Names of the notifiers that deliver the matches
+	Notifiers []string `json:"notifiers"`
+	// Matches of the same type and location are only delivered once within this duration, for example "30m"
+	Dedupe string `json:"dedupe,omitempty"`
+
+	dedupe   time.Duration
+	from, to int
+}
+
+// prepare validates the rule and parses its durations and time of day window
+func (r *Rule) prepare() error {
+	if r.Name == "" {
+		return fmt.Errorf("rule without name")
+	}
+	if r.Near != nil {
+		if err := r.Near.Validate(); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+	}
+	if r.Dedupe != "" {
+		dedupe, err := time.ParseDuration(r.Dedupe)
+		if err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		r.dedupe = dedupe
+	}
+	if (r.From == "") != (r.To == "") {
+		return fmt.Errorf("rule %q: both from and to are needed for a time of day window", r.Name)
+	}
+	if r.From != "" {
+		var err error
+		if r.from, err = minuteOfDay(r.From); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		if r.to, err = minuteOfDay(r.To); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the event fulfils every criterion of the rule
+func (r *Rule) Matches(event Event) bool {
+	if len(r.Types) > 0 && !containsFold(r.Types, event.Type) {
+		return false
+	}
+	if len(r.Categories) > 0 && !containsFold(r.Categories, event.Category().ID) {
+		return false
+	}
+	if r.MinSeverity > 0 && event.Severity() < r.MinSeverity {
+		return false
+	}
+	if len(r.Locations) > 0 && !r.inLocations(event) {
+		return false
+	}
+	if r.Near != nil && !r.Near.Contains(event) {
+		return false
+	}
+	if len(r.Keywords) > 0 && !r.containsKeyword(event.Name+" "+event.Summary) {
+		return false
+	}
+	if r.From != "" && !r.inWindow(event.Time()) {
+		return false
+	}
+	return true
+}
+
+// inLocations reports whether the event is in one of the locations, a county includes its municipalities
+func (r *Rule) inLocations(event Event) bool {
+	for _, location := range r.Locations {
+		if event.InLocation(location) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) containsKeyword(text string) bool {
+	text = strings.ToLower(text)
+	for _, keyword := range r.Keywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) inWindow(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if r.from <= r.to {
+		return minute >= r.from && minute < r.to
+	}
+	return minute >= r.from || minute < r.to
+}
+
+// minuteOfDay parses a clock time such as "22:30" into minutes after midnight
+func minuteOfDay(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
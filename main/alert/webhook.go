@@ -0,0 +1,158 @@
+package alert
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	. "project/main/event"
+	"text/template"
+	"time"
+)
+
+// SignatureHeader is the request header holding the HMAC-SHA256 signature of the body,
+// formatted as "sha256=<hex digest>" and computed with the secret of the webhook
+const SignatureHeader = "X-Signature-256"
+
+// Payload templates for chat services that accept incoming webhooks
+var webhookTemplates = map[string]string{
+	"slack": `{{- $text := printf "*Alert: %s* (%d events)" .Rule (len .Events) -}}
+{{- range .Events }}{{ $text = printf "%s\n• <%s|%s> %s" $text (link .Url) .Name .Summary }}{{ end -}}
+{"text": {{ json $text }}}`,
+	"mattermost": `{{- $text := printf "#### Alert: %s (%d events)" .Rule (len .Events) -}}
+{{- range .Events }}{{ $text = printf "%s\n- [%s](%s) %s" $text .Name (link .Url) .Summary }}{{ end -}}
+{"text": {{ json $text }}}`,
+	"teams": `{{- $text := "" -}}
+{{- range .Events }}{{ $text = printf "%s\n\n[%s](%s) %s" $text .Name (link .Url) .Summary }}{{ end -}}
+{"@type": "MessageCard", "@context": "https://schema.org/extensions",
+ "summary": {{ json (printf "Alert: %s" .Rule) }},
+ "title": {{ json (printf "Alert: %s (%d events)" .Rule (len .Events)) }},
+ "text": {{ json $text }}}`,
+}
+
+// Functions available in the payload templates
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"link": PageURL,
+}
+
+// WebhookNotifier posts the matches of a rule to a URL. The body is the JSON payload below unless a
+// template is set, the body is signed when a secret is set and failed posts are retried when a queue is set.
+type WebhookNotifier struct {
+	URL      string
+	Secret   string
+	Template *template.Template
+	Client   *http.Client
+	Queue    *Queue
+}
+
+// NewWebhookNotifier creates a webhook notifier with a request timeout
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// newConfiguredWebhook creates the webhook notifier described by the configuration with its payload format,
+// which is "json" (default), "slack", "mattermost", "teams" or the path of a text/template file
+func newConfiguredWebhook(config NotifierConfig) (*WebhookNotifier, error) {
+	notifier := NewWebhookNotifier(config.URL)
+	notifier.Secret = config.Secret
+
+	format := config.Format
+	if format != "" && format != "json" {
+		text, ok := webhookTemplates[format]
+		if !ok {
+			data, err := os.ReadFile(format)
+			if err != nil {
+				return nil, fmt.Errorf("notifier %q: unknown format: %w", config.Name, err)
+			}
+			text = string(data)
+		}
+		tmpl, err := template.New(config.Name).Funcs(webhookTemplateFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %q: %w", config.Name, err)
+		}
+		notifier.Template = tmpl
+	}
+
+	queue, err := OpenQueue(config.Name)
+	if err != nil {
+		return nil, fmt.Errorf("notifier %q: %w", config.Name, err)
+	}
+	notifier.Queue = queue
+	return notifier, nil
+}
+
+// webhookPayload is the JSON body posted by WebhookNotifier, it is also the data of the payload templates
+type webhookPayload struct {
+	Rule   string  `json:"rule"`
+	Events []Event `json:"events"`
+}
+
+func (n *WebhookNotifier) Notify(rule Rule, events []Event) error {
+	body, err := n.payload(webhookPayload{Rule: rule.Name, Events: events})
+	if err != nil {
+		return err
+	}
+	if err := n.post(body); err != nil {
+		if n.Queue != nil {
+			n.Queue.Add(body, err)
+			return fmt.Errorf("%w, queued for retry", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Retry posts the queued payloads that are due
+func (n *WebhookNotifier) Retry() {
+	if n.Queue != nil {
+		n.Queue.Retry(n.post)
+	}
+}
+
+// payload renders the request body from the template, or as JSON if there is no template
+func (n *WebhookNotifier) payload(data webhookPayload) ([]byte, error) {
+	if n.Template == nil {
+		return json.Marshal(data)
+	}
+	var body bytes.Buffer
+	if err := n.Template.Execute(&body, data); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+// post sends the body to the URL, a response outside 2xx is an error
+func (n *WebhookNotifier) post(body []byte) error {
+	request, err := http.NewRequest(http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if n.Secret != "" {
+		request.Header.Set(SignatureHeader, Sign(n.Secret, body))
+	}
+	response, err := n.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded %s", response.Status)
+	}
+	return nil
+}
+
+// Sign returns the value of SignatureHeader for a body, receivers compute the same value to verify a request
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,303 @@
+package alert
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	. "project/main/event"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// inTempDir runs the test in an empty directory, so the queues are created there
+func inTempDir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func testEvent() Event {
+	event := Event{
+		Id:       1,
+		Datetime: "2023-04-25 22:42:49 +02:00",
+		Name:     "25 april 21:57, Brand, Lessebo",
+		Summary:  "Kosta. Brand i fordon.",
+		Url:      "/aktuellt/handelser/2023/april/25/25-april-2157-brand-lessebo/",
+		Type:     "Brand",
+	}
+	event.Location.Name = "Lessebo"
+	return event
+}
+
+// receiver is a webhook receiver that records the requests and answers with the next status
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	body, _ := io.ReadAll(request.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, body)
+	r.headers = append(r.headers, request.Header.Clone())
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestWebhookSignature(t *testing.T) {
+	inTempDir(t)
+	r := &receiver{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	notifier, err := newConfiguredWebhook(NotifierConfig{Name: "signed", URL: server.URL, Secret: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(Rule{Name: "Bränder"}, []Event{testEvent()}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.bodies) != 1 {
+		t.Fatalf("the receiver got %d requests, want 1", len(r.bodies))
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(r.bodies[0])
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := r.headers[0].Get(SignatureHeader); got != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, got, want)
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal(r.bodies[0], &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Rule != "Bränder" || len(payload.Events) != 1 || payload.Events[0].Id != 1 {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestWebhookWithoutSecretIsNotSigned(t *testing.T) {
+	inTempDir(t)
+	r := &receiver{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	notifier, err := newConfiguredWebhook(NotifierConfig{Name: "unsigned", URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(Rule{Name: "Bränder"}, []Event{testEvent()}); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.headers[0].Get(SignatureHeader); got != "" {
+		t.Errorf("%s = %q, want no signature", SignatureHeader, got)
+	}
+}
+
+func TestWebhookTemplates(t *testing.T) {
+	inTempDir(t)
+	link := PageURL(testEvent().Url)
+	tests := []struct {
+		format string
+		want   map[string]string
+	}{
+		{"slack", map[string]string{
+			"text": "*Alert: Bränder* (1 events)\n• <" + link + "|25 april 21:57, Brand, Lessebo> Kosta. Brand i fordon.",
+		}},
+		{"mattermost", map[string]string{
+			"text": "#### Alert: Bränder (1 events)\n- [25 april 21:57, Brand, Lessebo](" + link + ") Kosta. Brand i fordon.",
+		}},
+		{"teams", map[string]string{
+			"@type":   "MessageCard",
+			"summary": "Alert: Bränder",
+			"title":   "Alert: Bränder (1 events)",
+			"text":    "\n\n[25 april 21:57, Brand, Lessebo](" + link + ") Kosta. Brand i fordon.",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			r := &receiver{}
+			server := httptest.NewServer(r)
+			defer server.Close()
+			notifier, err := newConfiguredWebhook(NotifierConfig{Name: test.format, URL: server.URL, Format: test.format, Secret: "key"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := notifier.Notify(Rule{Name: "Bränder"}, []Event{testEvent()}); err != nil {
+				t.Fatal(err)
+			}
+			var payload map[string]any
+			if err := json.Unmarshal(r.bodies[0], &payload); err != nil {
+				t.Fatalf("the %s payload is not JSON: %v\n%s", test.format, err, r.bodies[0])
+			}
+			for key, want := range test.want {
+				if got := payload[key]; got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			if got := r.headers[0].Get(SignatureHeader); got != Sign("key", r.bodies[0]) {
+				t.Errorf("the templated payload is signed %q, want %q", got, Sign("key", r.bodies[0]))
+			}
+		})
+	}
+}
+
+func TestWebhookRetriesFailedPosts(t *testing.T) {
+	inTempDir(t)
+	r := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	notifier, err := newConfiguredWebhook(NotifierConfig{Name: "retried", URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2023, 4, 25, 12, 0, 0, 0, time.UTC)
+	notifier.Queue.now = func() time.Time { return now }
+
+	if err := notifier.Notify(Rule{Name: "Bränder"}, []Event{testEvent()}); err == nil {
+		t.Fatal("Notify succeeded although the receiver answered 503")
+	}
+	if notifier.Queue.Len() != 1 {
+		t.Fatalf("the queue has %d deliveries, want 1", notifier.Queue.Len())
+	}
+	notifier.Retry()
+	if len(r.bodies) != 1 {
+		t.Fatalf("Retry posted before the backoff had passed")
+	}
+
+	now = now.Add(notifier.Queue.Backoff)
+	notifier.Retry()
+	if len(r.bodies) != 2 || notifier.Queue.Len() != 1 {
+		t.Fatalf("after a failed retry: %d posts and %d queued, want 2 and 1", len(r.bodies), notifier.Queue.Len())
+	}
+	now = now.Add(2 * notifier.Queue.Backoff)
+	notifier.Retry()
+	if len(r.bodies) != 3 || notifier.Queue.Len() != 0 {
+		t.Fatalf("after a successful retry: %d posts and %d queued, want 3 and 0", len(r.bodies), notifier.Queue.Len())
+	}
+	if string(r.bodies[2]) != string(r.bodies[0]) {
+		t.Errorf("the retried body differs from the first one")
+	}
+
+	// The queue file is empty again when the queue is opened anew
+	reopened, err := OpenQueue("retried")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Len() != 0 {
+		t.Errorf("the reopened queue has %d deliveries, want 0", reopened.Len())
+	}
+}
+
+func TestQueueRetryDoesNotBlockAdd(t *testing.T) {
+	inTempDir(t)
+	queue, err := OpenQueue("slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.Backoff = 0
+	queue.Add([]byte("first"), io.EOF)
+
+	delivering := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		queue.Retry(func(payload []byte) error {
+			close(delivering)
+			<-release
+			return nil
+		})
+		close(done)
+	}()
+	<-delivering
+	added := make(chan struct{})
+	go func() {
+		queue.Add([]byte("second"), io.EOF)
+		close(added)
+	}()
+	select {
+	case <-added:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Add waited for the slow delivery of Retry")
+	}
+	close(release)
+	<-done
+	if queue.Len() != 1 {
+		t.Errorf("the queue has %d deliveries, want only the second one", queue.Len())
+	}
+}
+
+func TestQueueSharedByProcesses(t *testing.T) {
+	inTempDir(t)
+	first, err := OpenQueue("shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := OpenQueue("shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Backoff, second.Backoff = 0, 0
+	first.Add([]byte("first"), io.EOF)
+	second.Add([]byte("second"), io.EOF)
+	third, err := OpenQueue("shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Len() != 2 {
+		t.Fatalf("the queue file has %d deliveries, want the two of both queues", third.Len())
+	}
+
+	var delivered []string
+	deliver := func(payload []byte) error {
+		delivered = append(delivered, string(payload))
+		return nil
+	}
+	first.Retry(deliver)
+	second.Retry(deliver)
+	if len(delivered) != 2 {
+		t.Errorf("delivered %v, want first and second once", delivered)
+	}
+	reopened, err := OpenQueue("shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Len() != 0 || second.Len() != 0 || reopened.Len() != 0 {
+		t.Errorf("%d, %d and %d deliveries are queued, want none", first.Len(), second.Len(), reopened.Len())
+	}
+}
+
+func TestQueueFileNames(t *testing.T) {
+	inTempDir(t)
+	queue, err := OpenQueue("../../etc/passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir := filepath.Dir(queue.path); dir != filepath.Clean(QueueDir) {
+		t.Errorf("the queue is stored in %s, want %s", dir, QueueDir)
+	}
+	if base := filepath.Base(queue.path); strings.ContainsAny(strings.TrimSuffix(base, ".json"), "./") {
+		t.Errorf("unsafe queue file name %q", base)
+	}
+	if queueFileName("a/b") == queueFileName("a_b") || queueFileName("") == queueFileName("notifier") {
+		t.Error("different notifier names have the same queue file")
+	}
+}
@@ -0,0 +1,110 @@
+// This package builds the daily or weekly briefing documents of the archive, with headline counts,
+// the most serious events and a breakdown per län, and renders them with user-overridable templates
+package briefing
+
+import (
+	. "project/main/event"
+	"project/main/report"
+	"project/main/stats"
+	"sort"
+)
+
+// UnknownRegion is the region of the events whose location could not be resolved to a län
+const UnknownRegion = "Okänt län"
+
+// Options decide what the briefing contains
+type Options struct {
+	// Title is the heading of the document, for example "Daglig lägesbild"
+	Title string
+	// Filter selects the events of the briefing, for example a region
+	Filter Filter
+	// Serious is the number of the most serious events that are listed
+	Serious int
+	// Top is the number of types listed in the headline counts and per region
+	Top int
+}
+
+// DefaultOptions list the ten most serious events and the five most common types
+var DefaultOptions = Options{Title: "Briefing", Serious: 10, Top: 5}
+
+// Briefing is the data of the templates
+type Briefing struct {
+	Title      string
+	Period     report.Period
+	Total      report.Change
+	Categories []stats.Count
+	Types      []stats.Count
+	Serious    []Item
+	Regions    []RegionCount
+}
+
+// Item is one of the most serious events, Link is the page of the event on the police website
+type Item struct {
+	Event
+	Severity int
+	Category string
+	Link     string
+}
+
+// RegionCount is the number of events in a län with its most common types
+type RegionCount struct {
+	Name  string
+	Total int
+	Types []stats.Count
+}
+
+// Build builds the briefing of the events in the period. Total compares the period with the period
+// of the same length before it.
+func Build(events []Event, period report.Period, options Options) Briefing {
+	events = options.Filter.Apply(events)
+	inPeriod := period.Apply(events)
+	briefing := Briefing{
+		Title:      options.Title,
+		Period:     period,
+		Total:      report.Change{Key: "Total", Before: len(period.Previous().Apply(events)), After: len(inPeriod)},
+		Categories: stats.CountByCategory(inPeriod),
+		Types:      stats.Top(stats.CountByType(inPeriod), options.Top),
+		Serious:    serious(inPeriod, options.Serious),
+	}
+
+	byRegion := make(map[string][]Event)
+	for _, event := range inPeriod {
+		name := UnknownRegion
+		if county := event.Region().County; county != nil {
+			name = county.Name
+		}
+		byRegion[name] = append(byRegion[name], event)
+	}
+	for name, regionEvents := range byRegion {
+		briefing.Regions = append(briefing.Regions, RegionCount{
+			Name:  name,
+			Total: len(regionEvents),
+			Types: stats.Top(stats.CountByType(regionEvents), options.Top),
+		})
+	}
+	sort.Slice(briefing.Regions, func(i, j int) bool {
+		if briefing.Regions[i].Total != briefing.Regions[j].Total {
+			return briefing.Regions[i].Total > briefing.Regions[j].Total
+		}
+		return briefing.Regions[i].Name < briefing.Regions[j].Name
+	})
+	return briefing
+}
+
+// serious returns at most n events with the highest severity, the newest first among equally serious events
+func serious(events []Event, n int) []Item {
+	items := make([]Item, len(events))
+	for i, event := range events {
+		items[i] = Item{Event: event, Severity: event.Severity(), Category: event.Category().Label, Link: PageURL(event.Url)}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Severity != items[j].Severity {
+			return items[i].Severity > items[j].Severity
+		}
+		return items[i].Time().After(items[j].Time())
+	})
+	if len(items) > n {
+		return items[:n]
+	}
+	return items
+}
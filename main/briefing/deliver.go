@@ -0,0 +1,52 @@
+package briefing
+
+import (
+	"os"
+	"path/filepath"
+	"project/main/alert"
+	"strings"
+)
+
+// Subject is the subject of a briefing that is sent, for example by mail
+func (b Briefing) Subject() string {
+	return b.Title + ": " + b.Period.Label
+}
+
+// WriteFiles writes the briefing in the formats to the directory, named after its title and period, and
+// returns the paths of the written files. An existing briefing of the same period is replaced.
+func (b Briefing) WriteFiles(dir string, formats []string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	name := strings.ToLower(strings.NewReplacer(" ", "-", ":", "", "/", "-").Replace(b.Subject()))
+	var paths []string
+	for _, format := range formats {
+		data, err := b.Render(format)
+		if err != nil {
+			return paths, err
+		}
+		path := filepath.Join(dir, name+Formats[format].Extension)
+		temporary := path + ".tmp"
+		if err := os.WriteFile(temporary, data, 0644); err != nil {
+			return paths, err
+		}
+		if err := os.Rename(temporary, path); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// Send hands the briefing as plain text and HTML to a notifier that can deliver documents, such as the email notifier
+func (b Briefing) Send(sender alert.DocumentSender) error {
+	text, err := b.Render("text")
+	if err != nil {
+		return err
+	}
+	html, err := b.Render("html")
+	if err != nil {
+		return err
+	}
+	return sender.SendDocument(b.Subject(), string(text), string(html))
+}
@@ -0,0 +1,71 @@
+package briefing
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+)
+
+// TemplateDir is where the default templates can be overridden by files with the same names,
+// briefing.txt.tmpl, briefing.md.tmpl and briefing.html.tmpl
+const TemplateDir = "main/config/briefing"
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+// Format is a format a briefing is rendered in
+type Format struct {
+	Name      string
+	Extension string
+	template  string
+}
+
+// Formats are the formats of the briefings by name
+var Formats = map[string]Format{
+	"text":     {Name: "Plain text", Extension: ".txt", template: "briefing.txt.tmpl"},
+	"markdown": {Name: "Markdown", Extension: ".md", template: "briefing.md.tmpl"},
+	"html":     {Name: "HTML", Extension: ".html", template: "briefing.html.tmpl"},
+}
+
+// Functions available in the templates
+var templateFuncs = map[string]any{
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04")
+	},
+}
+
+// Render renders the briefing in the format, with the template in TemplateDir if there is one.
+// Plain text and Markdown are rendered with text/template, HTML with html/template which escapes the events.
+func (b Briefing) Render(format string) ([]byte, error) {
+	f, ok := Formats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown briefing format %q, expected text, markdown or html", format)
+	}
+	text, err := os.ReadFile(filepath.Join(TemplateDir, f.template))
+	if os.IsNotExist(err) {
+		text, err = defaultTemplates.ReadFile("templates/" + f.template)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	if format == "html" {
+		tmpl, err := htmltemplate.New(f.template).Funcs(templateFuncs).Parse(string(text))
+		if err != nil {
+			return nil, err
+		}
+		err = tmpl.Execute(&buffer, b)
+		return buffer.Bytes(), err
+	}
+	tmpl, err := template.New(f.template).Funcs(templateFuncs).Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+	err = tmpl.Execute(&buffer, b)
+	return buffer.Bytes(), err
+}
@@ -0,0 +1,69 @@
+package briefing
+
+import (
+	"fmt"
+	"project/main/report"
+	"strings"
+	"time"
+)
+
+// Schedule is when the briefings are made, every day or every week at a time of day
+type Schedule struct {
+	Weekly bool
+	// Weekday is the day of the weekly briefings
+	Weekday time.Weekday
+	// Hour and Minute are the time of day of the briefings
+	Hour   int
+	Minute int
+}
+
+var weekdays = map[string]time.Weekday{
+	"monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday, "thursday": time.Thursday,
+	"friday": time.Friday, "saturday": time.Saturday, "sunday": time.Sunday,
+}
+
+// ParseSchedule parses the schedule "daily" or "weekly" at a time such as "07:00", weekday is the
+// English name of the day of the weekly briefings
+func ParseSchedule(every string, at string, weekday string) (Schedule, error) {
+	var schedule Schedule
+	switch every {
+	case "daily":
+	case "weekly":
+		schedule.Weekly = true
+		day, ok := weekdays[strings.ToLower(weekday)]
+		if !ok {
+			return schedule, fmt.Errorf("invalid weekday %q, expected for example monday", weekday)
+		}
+		schedule.Weekday = day
+	default:
+		return schedule, fmt.Errorf("invalid schedule %q, expected daily or weekly", every)
+	}
+	clock, err := time.Parse("15:04", at)
+	if err != nil {
+		return schedule, fmt.Errorf("invalid time of day %q, expected for example 07:00", at)
+	}
+	schedule.Hour, schedule.Minute = clock.Hour(), clock.Minute()
+	return schedule, nil
+}
+
+// Next returns the first time of a briefing after the given time
+func (s Schedule) Next(after time.Time) time.Time {
+	next := time.Date(after.Year(), after.Month(), after.Day(), s.Hour, s.Minute, 0, 0, after.Location())
+	for !next.After(after) || (s.Weekly && next.Weekday() != s.Weekday) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Period returns the period of the briefing made at the given time, the day or the week before it
+func (s Schedule) Period(at time.Time) report.Period {
+	if s.Weekly {
+		return report.Period{Label: "Week " + weekLabel(at.AddDate(0, 0, -7)), From: at.AddDate(0, 0, -7), To: at}
+	}
+	return report.Period{Label: "Day " + at.AddDate(0, 0, -1).Format("2006-01-02"), From: at.AddDate(0, 0, -1), To: at}
+}
+
+func weekLabel(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	. "project/main/alert"
+	"project/main/briefing"
+	"project/main/duplicate"
+	. "project/main/event"
+	"strings"
+	"time"
+)
+
+// How often the briefing mails that could not be delivered are retried
+const briefingRetryInterval = time.Minute
+
+// runBriefings makes a briefing of the archive every day or every week until the program is stopped.
+// Each briefing is written to a directory in the chosen formats and handed to a notifier of the alert
+// configuration, for example an email notifier. With -once the briefing of the period that ends now is
+// made at once, and the program exits when the briefing is delivered or its delivery has failed too
+// many times. The templates can be overridden by files in briefing.TemplateDir.
+func runBriefings(args []string) {
+	flags := flag.NewFlagSet("briefing", flag.ExitOnError)
+	selection := addFilterFlags(flags)
+	every := flags.String("every", "daily", "daily or weekly")
+	at := flags.String("at", "07:00", "time of day the briefings are made")
+	weekday := flags.String("weekday", "monday", "day of the weekly briefings")
+	title := flags.String("title", "", "heading of the briefings, for example Daglig lägesbild")
+	dir := flags.String("dir", "briefings", "directory the briefings are written to, no files if empty")
+	formats := flags.String("formats", "markdown,html", "comma separated formats of the files: text, markdown and html")
+	notify := flags.String("notify", "", "name of the notifier in -alerts that the briefings are handed to")
+	alertsPath := flags.String("alerts", DefaultConfigPath, "alert configuration file with the notifier")
+	serious := flags.Int("serious", briefing.DefaultOptions.Serious, "number of the most serious events that are listed")
+	once := flags.Bool("once", false, "make the briefing of the period that ends now and exit")
+	flags.Parse(args)
+
+	schedule, err := briefing.ParseSchedule(*every, *at, *weekday)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	formatNames := strings.Split(*formats, ",")
+	for _, format := range formatNames {
+		if _, ok := briefing.Formats[format]; !ok {
+			fmt.Println("Unknown format", format+", expected text, markdown or html")
+			os.Exit(2)
+		}
+	}
+	options := briefing.DefaultOptions
+	options.Filter = selection.Filter()
+	options.Serious = *serious
+	options.Title = *title
+	if options.Title == "" && schedule.Weekly {
+		options.Title = "Weekly briefing"
+	} else if options.Title == "" {
+		options.Title = "Daily briefing"
+	}
+	var sender DocumentSender
+	if *notify != "" {
+		sender = loadDocumentSender(*alertsPath, *notify)
+	}
+
+	removeMerged := duplicate.MergedRemover(duplicate.DecisionsPath)
+	makeBriefing := func(at time.Time) {
+		// The archive is read again for every briefing, it is kept up to date by the watch command
+		b := briefing.Build(removeMerged(GetArchive()), schedule.Period(at), options)
+		if *dir != "" {
+			paths, err := b.WriteFiles(*dir, formatNames)
+			if err != nil {
+				log.Println("An error occurred while writing the briefing:", err)
+			}
+			for _, path := range paths {
+				log.Println("Wrote", path)
+			}
+		}
+		if sender != nil {
+			if err := b.Send(sender); err != nil {
+				log.Println("The briefing could not be delivered by", *notify+":", err)
+			} else {
+				log.Println("Delivered", b.Subject(), "by", *notify)
+			}
+		}
+	}
+
+	// A briefing that could not be delivered is queued by the notifier and retried on the ticker
+	retrier, _ := sender.(Retrier)
+	retries := time.NewTicker(briefingRetryInterval)
+	defer retries.Stop()
+
+	if *once {
+		makeBriefing(time.Now())
+		if email, ok := sender.(*EmailNotifier); ok && email.Queue != nil && email.Queue.Len() > 0 {
+			log.Println("Retrying the", email.Queue.Len(), "queued mails of", *notify, "before exiting")
+			for email.Queue.Len() > 0 {
+				<-retries.C
+				email.Retry()
+			}
+		}
+		return
+	}
+	for {
+		next := schedule.Next(time.Now())
+		log.Println("Next briefing at", next.Format("2006-01-02 15:04"))
+		timer := time.NewTimer(time.Until(next))
+		for waiting := true; waiting; {
+			select {
+			case <-retries.C:
+				if retrier != nil {
+					retrier.Retry()
+				}
+			case <-timer.C:
+				waiting = false
+			}
+		}
+		makeBriefing(next)
+	}
+}
+
+// loadDocumentSender returns the notifier with the name from the alert configuration, the program
+// exits if it does not exist or can not deliver documents
+func loadDocumentSender(path string, name string) DocumentSender {
+	config, err := LoadConfig(path)
+	if err != nil {
+		fmt.Println("An error occurred while reading the alert configuration")
+		log.Fatal(err)
+	}
+	engine, err := NewEngine(config, nil)
+	if err != nil {
+		fmt.Println("An error occurred while reading the alert configuration")
+		log.Fatal(err)
+	}
+	notifier, ok := engine.Notifier(name)
+	if !ok {
+		fmt.Println("There is no notifier named", name, "in", path)
+		os.Exit(2)
+	}
+	sender, ok := notifier.(DocumentSender)
+	if !ok {
+		fmt.Println("The notifier", name, "can not deliver briefings, use an email or stdout notifier")
+		os.Exit(2)
+	}
+	return sender
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"project/main/duplicate"
+	. "project/main/event"
+	"project/main/report"
+	"time"
+)
+
+// compareEvents prints a report that compares the archived events of two periods, for example this week
+// with last week, or of two saved filters. Both selections are also restricted by the filter flags.
+// Without any period or saved filter this week is compared with last week.
+func compareEvents(args []string) {
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	selection := addFilterFlags(flags)
+	before := flags.String("before", "", "period compared against: this-week, last-week, this-month, last-month, 2023-09, 7d or 2023-04-10..2023-04-16")
+	after := flags.String("after", "", "period that is compared, in the same forms as -before")
+	beforeFilter := flags.String("before-filter", "", "name of a saved filter the events compared against must match")
+	afterFilter := flags.String("after-filter", "", "name of a saved filter the compared events must match")
+	format := flags.String("format", "text", "text, markdown or html")
+	output := flags.String("o", "", "file the report is written to, the terminal if empty")
+	top := flags.Int("top", 10, "number of top movers by type and by location")
+	flags.Parse(args)
+
+	if *before == "" && *after == "" && *beforeFilter == "" && *afterFilter == "" {
+		*before, *after = "last-week", "this-week"
+	}
+	filter := selection.Filter()
+	decisions, err := duplicate.LoadDecisions(duplicate.DecisionsPath)
+	if err != nil {
+		fmt.Println("An error occurred while reading the reviewed duplicates")
+		log.Fatal(err)
+	}
+	events := filter.Apply(decisions.RemoveMerged(GetArchive()))
+	comparison := report.Compare(
+		selectSide(events, *before, *beforeFilter, "Before"),
+		selectSide(events, *after, *afterFilter, "After"),
+		*top)
+
+	data, err := comparison.Render(*format)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Println("An error occurred while writing the report to", *output)
+		log.Fatal(err)
+	}
+	fmt.Println("Wrote the comparison of", comparison.After.Label, "with", comparison.Before.Label, "to", *output)
+}
+
+// selectSide selects the events of the period and the saved filter, either may be empty.
+// The side is labelled by the period and the filter, or by fallback if both are empty.
+func selectSide(events []Event, period string, savedFilter string, fallback string) report.Side {
+	side := report.Side{Label: fallback, Events: events}
+	if savedFilter != "" {
+		filters, err := LoadSavedFilters()
+		if err != nil {
+			fmt.Println("An error occurred while reading the saved filters")
+			log.Fatal(err)
+		}
+		filter, ok := filters[savedFilter]
+		if !ok {
+			fmt.Println("There is no saved filter named", savedFilter)
+			os.Exit(1)
+		}
+		side.Label = savedFilter
+		side.Events = filter.Apply(side.Events)
+	}
+	if period != "" {
+		parsed, err := report.ParsePeriod(period, time.Now())
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		if savedFilter != "" {
+			side.Label += ", " + parsed.Label
+		} else {
+			side.Label = parsed.Label
+		}
+		side.Events = parsed.Apply(side.Events)
+	}
+	return side
+}
@@ -0,0 +1,262 @@
+// This package splits the digest events, such as "Sammanfattning natt" or "Sammanfattning helg",
+// into the incidents they bundle. The items are parsed from the scraped extended summary and get
+// their own type, place and time, so they can be counted like regular events.
+package digest
+
+import (
+	"fmt"
+	. "project/main/event"
+	"project/main/geo"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DigestTypePrefix is the start of the types of all digest events
+const DigestTypePrefix = "Sammanfattning"
+
+// FallbackType is the type of items whose type can not be inferred from their text
+const FallbackType = "Övrigt"
+
+// Longest line, in characters, that is taken as the heading of an item
+const maxHeadingLength = 70
+
+// Most items an event Id is reserved for, see Item.Event
+const maxItems = 100
+
+// Item is one incident of a digest. Time is zero when the text does not state a time.
+type Item struct {
+	Digest   Event
+	Index    int
+	Type     string
+	Location string
+	Time     time.Time
+	Heading  string
+	Text     string
+}
+
+// IsDigest reports whether the event is a digest of several incidents
+func IsDigest(event Event) bool {
+	return strings.HasPrefix(event.Type, DigestTypePrefix)
+}
+
+// Parse splits the extended summary of a digest into items. A short line without a full stop is the
+// heading of the paragraphs that follow it, when the text has no headings every paragraph is an item.
+// The introduction, for example "Ett urval av nattens händelser", is skipped.
+func Parse(digest Event, text string) []Item {
+	var paragraphs []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+	if len(paragraphs) > 0 && isIntroduction(paragraphs[0]) {
+		paragraphs = paragraphs[1:]
+	}
+
+	type section struct {
+		heading string
+		body    []string
+	}
+	var sections []section
+	hasHeadings := false
+	for _, paragraph := range paragraphs {
+		if isHeading(paragraph) {
+			hasHeadings = true
+			sections = append(sections, section{heading: paragraph})
+			continue
+		}
+		if !hasHeadings || len(sections) == 0 {
+			sections = append(sections, section{body: []string{paragraph}})
+			continue
+		}
+		last := &sections[len(sections)-1]
+		last.body = append(last.body, paragraph)
+	}
+
+	var items []Item
+	for _, s := range sections {
+		body := strings.Join(s.body, " ")
+		if body == "" && s.heading == "" || len(items) == maxItems-1 {
+			continue
+		}
+		all := s.heading + " " + body
+		items = append(items, Item{
+			Digest:   digest,
+			Index:    len(items) + 1,
+			Type:     inferType(s.heading, body),
+			Location: inferLocation(digest, s.heading, all),
+			Time:     inferTime(digest, all),
+			Heading:  s.heading,
+			Text:     body,
+		})
+	}
+	return items
+}
+
+// Event returns the item as an event. Its Id is the Id of the digest times 100 plus the index of the item,
+// its time is the time of the digest when the item does not state one.
+func (item Item) Event() Event {
+	var event Event
+	event.Id = item.Digest.Id*maxItems + item.Index
+	datetime := item.Time
+	if datetime.IsZero() {
+		datetime = item.Digest.Time()
+	}
+	event.Datetime = datetime.Format(DatetimeLayout)
+	event.Name = item.Type + ", " + item.Location
+	event.Summary = strings.TrimSpace(item.Heading + " " + item.Text)
+	event.Url = item.Digest.Url
+	event.Type = item.Type
+	event.Location.Name = item.Location
+	if municipality, ok := geo.LookupMunicipality(item.Location); ok {
+		event.Location.Gps = fmt.Sprintf("%g,%g", municipality.Lat, municipality.Lon)
+	} else {
+		event.Location.Gps = item.Digest.Location.Gps
+	}
+	return event
+}
+
+func isIntroduction(paragraph string) bool {
+	lower := strings.ToLower(paragraph)
+	return strings.Contains(lower, "urval") || strings.Contains(lower, "sammanfattning")
+}
+
+func isHeading(paragraph string) bool {
+	last, _ := utf8.DecodeLastRuneInString(paragraph)
+	return len([]rune(paragraph)) <= maxHeadingLength && last != '.' && last != '!' && last != '?' && last != ':'
+}
+
+// typeKeywords maps the start of a word to the type of an item. The list is searched in order, so the
+// more specific words come first.
+var typeKeywords = []struct {
+	prefix    string
+	eventType string
+}{
+	{"skottlossning", "Skottlossning"},
+	{"skjut", "Skottlossning"},
+	{"mord", "Mord/dråp"},
+	{"dråp", "Mord/dråp"},
+	{"våldtäkt", "Våldtäkt"},
+	{"rån", "Rån"},
+	{"knivhugg", "Misshandel"},
+	{"misshand", "Misshandel"},
+	{"inbrott", "Inbrott"},
+	{"drograttfyll", "Rattfylleri"},
+	{"rattfyll", "Rattfylleri"},
+	{"viltolyck", "Trafikolycka, vilt"},
+	{"singelolyck", "Trafikolycka, singel"},
+	{"trafikolyck", "Trafikolycka"},
+	{"kollid", "Trafikolycka"},
+	{"krock", "Trafikolycka"},
+	{"brand", "Brand"},
+	{"brinn", "Brand"},
+	{"brann", "Brand"},
+	{"narkotik", "Narkotikabrott"},
+	{"cannabis", "Narkotikabrott"},
+	{"snatt", "Stöld, ringa"},
+	{"stöld", "Stöld"},
+	{"stul", "Stöld"},
+	{"skadegör", "Skadegörelse"},
+	{"klotter", "Skadegörelse"},
+	{"bedräg", "Bedrägeri"},
+	{"hota", "Olaga hot"},
+	{"hotfull", "Olaga hot"},
+	{"ofreda", "Ofredande/förargelse"},
+	{"bråk", "Bråk"},
+	{"slagsmål", "Bråk"},
+	{"berusad", "Fylleri/LOB"},
+	{"fylleri", "Fylleri/LOB"},
+	{"körkort", "Olovlig körning"},
+	{"fortkör", "Trafikbrott"},
+	{"hastighet", "Trafikbrott"},
+	{"försvunn", "Försvunnen person"},
+	{"kniv", "Knivlagen"},
+	{"vapen", "Vapenlagen"},
+}
+
+// inferType returns the type of an item, a type named in the heading wins over keywords in the text
+func inferType(heading string, body string) string {
+	if heading != "" {
+		if typeName, _, _ := strings.Cut(heading, ","); GetTaxonomy().Info(strings.TrimSpace(typeName)).Category != OtherCategory {
+			return strings.TrimSpace(typeName)
+		}
+	}
+	for _, text := range []string{heading, body} {
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r)
+		})
+		for _, keyword := range typeKeywords {
+			for _, word := range words {
+				if strings.HasPrefix(word, keyword.prefix) {
+					return keyword.eventType
+				}
+			}
+		}
+	}
+	return FallbackType
+}
+
+// inferLocation returns the municipality named in the item, preferring the heading and the municipalities
+// of the digest's county, or the location of the digest when no municipality is named
+func inferLocation(digest Event, heading string, text string) string {
+	candidates := geo.Municipalities()
+	if county := digest.Region().County; county != nil {
+		candidates = geo.MunicipalitiesIn(*county)
+	}
+	for _, searched := range []string{heading, text} {
+		lower := strings.ToLower(searched)
+		for _, municipality := range candidates {
+			if containsWord(lower, strings.ToLower(municipality.Name)) {
+				return municipality.Name
+			}
+		}
+	}
+	return digest.Location.Name
+}
+
+// containsWord reports whether word occurs in text without letters directly before or after it
+func containsWord(text string, word string) bool {
+	for offset := 0; ; {
+		index := strings.Index(text[offset:], word)
+		if index < 0 {
+			return false
+		}
+		start, end := offset+index, offset+index+len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !unicode.IsLetter(before) && !unicode.IsLetter(after) {
+			return true
+		}
+		offset = end
+	}
+}
+
+var (
+	clockTime = regexp.MustCompile(`(?i)\bkl(?:ockan)?\.?\s*(\d{1,2})[.:](\d{2})`)
+	aboutTime = regexp.MustCompile(`(?i)\b(?:vid|runt|omkring)\s+(\d{1,2})[-\s]?tiden`)
+)
+
+// inferTime returns the first time of day stated in the text, on the day of the digest or the day
+// before if that time is later than the digest was published
+func inferTime(digest Event, text string) time.Time {
+	hour, minute := -1, 0
+	if match := clockTime.FindStringSubmatch(text); match != nil {
+		hour, _ = strconv.Atoi(match[1])
+		minute, _ = strconv.Atoi(match[2])
+	} else if match := aboutTime.FindStringSubmatch(text); match != nil {
+		hour, _ = strconv.Atoi(match[1])
+	}
+	if hour < 0 || hour > 23 || minute > 59 {
+		return time.Time{}
+	}
+	published := digest.Time()
+	datetime := time.Date(published.Year(), published.Month(), published.Day(), hour, minute, 0, 0, published.Location())
+	if datetime.After(published) {
+		datetime = datetime.AddDate(0, 0, -1)
+	}
+	return datetime
+}
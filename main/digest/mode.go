@@ -0,0 +1,76 @@
+package digest
+
+import (
+	"fmt"
+	. "project/main/event"
+)
+
+// Mode decides how the digest events are counted next to the regular events
+type Mode string
+
+const (
+	// Include counts every digest as a single event
+	Include Mode = "include"
+	// Exclude leaves the digests out
+	Exclude Mode = "exclude"
+	// Expand replaces every digest by its items
+	Expand Mode = "expand"
+	// ItemsOnly keeps only the items of the digests
+	ItemsOnly Mode = "items"
+)
+
+// Modes are all modes, in the order they are presented to the user
+var Modes = []Mode{Include, Exclude, Expand, ItemsOnly}
+
+// ParseMode returns the mode with the given name
+func ParseMode(name string) (Mode, error) {
+	for _, mode := range Modes {
+		if string(mode) == name {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown digest mode %q, expected include, exclude, expand or items", name)
+}
+
+// Apply returns the events selected by the mode, in their original order with every expanded digest
+// replaced by its items. The items are parsed from the extended summaries in the cache and, if fetch
+// is true, the missing summaries are scraped. Expand keeps a digest whose summary is not available.
+// Scraping stops at the first error, which is returned together with the events.
+func Apply(events []Event, mode Mode, cache *SummaryCache, fetch bool) ([]Event, error) {
+	if mode == Include {
+		return events, nil
+	}
+	var selected []Event
+	var fetchErr error
+	for _, event := range events {
+		if !IsDigest(event) {
+			if mode != ItemsOnly {
+				selected = append(selected, event)
+			}
+			continue
+		}
+		if mode == Exclude {
+			continue
+		}
+
+		summary, ok := cache.Get(event)
+		if !ok && fetch && fetchErr == nil {
+			var err error
+			if summary, err = cache.Fetch(event); err != nil {
+				fetchErr = err
+			} else {
+				ok = true
+			}
+		}
+		if !ok {
+			if mode == Expand {
+				selected = append(selected, event)
+			}
+			continue
+		}
+		for _, item := range Parse(event, summary) {
+			selected = append(selected, item.Event())
+		}
+	}
+	return selected, fetchErr
+}
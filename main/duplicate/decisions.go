@@ -0,0 +1,132 @@
+package duplicate
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	. "project/main/event"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DecisionsPath is the JSON file where the reviewed pairs are stored
+const DecisionsPath = "main/archive/duplicates.json"
+
+// Decision is the outcome of reviewing a pair
+type Decision string
+
+const (
+	// Merged confirms that the events are duplicates, the event with the highest Id is left out of the statistics
+	Merged Decision = "merged"
+	// Dismissed means the events are different even though they are similar
+	Dismissed Decision = "dismissed"
+)
+
+// Decisions holds the reviewed pairs by the Ids of their events, it is safe for concurrent use
+type Decisions struct {
+	path      string
+	mu        sync.Mutex
+	decisions map[string]Decision
+}
+
+// LoadDecisions reads the decisions stored at path, a missing file means that no pair has been reviewed
+func LoadDecisions(path string) (*Decisions, error) {
+	d := &Decisions{path: path, decisions: make(map[string]Decision)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &d.decisions); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Get returns the decision about the pair
+func (d *Decisions) Get(pair Pair) (Decision, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	decision, ok := d.decisions[pairKey(pair.A.Id, pair.B.Id)]
+	return decision, ok
+}
+
+// Set stores the decision about the pair and writes the decisions to the file
+func (d *Decisions) Set(pair Pair, decision Decision) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.decisions[pairKey(pair.A.Id, pair.B.Id)] = decision
+	data, err := json.MarshalIndent(d.decisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(d.path, data, 0644)
+}
+
+// Pending returns the pairs that have not been reviewed
+func (d *Decisions) Pending(pairs []Pair) []Pair {
+	var pending []Pair
+	for _, pair := range pairs {
+		if _, ok := d.Get(pair); !ok {
+			pending = append(pending, pair)
+		}
+	}
+	return pending
+}
+
+// RemoveMerged returns the events without the events that are merged into an event with a lower Id
+func (d *Decisions) RemoveMerged(events []Event) []Event {
+	d.mu.Lock()
+	merged := make(map[int]bool)
+	for key, decision := range d.decisions {
+		if decision != Merged {
+			continue
+		}
+		if _, b, ok := strings.Cut(key, "-"); ok {
+			if id, err := strconv.Atoi(b); err == nil {
+				merged[id] = true
+			}
+		}
+	}
+	d.mu.Unlock()
+	if len(merged) == 0 {
+		return events
+	}
+	var kept []Event
+	for _, event := range events {
+		if !merged[event.Id] {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
+
+// MergedRemover returns a function that removes the merged events of the decisions stored at path, for
+// Store.Exclude. The decisions are read again when the file has changed, so the decisions made in the
+// GUI apply to a running server. If the file can not be read the previous decisions are used.
+func MergedRemover(path string) func(events []Event) []Event {
+	var mu sync.Mutex
+	var modTime time.Time
+	decisions := &Decisions{path: path, decisions: make(map[string]Decision)}
+	return func(events []Event) []Event {
+		mu.Lock()
+		if info, err := os.Stat(path); err == nil && !info.ModTime().Equal(modTime) {
+			if loaded, err := LoadDecisions(path); err != nil {
+				log.Println("An error occurred while reading the reviewed duplicates:", err)
+			} else {
+				decisions, modTime = loaded, info.ModTime()
+			}
+		}
+		current := decisions
+		mu.Unlock()
+		return current.RemoveMerged(events)
+	}
+}
+
+func pairKey(a, b int) string {
+	return strconv.Itoa(a) + "-" + strconv.Itoa(b)
+}
@@ -0,0 +1,190 @@
+// This package finds events with different Ids that describe the same thing. The texts are compared
+// with MinHash signatures of their shingles, and candidates are only reported when they are also
+// close in time and place.
+package duplicate
+
+import (
+	"hash/fnv"
+	"math"
+	. "project/main/event"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Length of the shingles in characters
+const shingleLength = 4
+
+// The signatures are split in bands, events whose signatures are equal in one band are candidates
+const (
+	signatureSize = 64
+	bands         = 16
+	rows          = signatureSize / bands
+)
+
+// Options controls which events are reported as duplicates
+type Options struct {
+	// MinSimilarity is the estimated text similarity, between 0 and 1, of a duplicate
+	MinSimilarity float64
+	// Window is the longest time between two duplicates
+	Window time.Duration
+	// MaxDistanceKm is the longest distance between the coordinates of two duplicates in different locations
+	MaxDistanceKm float64
+}
+
+// DefaultOptions reports events with 70% similar texts within six hours and five kilometres
+var DefaultOptions = Options{
+	MinSimilarity: 0.7,
+	Window:        6 * time.Hour,
+	MaxDistanceKm: 5,
+}
+
+// Pair is two events that are likely duplicates, A is the event with the lowest Id
+type Pair struct {
+	A          Event
+	B          Event
+	Similarity float64
+}
+
+// Signature is the MinHash signature of a text, the share of equal values of two signatures
+// estimates the Jaccard similarity of the shingles of the texts
+type Signature [signatureSize]uint64
+
+var seeds = func() [signatureSize]uint64 {
+	var s [signatureSize]uint64
+	state := uint64(0x5eed)
+	for i := range s {
+		state += 0x9e3779b97f4a7c15
+		s[i] = mix(state)
+	}
+	return s
+}()
+
+// NewSignature returns the signature of the text
+func NewSignature(text string) Signature {
+	var signature Signature
+	for i := range signature {
+		signature[i] = math.MaxUint64
+	}
+	for _, shingle := range shingles(text) {
+		hash := fnv.New64a()
+		hash.Write([]byte(shingle))
+		h := hash.Sum64()
+		for i, seed := range seeds {
+			if value := mix(h ^ seed); value < signature[i] {
+				signature[i] = value
+			}
+		}
+	}
+	return signature
+}
+
+// Similarity estimates the Jaccard similarity of the texts of two signatures
+func (s Signature) Similarity(other Signature) float64 {
+	equal := 0
+	for i := range s {
+		if s[i] == other[i] {
+			equal++
+		}
+	}
+	return float64(equal) / signatureSize
+}
+
+// Text returns the text of the event that is compared, the Name without its time of occurrence and the Summary
+func Text(event Event) string {
+	name := event.Name
+	if _, rest, ok := strings.Cut(name, ","); ok {
+		name = rest
+	}
+	return name + " " + event.Summary
+}
+
+// Detect returns the likely duplicates among the events, the most similar pairs first
+func Detect(events []Event, options Options) []Pair {
+	signatures := make([]Signature, len(events))
+	buckets := make(map[[2]uint64][]int)
+	for i, event := range events {
+		signatures[i] = NewSignature(Text(event))
+		for band := 0; band < bands; band++ {
+			key := [2]uint64{uint64(band), bandHash(signatures[i][band*rows : (band+1)*rows])}
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	seen := make(map[[2]int]bool)
+	var pairs []Pair
+	for _, bucket := range buckets {
+		for x := 0; x < len(bucket); x++ {
+			for y := x + 1; y < len(bucket); y++ {
+				i, j := bucket[x], bucket[y]
+				if seen[[2]int{i, j}] || events[i].Id == events[j].Id {
+					continue
+				}
+				seen[[2]int{i, j}] = true
+				similarity := signatures[i].Similarity(signatures[j])
+				if similarity < options.MinSimilarity || !nearby(events[i], events[j], options) {
+					continue
+				}
+				a, b := events[i], events[j]
+				if b.Id < a.Id {
+					a, b = b, a
+				}
+				pairs = append(pairs, Pair{A: a, B: b, Similarity: similarity})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Similarity != pairs[j].Similarity {
+			return pairs[i].Similarity > pairs[j].Similarity
+		}
+		return pairs[i].A.Id < pairs[j].A.Id
+	})
+	return pairs
+}
+
+// nearby reports whether two events are close enough in time and place to be duplicates
+func nearby(a, b Event, options Options) bool {
+	if math.Abs(a.Time().Sub(b.Time()).Hours()) > options.Window.Hours() {
+		return false
+	}
+	if strings.EqualFold(a.Location.Name, b.Location.Name) {
+		return true
+	}
+	latA, lonA, okA := a.Coordinates()
+	latB, lonB, okB := b.Coordinates()
+	return okA && okB && Distance(latA, lonA, latB, lonB) <= options.MaxDistanceKm
+}
+
+// shingles returns the overlapping substrings of shingleLength characters of the normalised text
+func shingles(text string) []string {
+	normalised := []rune(strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " "))
+	if len(normalised) <= shingleLength {
+		return []string{string(normalised)}
+	}
+	result := make([]string, 0, len(normalised)-shingleLength+1)
+	for i := 0; i+shingleLength <= len(normalised); i++ {
+		result = append(result, string(normalised[i:i+shingleLength]))
+	}
+	return result
+}
+
+func bandHash(values []uint64) uint64 {
+	h := uint64(0)
+	for _, value := range values {
+		h = mix(h ^ value)
+	}
+	return h
+}
+
+// mix is the finaliser of splitmix64, it spreads the bits of x over the whole result
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
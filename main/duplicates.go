@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"project/main/duplicate"
+	. "project/main/event"
+	"strconv"
+	"strings"
+)
+
+// listDuplicates prints the likely duplicates among the archived events that have not been reviewed.
+// A pair is reviewed with -merge or -dismiss and the Ids of its events, for example -merge 420165,420166.
+func listDuplicates(args []string) {
+	flags := flag.NewFlagSet("duplicates", flag.ExitOnError)
+	all := flags.Bool("all", false, "also print the reviewed pairs")
+	merge := flags.String("merge", "", "confirm that the pair id,id are duplicates")
+	dismiss := flags.String("dismiss", "", "mark the pair id,id as different events")
+	flags.Parse(args)
+
+	decisions, err := duplicate.LoadDecisions(duplicate.DecisionsPath)
+	if err != nil {
+		fmt.Println("An error occurred while reading the reviewed duplicates")
+		log.Fatal(err)
+	}
+	pairs := duplicate.Detect(GetArchive(), duplicate.DefaultOptions)
+
+	if *merge != "" || *dismiss != "" {
+		ids, decision := *merge, duplicate.Merged
+		if *dismiss != "" {
+			ids, decision = *dismiss, duplicate.Dismissed
+		}
+		pair, ok := findPair(pairs, ids)
+		if !ok {
+			fmt.Println(ids, "is not a pair of likely duplicates")
+			os.Exit(1)
+		}
+		if err := decisions.Set(pair, decision); err != nil {
+			fmt.Println("An error occurred while saving the review")
+			log.Fatal(err)
+		}
+		return
+	}
+
+	for _, pair := range pairs {
+		decision, reviewed := decisions.Get(pair)
+		if reviewed && !*all {
+			continue
+		}
+		status := "pending"
+		if reviewed {
+			status = string(decision)
+		}
+		fmt.Printf("%d,%d  %.0f%%  %s\n", pair.A.Id, pair.B.Id, 100*pair.Similarity, status)
+		fmt.Println("  ", pair.A.Name, "----", pair.A.Summary)
+		fmt.Println("  ", pair.B.Name, "----", pair.B.Summary)
+	}
+}
+
+// findPair returns the pair with the Ids "id,id" in any order
+func findPair(pairs []duplicate.Pair, ids string) (duplicate.Pair, bool) {
+	first, second, _ := strings.Cut(ids, ",")
+	a, errA := strconv.Atoi(strings.TrimSpace(first))
+	b, errB := strconv.Atoi(strings.TrimSpace(second))
+	if errA != nil || errB != nil {
+		return duplicate.Pair{}, false
+	}
+	for _, pair := range pairs {
+		if pair.A.Id == a && pair.B.Id == b || pair.A.Id == b && pair.B.Id == a {
+			return pair, true
+		}
+	}
+	return duplicate.Pair{}, false
+}
+
+// reviewedArchive returns the archived events without those that were merged into another event when
+// the duplicates were reviewed, like the server and the GUI show them
+func reviewedArchive() []Event {
+	return duplicate.MergedRemover(duplicate.DecisionsPath)(GetArchive())
+}
@@ -0,0 +1,115 @@
+// This file derives the event types and locations from the events themselves, so types that
+// polisen.se introduces are found even though they are missing from the curated TypeKeys
+package event
+
+import (
+	"project/main/geo"
+	"sort"
+	"time"
+)
+
+// KeyUsage describes how a type or location is used by a set of events. Known is false for
+// types that are not in TypeKeys and for locations that are not a kommun or län.
+type KeyUsage struct {
+	Key       string
+	Count     int
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Known     bool
+}
+
+// TypeUsage returns the types of the events ordered by name, with the known types first
+func TypeUsage(events []Event) []KeyUsage {
+	return keyUsage(events, func(event Event) string { return event.Type }, IsKnownType)
+}
+
+// LocationUsage returns the locations of the events ordered by name, with the known locations first
+func LocationUsage(events []Event) []KeyUsage {
+	return keyUsage(events, func(event Event) string { return event.Location.Name }, IsKnownLocation)
+}
+
+// Unknown returns the usages whose key is not known
+func Unknown(usages []KeyUsage) []KeyUsage {
+	var unknown []KeyUsage
+	for _, usage := range usages {
+		if !usage.Known {
+			unknown = append(unknown, usage)
+		}
+	}
+	return unknown
+}
+
+// IsKnownType reports whether the type is in the curated TypeKeys
+func IsKnownType(eventType string) bool {
+	for _, key := range TypeKeys {
+		if key == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKnownLocation reports whether the location is the name of a kommun or län in the gazetteer
+func IsKnownLocation(location string) bool {
+	if _, ok := geo.LookupMunicipality(location); ok {
+		return true
+	}
+	_, ok := geo.LookupCounty(location)
+	return ok
+}
+
+// NewKeys returns the types and locations of fetchedEvents that none of the events in the archive has
+func NewKeys(eventsInArchive []Event, fetchedEvents []Event) (types []string, locations []string) {
+	seenTypes := make(map[string]bool)
+	seenLocations := make(map[string]bool)
+	for _, event := range eventsInArchive {
+		seenTypes[event.Type] = true
+		seenLocations[event.Location.Name] = true
+	}
+	for _, event := range fetchedEvents {
+		if !seenTypes[event.Type] {
+			seenTypes[event.Type] = true
+			types = append(types, event.Type)
+		}
+		if !seenLocations[event.Location.Name] {
+			seenLocations[event.Location.Name] = true
+			locations = append(locations, event.Location.Name)
+		}
+	}
+	return types, locations
+}
+
+func keyUsage(events []Event, key func(Event) string, known func(string) bool) []KeyUsage {
+	usages := make(map[string]*KeyUsage)
+	for _, event := range events {
+		name := key(event)
+		if name == "" {
+			continue
+		}
+		usage, ok := usages[name]
+		if !ok {
+			usage = &KeyUsage{Key: name, Known: known(name)}
+			usages[name] = usage
+		}
+		usage.Count++
+		datetime := event.Time()
+		if usage.FirstSeen.IsZero() || datetime.Before(usage.FirstSeen) {
+			usage.FirstSeen = datetime
+		}
+		if datetime.After(usage.LastSeen) {
+			usage.LastSeen = datetime
+		}
+	}
+
+	sorted := make([]KeyUsage, 0, len(usages))
+	for _, usage := range usages {
+		sorted = append(sorted, *usage)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Known != sorted[j].Known {
+			return sorted[i].Known
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+	return sorted
+}
@@ -107,10 +107,14 @@ var (
 		"Våldtäkt, försök",
 		"Vållande till kroppsskada",
 	}
-
-	LocationKeys = GetLocationKeys(AllEventsSlice())
 )
 
+// DatetimeLayout is the layout of Event.Datetime as delivered by the API
+const DatetimeLayout = "2006-01-02 15:04:05 -07:00"
+
+// ArchivePath is the JSON file where the events are stored
+const ArchivePath = "main/archive/archive.json"
+
 type Event struct {
 	Id       int    `json:"id"`
 	Datetime string `json:"datetime"`
@@ -122,6 +126,8 @@ type Event struct {
 		Name string `json:"name"`
 		Gps  string `json:"gps"`
 	} `json:"location"`
+	// Facts extracted from the summaries, they are not part of the API and are stored with the event in the archive
+	Facts *Facts `json:"facts,omitempty"`
 }
 
 // MarshalJSON Implements []byte() method
@@ -134,6 +140,21 @@ func (e *Event) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// ParseTime parses Event.Datetime, for the programs that must not stop on an event with a bad datetime
+func (e Event) ParseTime() (time.Time, error) {
+	return time.Parse(DatetimeLayout, e.Datetime)
+}
+
+// Time parses Event.Datetime and returns it as a time.Time, the program exits if it is invalid
+func (e Event) Time() time.Time {
+	t, err := e.ParseTime()
+	if err != nil {
+		fmt.Println("Failed to parse", e.Datetime)
+		log.Fatal(err)
+	}
+	return t
+}
+
 // ById Implements sort methods:
 // Len() int Less(i int, j int) bool Swap(i int, j int)
 type ById []Event
@@ -159,17 +180,7 @@ func (e ByDatetime) Len() int {
 }
 
 func (e ByDatetime) Less(i, j int) bool {
-	t1, err := time.Parse("2006-01-02 15:04:05 -07:00", e[i].Datetime)
-	if err != nil {
-		fmt.Println("Failed to parse", e[i].Datetime)
-		log.Fatal(err)
-	}
-	t2, err := time.Parse("2006-01-02 15:04:05 -07:00", e[j].Datetime)
-	if err != nil {
-		fmt.Println("Failed to parse", e[j].Datetime)
-		log.Fatal(err)
-	}
-	return t1.Before(t2)
+	return e[i].Time().Before(e[j].Time())
 }
 
 func (e ByDatetime) Swap(i int, j int) {
@@ -213,23 +224,36 @@ AllEventsSlice Merges archive- and new data and returns the merged slice also
 datetime sorted
 */
 func AllEventsSlice() []Event {
+	events, err := AllEvents()
+	if err != nil {
+		fmt.Println("An error occurred while fetching events from the API")
+		log.Fatal(err)
+	}
+	return events
+}
+
+// AllEvents merges the archived events with the events fetched from the API, sorted by datetime.
+// If the API can not be reached the archived events are returned together with the error.
+func AllEvents() ([]Event, error) {
 	// Archive data
 	eventsInArchive := GetArchive()
 	// New data
-	newEvents := GetNewEvents()
+	newEvents, err := FetchNewEvents()
+	if err != nil {
+		sort.Sort(ByDatetime(eventsInArchive))
+		return eventsInArchive, err
+	}
 	// Merge old event with new events. Also, save the amount of duplicates in variable (could be useful)
 	mergedEvents, _ := MergeEvents(eventsInArchive, newEvents)
 
 	sort.Sort(ByDatetime(mergedEvents))
 
-	return mergedEvents
+	return mergedEvents, nil
 }
 
-// GetLocationKeys takes a slice of Event structs, sorts them by location and returns a slice of unique location keys.
+// GetLocationKeys takes a slice of Event structs and returns the sorted unique location keys, the slice is not modified.
 func GetLocationKeys(events []Event) []string {
 
-	sort.Sort(ByLocation(events))
-
 	availableLocations := make(map[string]int)
 
 	var uniqueLocations []string
@@ -263,11 +287,12 @@ func SubCatType(events []Event, key string) []Event {
 }
 
 // SubCatLocation takes a slice of Event structs and a string key, and returns a slice of events with matching location.
+// The key may be a county, which matches the events of all its municipalities, see Event.InLocation.
 func SubCatLocation(events []Event, key string) []Event {
 	var subCategory []Event
 
 	for _, event := range events {
-		if strings.EqualFold(event.Location.Name, key) {
+		if event.InLocation(key) {
 			subCategory = append(subCategory, event)
 		}
 	}
@@ -297,20 +322,78 @@ func MergeEvents(eventsInArchive []Event, newEvents []Event) ([]Event, int) {
 	return mergedEvents, duplicates
 }
 
+// NewEvents returns the fetched events whose Id is not already in the archive
+func NewEvents(eventsInArchive []Event, fetchedEvents []Event) []Event {
+	archived := make(map[int]bool)
+	for _, event := range eventsInArchive {
+		archived[event.Id] = true
+	}
+	var newEvents []Event
+	for _, event := range fetchedEvents {
+		if !archived[event.Id] {
+			archived[event.Id] = true
+			newEvents = append(newEvents, event)
+		}
+	}
+	return newEvents
+}
+
 // returns the new data of type event
 func GetNewEvents() []Event {
-	newData := bytesFromAPI()
-	newEvents := eventCreator(newData)
+	newEvents, err := FetchNewEvents()
+	if err != nil {
+		fmt.Println("An error occurred while fetching events from the API")
+		log.Fatal(err)
+	}
 	return newEvents
 }
 
+// FetchNewEvents returns the new data of type event, or an error if the API could not be reached.
+// It is used by long running programs that should survive a failed fetch.
+func FetchNewEvents() ([]Event, error) {
+	var data []byte
+	var fetchErr error
+	c := colly.NewCollector(colly.AllowURLRevisit())
+	c.OnError(func(response *colly.Response, err error) {
+		fetchErr = err
+	})
+	c.OnResponse(func(response *colly.Response) {
+		data = response.Body
+	})
+	if err := c.Visit("https://polisen.se/api/events"); err != nil {
+		return nil, err
+	}
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	var events []Event
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
 // Returns the events that are currently stored in the archive
 func GetArchive() []Event {
-	data, _ := os.ReadFile("main/archive/archive.json")
+	data, _ := os.ReadFile(ArchivePath)
 	eventsInArchive := eventCreator(data)
 	return eventsInArchive
 }
 
+// LoadArchive returns the events that are currently stored in the archive, or an error if the
+// archive could not be read. It is used by long running programs that should survive a bad read.
+func LoadArchive() ([]Event, error) {
+	data, err := os.ReadFile(ArchivePath)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
 // From byte data to structs of type event
 func eventCreator(data []byte) []Event {
 	var events []Event
@@ -321,28 +404,12 @@ func eventCreator(data []byte) []Event {
 	return events
 }
 
-// Scrapes API-data and returns byte arr
-func bytesFromAPI() []byte {
-	var data []byte
-	c := colly.NewCollector()
-	c.OnError(func(response *colly.Response, err error) {
-		log.Fatal(err)
-	})
-	c.OnResponse(func(response *colly.Response) {
-		data = response.Body
-	})
-	if err := c.Visit("https://polisen.se/api/events"); err != nil {
-		log.Fatal(err)
-	}
-	return data
-}
-
 // SaveInArchive saves a slice of events in a JSON file located at "main/archive/archive.json".
 // If the file doesn't exist, it creates the file. If the file already exists, it appends the data.
 // The function returns an error if any error occurs during file operations.
 func SaveInArchive(events []Event) {
 	// Creates file if necessary, appends if file exists
-	file, err := os.OpenFile("main/archive/archive.json", os.O_CREATE|os.O_RDWR, 0644)
+	file, err := os.OpenFile(ArchivePath, os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
 		fmt.Println("An error occurred while trying to open the archive")
 		log.Fatal(err)
@@ -364,9 +431,14 @@ func SaveInArchive(events []Event) {
 	}
 }
 
+// PageURL takes Event.URL value and returns the absolute address of the event page on polisen.se
+func PageURL(URL string) string {
+	return "https://polisen.se/" + strings.TrimPrefix(URL, "/")
+}
+
 // Takes Event.URL value and opens a webpage with the corresponding extensive event summary
 func OpenInBrowser(URL string) {
-	url := "https://polisen.se/" + URL
+	url := PageURL(URL)
 	if err := browser.OpenURL(url); err != nil {
 		fmt.Println("An error occurred while trying to open the event summary page.")
 	}
@@ -376,14 +448,25 @@ func OpenInBrowser(URL string) {
 // Takes a string representing the URL of the news article as input.
 // Gives a string representing the extended summary of the news article as output.
 func GetExtendedSummary(URL string) string {
-	url := "https://polisen.se/" + URL
+	summary, err := FetchExtendedSummary(URL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return summary
+}
 
-	var summary string
+// FetchExtendedSummary scrapes the extended summary of an event from its page on polisen.se.
+// Every paragraph, list item and heading of the text is on its own line.
+func FetchExtendedSummary(URL string) (string, error) {
+	url := PageURL(URL)
+
+	var paragraphs []string
+	var scrapeErr error
 
 	c := colly.NewCollector(colly.AllowURLRevisit())
 
 	c.OnError(func(response *colly.Response, err error) {
-		log.Fatal(err)
+		scrapeErr = err
 	})
 
 	c.OnRequest(func(request *colly.Request) {
@@ -391,12 +474,21 @@ func GetExtendedSummary(URL string) string {
 	})
 
 	c.OnHTML("#main-content > div.body-content-wrapper > div > div > div > div > div.event-content > div.text-body.editorial-html", func(e *colly.HTMLElement) {
-		summary = strings.TrimSpace(e.Text)
+		e.ForEach("p, li, h2, h3, h4", func(_ int, element *colly.HTMLElement) {
+			if text := strings.TrimSpace(element.Text); text != "" {
+				paragraphs = append(paragraphs, text)
+			}
+		})
+		if len(paragraphs) == 0 {
+			paragraphs = append(paragraphs, strings.TrimSpace(e.Text))
+		}
 	})
 
 	if err := c.Visit(url); err != nil {
-		log.Fatal(err)
+		return "", err
 	}
-
-	return summary
+	if scrapeErr != nil {
+		return "", scrapeErr
+	}
+	return strings.Join(paragraphs, "\n"), nil
 }
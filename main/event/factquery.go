@@ -0,0 +1,118 @@
+// This file parses and evaluates conditions on the extracted facts of the events,
+// for example "arrests > 0" or "weapon = kniv and age < 18"
+package event
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FactFields are the names that can be used in a fact condition
+var FactFields = []string{"injured", "arrests", "age", "vehicle", "weapon", "street"}
+
+// FactCondition compares an extracted fact with a value
+type FactCondition struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+var (
+	conditionPattern = regexp.MustCompile(`^\s*(\w+)\s*(>=|<=|!=|=|>|<)\s*(.+?)\s*$`)
+	conditionSplit   = regexp.MustCompile(`(?i)\s+and\s+|\s*,\s*`)
+)
+
+// ParseFactQuery parses conditions separated by "and" or commas, every condition has to match.
+// Numbers (injured, arrests and age) are compared with =, !=, <, <=, > and >=, an event matches an age
+// condition if one of its ages does. Texts (vehicle, weapon and street) are compared with = and !=.
+func ParseFactQuery(text string) ([]FactCondition, error) {
+	var conditions []FactCondition
+	for _, part := range conditionSplit.Split(strings.TrimSpace(text), -1) {
+		match := conditionPattern.FindStringSubmatch(part)
+		if match == nil {
+			return nil, fmt.Errorf("invalid fact condition %q, expected for example \"arrests > 0\"", part)
+		}
+		condition := FactCondition{Field: strings.ToLower(match[1]), Operator: match[2], Value: match[3]}
+		if condition.Field == "arrested" {
+			condition.Field = "arrests"
+		}
+		switch condition.Field {
+		case "injured", "arrests", "age":
+			if _, err := strconv.Atoi(condition.Value); err != nil {
+				return nil, fmt.Errorf("invalid fact condition %q, %s is compared with a number", part, condition.Field)
+			}
+		case "vehicle", "weapon", "street":
+			if condition.Operator != "=" && condition.Operator != "!=" {
+				return nil, fmt.Errorf("invalid fact condition %q, %s is compared with = or !=", part, condition.Field)
+			}
+		default:
+			return nil, fmt.Errorf("unknown fact %q, expected one of %s", condition.Field, strings.Join(FactFields, ", "))
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// Matches reports whether the facts satisfy the condition
+func (c FactCondition) Matches(facts Facts) bool {
+	switch c.Field {
+	case "injured":
+		return c.compare(facts.Injured)
+	case "arrests":
+		return c.compare(facts.Arrested)
+	case "age":
+		for _, age := range facts.Ages {
+			if c.compare(age) {
+				return true
+			}
+		}
+		return false
+	case "vehicle":
+		return c.contains(facts.Vehicles, strings.EqualFold)
+	case "weapon":
+		return c.contains(facts.Weapons, strings.EqualFold)
+	case "street":
+		return c.contains(facts.Streets, func(street, value string) bool {
+			return strings.Contains(strings.ToLower(street), strings.ToLower(value))
+		})
+	}
+	return false
+}
+
+// String returns the condition as it is written in a query
+func (c FactCondition) String() string {
+	return c.Field + " " + c.Operator + " " + c.Value
+}
+
+func (c FactCondition) compare(fact int) bool {
+	value, _ := strconv.Atoi(c.Value)
+	switch c.Operator {
+	case "=":
+		return fact == value
+	case "!=":
+		return fact != value
+	case "<":
+		return fact < value
+	case "<=":
+		return fact <= value
+	case ">":
+		return fact > value
+	case ">=":
+		return fact >= value
+	}
+	return false
+}
+
+// contains reports for = whether one of the facts equals the value, and for != whether none does
+func (c FactCondition) contains(facts []string, equal func(fact, value string) bool) bool {
+	found := false
+	for _, fact := range facts {
+		if equal(fact, c.Value) {
+			found = true
+			break
+		}
+	}
+	return found == (c.Operator == "=")
+}
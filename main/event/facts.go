@@ -0,0 +1,286 @@
+// This file extracts facts from the Swedish text of the events with rule based patterns, for example
+// how many people were arrested or injured, the ages that are mentioned and the weapons used
+package event
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Facts are the facts extracted from the summary and the extended summary of an event.
+// Injured and Arrested are the largest numbers of people mentioned, zero if none is mentioned.
+type Facts struct {
+	Injured  int      `json:"injured,omitempty"`
+	Arrested int      `json:"arrested,omitempty"`
+	Ages     []int    `json:"ages,omitempty"`
+	Vehicles []string `json:"vehicles,omitempty"`
+	Weapons  []string `json:"weapons,omitempty"`
+	Streets  []string `json:"streets,omitempty"`
+}
+
+// Number of words before a verb that are searched for the number of people, "två män i 20-årsåldern greps"
+const countWindow = 6
+
+// Numbers that are written with letters in the summaries
+var numberWords = map[string]int{
+	"en": 1, "ett": 1, "två": 2, "tre": 3, "fyra": 4, "fem": 5,
+	"sex": 6, "sju": 7, "åtta": 8, "nio": 9, "tio": 10,
+}
+
+// Words that state that nobody was arrested or injured, for example "ingen skadades"
+var negations = map[string]bool{"ingen": true, "inga": true, "inte": true, "ej": true}
+
+// Forms of the verbs for arresting someone, the active forms are followed by who was arrested
+var (
+	arrestWords       = wordSet("grips", "greps", "gripits", "gripen", "gripna", "anhålls", "anhölls", "anhållits", "anhållen", "anhållna")
+	activeArrestWords = wordSet("griper", "grep", "gripit", "anhåller", "anhöll", "anhållit")
+	injuryWords       = wordSet("skadas", "skadades", "skadats", "skadad", "skadade", "knivskuren", "knivskurna", "skottskadad", "skottskadade")
+)
+
+// Words around a number that make it a time or a duration instead of a number of people,
+// "vid 23-tiden", "klockan 02.15", "strax efter 14" and "två timmar senare"
+var (
+	timeWords     = wordSet("kl", "klockan", "vid", "efter", "före")
+	durationWords = wordSet("sekunder", "minut", "minuter", "timme", "timmar", "timmen", "dag", "dagar", "dygn", "vecka", "veckor", "månad", "månader")
+)
+
+// Nouns of people, a number after a passive verb is only the number of people if one of them follows,
+// "greps två personer" but not "greps en timme senare"
+var personWords = wordSet("man", "män", "kvinna", "kvinnor", "person", "personer", "pojke", "pojkar", "flicka", "flickor",
+	"yngling", "ynglingar", "tonåring", "tonåringar", "individ", "individer", "barn", "ungdom", "ungdomar", "förare",
+	"gärningsman", "gärningsmän")
+
+// Vehicles and weapons by their base form, inflected forms such as "bilen" or "knivar" are recognised too,
+// and for weapons also compounds such as "knivrån"
+var (
+	vehicles = []string{"personbil", "bil", "lastbil", "buss", "motorcykel", "mc", "moped", "cykel", "elsparkcykel",
+		"traktor", "taxi", "spårvagn", "tåg", "båt", "fyrhjuling", "snöskoter", "husbil", "ambulans"}
+	weapons = []string{"kniv", "pistol", "skjutvapen", "vapen", "gevär", "hagelgevär", "revolver", "yxa", "machete",
+		"batong", "kofot", "slagträ", "sprängmedel", "handgranat", "luftvapen", "softairvapen", "pepparsprej"}
+	inflections = []string{"", "en", "n", "et", "ar", "arna", "er", "erna", "na", "or", "orna"}
+)
+
+var (
+	agePattern    = regexp.MustCompile(`(\d{1,3})[- ]?år(?:ig|ing|s)`)
+	yearsPattern  = regexp.MustCompile(`(\d{1,3}) år gammal`)
+	streetPattern = regexp.MustCompile(`\p{Lu}[\p{L}-]*(?:gatan|vägen|leden|gränd|torget|allén|backen|stigen|esplanaden|platsen|kajen|bron)`)
+	roadPattern   = regexp.MustCompile(`\b(?:E\s?\d{1,2}|(?:[Rr]iksväg|[Ll]änsväg|[Vv]äg) \d{1,4})\b`)
+	// A word, a clock time such as "02.15" and a number with a suffix such as "23-tiden" are one word each
+	wordPattern = regexp.MustCompile(`\d+(?:[.:]\d+)*(?:-\pL+)?|[\pL\d]+`)
+)
+
+// ExtractFacts extracts the facts from a Swedish text
+func ExtractFacts(text string) Facts {
+	var facts Facts
+	words := wordPattern.FindAllString(strings.ToLower(text), -1)
+	for i, word := range words {
+		switch {
+		case arrestWords[word]:
+			facts.Arrested = maxInt(facts.Arrested, countPassive(words, i))
+		case activeArrestWords[word]:
+			facts.Arrested = maxInt(facts.Arrested, countAfter(words, i))
+		case injuryWords[word]:
+			facts.Injured = maxInt(facts.Injured, countPassive(words, i))
+		case (word == "sjukhus" || word == "sjukhuset") && i > 0 && words[i-1] == "till":
+			facts.Injured = maxInt(facts.Injured, countBefore(words, i))
+		}
+	}
+
+	for _, pattern := range []*regexp.Regexp{agePattern, yearsPattern} {
+		for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+			if age, err := strconv.Atoi(match[1]); err == nil && age <= 110 {
+				facts.Ages = appendUnique(facts.Ages, age)
+			}
+		}
+	}
+	sort.Ints(facts.Ages)
+	facts.Vehicles = findWords(words, vehicles, false)
+	facts.Weapons = findWords(words, weapons, true)
+	for _, pattern := range []*regexp.Regexp{streetPattern, roadPattern} {
+		for _, street := range pattern.FindAllString(text, -1) {
+			facts.Streets = appendUniqueString(facts.Streets, street)
+		}
+	}
+	return facts
+}
+
+// ExtractedFacts returns the facts stored with the event, or extracts them from the summary
+// if they have not been stored, see EnrichFacts
+func (e Event) ExtractedFacts() Facts {
+	if e.Facts != nil {
+		return *e.Facts
+	}
+	return ExtractFacts(e.Summary)
+}
+
+// EnrichFacts extracts and stores the facts of the events from their summary and, if cache is not nil,
+// from their extended summary in the cache
+func EnrichFacts(events []Event, cache *SummaryCache) {
+	for i := range events {
+		text := events[i].Summary
+		if cache != nil {
+			if extended, ok := cache.Get(events[i]); ok {
+				text += "\n" + extended
+			}
+		}
+		facts := ExtractFacts(text)
+		events[i].Facts = &facts
+	}
+}
+
+// String returns the facts as for example "2 arrested, ages 17, 20, weapons kniv", empty if there are none
+func (f Facts) String() string {
+	var parts []string
+	if f.Arrested > 0 {
+		parts = append(parts, strconv.Itoa(f.Arrested)+" arrested")
+	}
+	if f.Injured > 0 {
+		parts = append(parts, strconv.Itoa(f.Injured)+" injured")
+	}
+	if len(f.Ages) > 0 {
+		ages := make([]string, len(f.Ages))
+		for i, age := range f.Ages {
+			ages[i] = strconv.Itoa(age)
+		}
+		parts = append(parts, "ages "+strings.Join(ages, ", "))
+	}
+	if len(f.Vehicles) > 0 {
+		parts = append(parts, "vehicles "+strings.Join(f.Vehicles, ", "))
+	}
+	if len(f.Weapons) > 0 {
+		parts = append(parts, "weapons "+strings.Join(f.Weapons, ", "))
+	}
+	if len(f.Streets) > 0 {
+		parts = append(parts, "streets "+strings.Join(f.Streets, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// countPassive returns the number of people of a passive verb, who come after the verb in
+// "klockan 02.15 greps två personer" and before it in "två män greps"
+func countPassive(words []string, i int) int {
+	for j := i + 1; j < len(words) && j <= i+3; j++ {
+		n, ok := count(words, j)
+		if !ok {
+			continue
+		}
+		for k := j + 1; k < len(words) && k <= j+2; k++ {
+			if personWords[words[k]] {
+				return n
+			}
+		}
+		break
+	}
+	return countBefore(words, i)
+}
+
+// countBefore returns the number of people stated just before the verb at index i, one if no number
+// is stated and zero if the verb is negated. Being taken "till sjukhus" counts as being injured.
+func countBefore(words []string, i int) int {
+	for j := i - 1; j >= 0 && j >= i-countWindow; j-- {
+		if negations[words[j]] {
+			return 0
+		}
+		if n, ok := count(words, j); ok {
+			return n
+		}
+	}
+	return 1
+}
+
+// countAfter returns the number of people stated just after an active verb, "polisen grep två män"
+func countAfter(words []string, i int) int {
+	if i > 0 && negations[words[i-1]] || i+1 < len(words) && negations[words[i+1]] {
+		return 0
+	}
+	for j := i + 1; j < len(words) && j <= i+3; j++ {
+		if n, ok := count(words, j); ok {
+			return n
+		}
+	}
+	return 1
+}
+
+// count returns the number at index j if it can be a number of people, not a time, a duration or an age
+func count(words []string, j int) (int, bool) {
+	if j > 0 && timeWords[words[j-1]] {
+		return 0, false
+	}
+	// "en 20 år gammal man greps", the number of an age is not the number of people
+	if j+1 < len(words) && (durationWords[words[j+1]] || strings.HasPrefix(words[j+1], "år")) {
+		return 0, false
+	}
+	return number(words[j])
+}
+
+func number(word string) (int, bool) {
+	if n, ok := numberWords[word]; ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(word)
+	return n, err == nil && n < 100
+}
+
+// findWords returns the base forms whose inflected forms occur in the words, in the order of bases.
+// With compounds a word that starts with the base form counts too, "knivhuggen" mentions a kniv.
+func findWords(words []string, bases []string, compounds bool) []string {
+	present := wordSet(words...)
+	var found []string
+	for _, base := range bases {
+		if containsForm(present, words, base, compounds) {
+			found = append(found, base)
+		}
+	}
+	return found
+}
+
+func containsForm(present map[string]bool, words []string, base string, compounds bool) bool {
+	for _, inflection := range inflections {
+		if present[base+inflection] {
+			return true
+		}
+	}
+	if compounds {
+		for _, word := range words {
+			if strings.HasPrefix(word, base) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
+
+func appendUnique(values []int, value int) []int {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func appendUniqueString(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,36 @@
+package event
+
+import "testing"
+
+func TestExtractFactsCounts(t *testing.T) {
+	tests := []struct {
+		text              string
+		arrested, injured int
+	}{
+		{"Vid 23-tiden greps en man misstänkt för rån.", 1, 0},
+		{"Klockan 02.15 greps två personer efter ett inbrott.", 2, 0},
+		{"Strax efter 14 skadades en person i en trafikolycka.", 0, 1},
+		{"Kl. 03 greps en kvinna.", 1, 0},
+		{"Tre män i 20-årsåldern greps.", 3, 0},
+		{"En 17-årig pojke skadades lindrigt.", 0, 1},
+		{"Två timmar senare greps en man.", 1, 0},
+		{"Polisen grep två män vid 22-tiden.", 2, 0},
+		{"Ingen skadades och ingen greps.", 0, 0},
+		{"Fyra personer fördes till sjukhus.", 0, 4},
+		{"En man som suttit häktad i 3 år greps igen.", 1, 0},
+	}
+	for _, test := range tests {
+		facts := ExtractFacts(test.text)
+		if facts.Arrested != test.arrested || facts.Injured != test.injured {
+			t.Errorf("ExtractFacts(%q) = %d arrested, %d injured, want %d and %d",
+				test.text, facts.Arrested, facts.Injured, test.arrested, test.injured)
+		}
+	}
+}
+
+func TestExtractFactsAges(t *testing.T) {
+	facts := ExtractFacts("En 19-årig man och en 45 år gammal kvinna greps klockan 02.15.")
+	if len(facts.Ages) != 2 || facts.Ages[0] != 19 || facts.Ages[1] != 45 {
+		t.Errorf("Ages = %v, want [19 45]", facts.Ages)
+	}
+}
@@ -0,0 +1,222 @@
+// This file defines Filter, the selection of events that is shared between the
+// GUI, the terminal program and the statistics
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SavedFiltersPath is the JSON file that stores the saved filters by name
+const SavedFiltersPath = "main/config/filters.json"
+
+// Filter describes a selection of events. Empty fields match every event.
+type Filter struct {
+	Type     string `json:"type,omitempty"`
+	Location string `json:"location,omitempty"`
+	// Category selects the events whose type is in the category of the taxonomy, for example "violence"
+	Category string `json:"category,omitempty"`
+	// MinSeverity selects the events whose type has at least this severity
+	MinSeverity int `json:"min_severity,omitempty"`
+	// Near selects the events within a radius of a point
+	Near *Circle `json:"near,omitempty"`
+	// Box selects the events inside a bounding box
+	Box *BoundingBox `json:"box,omitempty"`
+	// Since selects the events of the last period before now, for example "24h" or "7d"
+	Since string `json:"since,omitempty"`
+	// Facts selects the events by their extracted facts, for example "arrests > 0" or "weapon = kniv"
+	Facts string `json:"facts,omitempty"`
+}
+
+// IsEmpty reports whether the filter matches every event
+func (f Filter) IsEmpty() bool {
+	return f == Filter{}
+}
+
+// Validate returns an error if a field of the filter can not be used
+func (f Filter) Validate() error {
+	if f.Near != nil {
+		if err := f.Near.Validate(); err != nil {
+			return err
+		}
+	}
+	if f.Box != nil {
+		if err := f.Box.Validate(); err != nil {
+			return err
+		}
+	}
+	if f.Since != "" {
+		if _, err := ParseSince(f.Since); err != nil {
+			return err
+		}
+	}
+	if f.Facts != "" {
+		if _, err := ParseFactQuery(f.Facts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the event is selected by the filter
+func (f Filter) Matches(event Event) bool {
+	return f.matcher()(event)
+}
+
+// Apply returns the events matching the filter, in the order of the given slice
+func (f Filter) Apply(events []Event) []Event {
+	if f.IsEmpty() {
+		return events
+	}
+	matches := f.matcher()
+	var filtered []Event
+	for _, event := range events {
+		if matches(event) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// ApplyIndexed returns the events of the index matching the filter. The geographic part of the
+// filter is answered by the index, so only the events in the area are tested for the other fields.
+func (f Filter) ApplyIndexed(index *SpatialIndex) []Event {
+	switch {
+	case f.Near != nil:
+		return f.Apply(index.WithinRadius(*f.Near))
+	case f.Box != nil:
+		return f.Apply(index.WithinBox(*f.Box))
+	default:
+		return f.Apply(index.events)
+	}
+}
+
+// matcher returns a function that tests an event against every field of the filter, the start of
+// the Since period and the fact conditions are computed once so all events are compared with the same time
+func (f Filter) matcher() func(Event) bool {
+	var since time.Time
+	if f.Since != "" {
+		if period, err := ParseSince(f.Since); err == nil {
+			since = time.Now().Add(-period)
+		}
+	}
+	var conditions []FactCondition
+	if f.Facts != "" {
+		conditions, _ = ParseFactQuery(f.Facts)
+	}
+	return func(event Event) bool {
+		if f.Type != "" && event.Type != f.Type {
+			return false
+		}
+		if f.Location != "" && !event.InLocation(f.Location) {
+			return false
+		}
+		if f.Category != "" && event.Category().ID != f.Category {
+			return false
+		}
+		if f.MinSeverity > 0 && event.Severity() < f.MinSeverity {
+			return false
+		}
+		if f.Near != nil && !f.Near.Contains(event) {
+			return false
+		}
+		if f.Box != nil && !f.Box.Contains(event) {
+			return false
+		}
+		if !since.IsZero() && event.Time().Before(since) {
+			return false
+		}
+		if len(conditions) > 0 {
+			facts := event.ExtractedFacts()
+			for _, condition := range conditions {
+				if !condition.Matches(facts) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+}
+
+// String returns a short human readable description of the filter
+func (f Filter) String() string {
+	if f.IsEmpty() {
+		return "All events"
+	}
+	var parts []string
+	if f.Type != "" {
+		parts = append(parts, f.Type)
+	}
+	if f.Location != "" {
+		parts = append(parts, f.Location)
+	}
+	if f.Category != "" {
+		parts = append(parts, GetTaxonomy().Category(f.Category).Label)
+	}
+	if f.MinSeverity > 0 {
+		parts = append(parts, fmt.Sprintf("severity %d+", f.MinSeverity))
+	}
+	if f.Near != nil {
+		parts = append(parts, fmt.Sprintf("within %g km of %g,%g", f.Near.RadiusKm, f.Near.Lat, f.Near.Lon))
+	}
+	if f.Box != nil {
+		parts = append(parts, fmt.Sprintf("inside %g,%g-%g,%g", f.Box.MinLat, f.Box.MinLon, f.Box.MaxLat, f.Box.MaxLon))
+	}
+	if f.Since != "" {
+		parts = append(parts, "last "+f.Since)
+	}
+	if f.Facts != "" {
+		parts = append(parts, f.Facts)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseSince parses a period such as "24h", "90m" or "7d"
+func ParseSince(text string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(text, "d"); ok {
+		value, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid period %q", text)
+		}
+		return time.Duration(value * float64(24*time.Hour)), nil
+	}
+	period, err := time.ParseDuration(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid period %q", text)
+	}
+	return period, nil
+}
+
+// LoadSavedFilters returns the saved filters by name, there are no saved filters if the file does not exist
+func LoadSavedFilters() (map[string]Filter, error) {
+	filters := make(map[string]Filter)
+	data, err := os.ReadFile(SavedFiltersPath)
+	if os.IsNotExist(err) {
+		return filters, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &filters); err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+// SaveFilter stores a filter under the given name, replacing any saved filter with the same name
+func SaveFilter(name string, filter Filter) error {
+	filters, err := LoadSavedFilters()
+	if err != nil {
+		return err
+	}
+	filters[name] = filter
+	data, err := json.MarshalIndent(filters, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(SavedFiltersPath, data, 0644)
+}
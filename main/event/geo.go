@@ -0,0 +1,39 @@
+// This file provides helpers for the GPS coordinates that every event carries in Location.Gps
+package event
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Mean radius of the earth in kilometres
+const earthRadiusKm = 6371.0
+
+// Coordinates parses Location.Gps ("lat,lon") and returns the latitude and longitude of the event.
+// ok is false if the event has no valid coordinates.
+func (e Event) Coordinates() (lat float64, lon float64, ok bool) {
+	parts := strings.Split(e.Location.Gps, ",")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	lon, errLon := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if errLat != nil || errLon != nil {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// Distance returns the great-circle distance in kilometres between two points, using the haversine formula
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLon := radians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
@@ -0,0 +1,103 @@
+// This file resolves the location of an event to the kommun and län it belongs to,
+// which makes it possible to search and aggregate the events by region
+package event
+
+import (
+	"project/main/geo"
+	"strings"
+)
+
+// Region is the municipality (kommun) and county (län) of an event. Municipality is nil when the
+// location of the event is a whole county, County is nil when the location could not be resolved.
+type Region struct {
+	Municipality *geo.Municipality
+	County       *geo.County
+}
+
+// Region resolves Location.Name to a municipality and county. Free-form place names that are neither
+// are resolved by the coordinates of the event, see LocatedRegion.
+func (e Event) Region() Region {
+	if municipality, ok := geo.LookupMunicipality(e.Location.Name); ok {
+		return regionOf(municipality)
+	}
+	if county, ok := geo.LookupCounty(e.Location.Name); ok {
+		return Region{County: &county}
+	}
+	region, _ := e.LocatedRegion()
+	return region
+}
+
+// LocatedRegion returns the region the coordinates of the event fall in. exact is false when the
+// municipality boundaries are not available and the municipality with the closest centre point is used.
+func (e Event) LocatedRegion() (region Region, exact bool) {
+	lat, lon, ok := e.Coordinates()
+	if !ok {
+		return Region{}, false
+	}
+	municipality, exact := geo.LocateMunicipality(lat, lon)
+	return regionOf(municipality), exact
+}
+
+// Distance from the centre of the stated municipality within which the coordinates are accepted
+// when the municipality boundaries are not available
+const mismatchDistanceKm = 15
+
+// LocationMismatch reports whether the coordinates of the event fall outside the location stated in
+// Location.Name. Without the municipality boundaries only municipalities are checked, and only
+// coordinates further than mismatchDistanceKm from the stated centre are reported.
+func (e Event) LocationMismatch() bool {
+	located, exact := e.LocatedRegion()
+	if located.Municipality == nil {
+		return false
+	}
+	if stated, ok := geo.LookupMunicipality(e.Location.Name); ok {
+		if located.Municipality.Code == stated.Code {
+			return false
+		}
+		if exact {
+			return true
+		}
+		lat, lon, _ := e.Coordinates()
+		return Distance(lat, lon, stated.Lat, stated.Lon) > mismatchDistanceKm
+	}
+	if stated, ok := geo.LookupCounty(e.Location.Name); ok && exact {
+		return located.County.Code != stated.Code
+	}
+	return false
+}
+
+// String returns the region as for example "Malmö kommun, Skåne län"
+func (r Region) String() string {
+	var parts []string
+	if r.Municipality != nil {
+		parts = append(parts, r.Municipality.Name+" kommun")
+	}
+	if r.County != nil {
+		parts = append(parts, r.County.Name)
+	}
+	if len(parts) == 0 {
+		return "Unknown region"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// InLocation reports whether the event happened in the named location. The search is hierarchical,
+// a county includes the events of its municipalities and a municipality includes the free-form places in it.
+func (e Event) InLocation(name string) bool {
+	if strings.EqualFold(e.Location.Name, name) {
+		return true
+	}
+	region := e.Region()
+	if municipality, ok := geo.LookupMunicipality(name); ok {
+		return region.Municipality != nil && region.Municipality.Code == municipality.Code
+	}
+	if county, ok := geo.LookupCounty(name); ok {
+		return region.County != nil && region.County.Code == county.Code
+	}
+	return false
+}
+
+func regionOf(municipality geo.Municipality) Region {
+	county, _ := geo.CountyByCode(municipality.CountyCode())
+	return Region{Municipality: &municipality, County: &county}
+}
@@ -0,0 +1,236 @@
+// This file provides geographic queries over events: radius and bounding box selection,
+// and a grid index that keeps those queries fast on large archives
+package event
+
+import (
+	"fmt"
+	"math"
+	"project/main/geo"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kilometres per degree of latitude
+const kmPerDegree = 111.32
+
+// Side of the cells of the spatial index in degrees
+const spatialCellSize = 0.1
+
+// MaxRadiusKm is the largest radius of a circle, enough to cover all of Sweden from any point in it
+const MaxRadiusKm = 2000
+
+// Circle is the area within RadiusKm kilometres of a point
+type Circle struct {
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	RadiusKm float64 `json:"radius_km"`
+}
+
+// Contains reports whether the coordinates of the event are inside the circle
+func (c Circle) Contains(event Event) bool {
+	lat, lon, ok := event.Coordinates()
+	return ok && Distance(c.Lat, c.Lon, lat, lon) <= c.RadiusKm
+}
+
+// Validate returns an error if the centre is not a valid position or the radius is negative or above MaxRadiusKm
+func (c Circle) Validate() error {
+	if err := validatePosition(c.Lat, c.Lon); err != nil {
+		return err
+	}
+	if !isFinite(c.RadiusKm) || c.RadiusKm < 0 || c.RadiusKm > MaxRadiusKm {
+		return fmt.Errorf("invalid radius %v km, expected at most %d km", c.RadiusKm, MaxRadiusKm)
+	}
+	return nil
+}
+
+// Bounds returns the smallest bounding box around the circle
+func (c Circle) Bounds() BoundingBox {
+	dLat := c.RadiusKm / kmPerDegree
+	dLon := c.RadiusKm / (kmPerDegree * math.Max(math.Cos(radians(c.Lat)), 0.01))
+	return BoundingBox{MinLat: c.Lat - dLat, MinLon: c.Lon - dLon, MaxLat: c.Lat + dLat, MaxLon: c.Lon + dLon}
+}
+
+// BoundingBox is the area between two latitudes and two longitudes
+type BoundingBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+// Validate returns an error if a corner is not a valid position or a minimum is above its maximum
+func (b BoundingBox) Validate() error {
+	if err := validatePosition(b.MinLat, b.MinLon); err != nil {
+		return err
+	}
+	if err := validatePosition(b.MaxLat, b.MaxLon); err != nil {
+		return err
+	}
+	if b.MinLat > b.MaxLat || b.MinLon > b.MaxLon {
+		return fmt.Errorf("invalid bounding box, the minimum latitude and longitude must not be above the maximum")
+	}
+	return nil
+}
+
+// Contains reports whether the coordinates of the event are inside the box
+func (b BoundingBox) Contains(event Event) bool {
+	lat, lon, ok := event.Coordinates()
+	return ok && lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// ParsePoint parses "lat,lon", or the name of a municipality or county whose centre point is used
+func ParsePoint(text string) (lat float64, lon float64, err error) {
+	parts := strings.Split(text, ",")
+	if len(parts) == 2 {
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lon, errLon := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if errLat == nil && errLon == nil {
+			return lat, lon, validatePosition(lat, lon)
+		}
+	}
+	if municipality, ok := geo.LookupMunicipality(text); ok {
+		return municipality.Lat, municipality.Lon, nil
+	}
+	if county, ok := geo.LookupCounty(text); ok {
+		return county.Lat, county.Lon, nil
+	}
+	return 0, 0, fmt.Errorf("%q is neither lat,lon nor a kommun or län", text)
+}
+
+// ParseDistance parses a distance such as "5km", "500m" or "5" (kilometres) and returns it in kilometres
+func ParseDistance(text string) (float64, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	factor := 1.0
+	if strings.HasSuffix(text, "km") {
+		text = strings.TrimSuffix(text, "km")
+	} else if strings.HasSuffix(text, "m") {
+		text = strings.TrimSuffix(text, "m")
+		factor = 0.001
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || !isFinite(value) || value < 0 {
+		return 0, fmt.Errorf("invalid distance %q", text)
+	}
+	return value * factor, nil
+}
+
+// ParseCircle parses a point as accepted by ParsePoint and a radius as accepted by ParseDistance
+func ParseCircle(point string, radius string) (*Circle, error) {
+	lat, lon, err := ParsePoint(point)
+	if err != nil {
+		return nil, err
+	}
+	radiusKm, err := ParseDistance(radius)
+	if err != nil {
+		return nil, err
+	}
+	circle := &Circle{Lat: lat, Lon: lon, RadiusKm: radiusKm}
+	return circle, circle.Validate()
+}
+
+// ParseBoundingBox parses "minLat,minLon,maxLat,maxLon"
+func ParseBoundingBox(text string) (*BoundingBox, error) {
+	parts := strings.Split(text, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid bounding box %q, expected minLat,minLon,maxLat,maxLon", text)
+	}
+	var values [4]float64
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bounding box %q: %w", text, err)
+		}
+		values[i] = value
+	}
+	box := &BoundingBox{MinLat: values[0], MinLon: values[1], MaxLat: values[2], MaxLon: values[3]}
+	if err := box.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid bounding box %q: %w", text, err)
+	}
+	return box, nil
+}
+
+// validatePosition returns an error if the latitude or longitude is not a number in its range
+func validatePosition(lat, lon float64) error {
+	if !isFinite(lat) || lat < -90 || lat > 90 {
+		return fmt.Errorf("invalid latitude %v, expected -90 to 90", lat)
+	}
+	if !isFinite(lon) || lon < -180 || lon > 180 {
+		return fmt.Errorf("invalid longitude %v, expected -180 to 180", lon)
+	}
+	return nil
+}
+
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+// SpatialIndex finds events by position. The events with coordinates are bucketed in a grid of cells,
+// so a query only looks at the events in the cells that overlap the queried area.
+type SpatialIndex struct {
+	events []Event
+	cells  map[[2]int][]int
+}
+
+// NewSpatialIndex indexes the events, the index keeps using the given slice
+func NewSpatialIndex(events []Event) *SpatialIndex {
+	index := &SpatialIndex{events: events, cells: make(map[[2]int][]int)}
+	for i, event := range events {
+		if lat, lon, ok := event.Coordinates(); ok {
+			cell := spatialCell(lat, lon)
+			index.cells[cell] = append(index.cells[cell], i)
+		}
+	}
+	return index
+}
+
+// WithinRadius returns the events within the circle, in the order of the indexed slice
+func (index *SpatialIndex) WithinRadius(circle Circle) []Event {
+	return index.query(circle.Bounds(), circle.Contains)
+}
+
+// WithinBox returns the events inside the bounding box, in the order of the indexed slice
+func (index *SpatialIndex) WithinBox(box BoundingBox) []Event {
+	return index.query(box, box.Contains)
+}
+
+// query tests the events of every cell overlapping the box. When the box covers more cells than there
+// are events, or is not a valid box, every event is tested instead.
+func (index *SpatialIndex) query(box BoundingBox, contains func(Event) bool) []Event {
+	if box.Validate() != nil || cellCount(box) > float64(len(index.events)) {
+		var events []Event
+		for _, event := range index.events {
+			if contains(event) {
+				events = append(events, event)
+			}
+		}
+		return events
+	}
+	low, high := spatialCell(box.MinLat, box.MinLon), spatialCell(box.MaxLat, box.MaxLon)
+	var matches []int
+	for i := low[0]; i <= high[0]; i++ {
+		for j := low[1]; j <= high[1]; j++ {
+			for _, position := range index.cells[[2]int{i, j}] {
+				if contains(index.events[position]) {
+					matches = append(matches, position)
+				}
+			}
+		}
+	}
+	sort.Ints(matches)
+	events := make([]Event, len(matches))
+	for i, position := range matches {
+		events[i] = index.events[position]
+	}
+	return events
+}
+
+// cellCount returns the number of cells of the index that overlap a valid box
+func cellCount(box BoundingBox) float64 {
+	low, high := spatialCell(box.MinLat, box.MinLon), spatialCell(box.MaxLat, box.MaxLon)
+	return float64(high[0]-low[0]+1) * float64(high[1]-low[1]+1)
+}
+
+func spatialCell(lat, lon float64) [2]int {
+	return [2]int{int(math.Floor(lat / spatialCellSize)), int(math.Floor(lon / spatialCellSize))}
+}
@@ -0,0 +1,106 @@
+package event
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseCircleRejectsInvalidInput(t *testing.T) {
+	tests := []struct{ point, radius string }{
+		{"NaN,17.6", "5km"},
+		{"59.8,Inf", "5km"},
+		{"91,17.6", "5km"},
+		{"59.8,-181", "5km"},
+		{"59.8,17.6", "NaN"},
+		{"59.8,17.6", "+Inf"},
+		{"59.8,17.6", "-1km"},
+		{"59.8,17.6", "1e9km"},
+		{"59.8,17.6", "2001"},
+	}
+	for _, test := range tests {
+		if circle, err := ParseCircle(test.point, test.radius); err == nil {
+			t.Errorf("ParseCircle(%q, %q) = %+v, want an error", test.point, test.radius, circle)
+		}
+	}
+	if _, err := ParseCircle("Uppsala", "2000km"); err != nil {
+		t.Errorf("ParseCircle(Uppsala, 2000km): %v", err)
+	}
+}
+
+func TestParseBoundingBoxRejectsInvalidInput(t *testing.T) {
+	for _, text := range []string{
+		"NaN,17,60,18",
+		"59,17,60,Inf",
+		"-Inf,17,60,18",
+		"60,17,59,18", // inverted latitudes
+		"59,18,60,17", // inverted longitudes
+		"-91,17,60,18",
+		"59,17,60,181",
+		"59,17,60",
+	} {
+		if box, err := ParseBoundingBox(text); err == nil {
+			t.Errorf("ParseBoundingBox(%q) = %+v, want an error", text, box)
+		}
+	}
+	if _, err := ParseBoundingBox("-90,-180,90,180"); err != nil {
+		t.Errorf("ParseBoundingBox of the whole world: %v", err)
+	}
+}
+
+func TestFilterValidateChecksCircleAndBox(t *testing.T) {
+	filters := []Filter{
+		{Near: &Circle{Lat: 59.8, Lon: 17.6, RadiusKm: 1e12}},
+		{Box: &BoundingBox{MinLat: 60, MinLon: 17, MaxLat: 59, MaxLon: 18}},
+	}
+	for _, filter := range filters {
+		if err := filter.Validate(); err == nil {
+			t.Errorf("Validate() accepted %s", filter)
+		}
+	}
+}
+
+func testEvents() []Event {
+	var events []Event
+	for i := 0; i < 50; i++ {
+		event := Event{Id: i}
+		lat, lon := 55.0+float64(i)*0.3, 11.0+float64(i)*0.2
+		event.Location.Gps = strconv.FormatFloat(lat, 'f', 4, 64) + "," + strconv.FormatFloat(lon, 'f', 4, 64)
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestSpatialIndexMatchesLinearScan(t *testing.T) {
+	events := testEvents()
+	index := NewSpatialIndex(events)
+	boxes := []BoundingBox{
+		{MinLat: 58, MinLon: 12, MaxLat: 61, MaxLon: 15},
+		{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180},
+		// Not valid boxes, they are answered by testing every event
+		{MinLat: 61, MinLon: 12, MaxLat: 58, MaxLon: 15},
+		{MinLat: -1e300, MinLon: -1e300, MaxLat: 1e300, MaxLon: 1e300},
+	}
+	for _, box := range boxes {
+		var want []Event
+		for _, event := range events {
+			if box.Contains(event) {
+				want = append(want, event)
+			}
+		}
+		got := index.WithinBox(box)
+		if len(got) != len(want) {
+			t.Errorf("WithinBox(%+v) found %d events, want %d", box, len(got), len(want))
+		}
+	}
+}
+
+func TestSpatialIndexLargeAreasAreFast(t *testing.T) {
+	index := NewSpatialIndex(testEvents())
+	start := time.Now()
+	index.WithinRadius(Circle{Lat: 89.9, Lon: 0, RadiusKm: MaxRadiusKm})
+	index.WithinBox(BoundingBox{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("queries of large areas took %v", elapsed)
+	}
+}
@@ -0,0 +1,86 @@
+// This file defines Store, which gives long running programs such as the server
+// access to the archive while other programs keep adding events to it
+package event
+
+import (
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store keeps the archived events in memory and reloads them when the archive file has changed
+type Store struct {
+	// Exclude, if set, removes events from the results of Query, for example the merged duplicates
+	Exclude func(events []Event) []Event
+
+	mu      sync.Mutex
+	modTime time.Time
+	events  []Event
+	index   *SpatialIndex
+}
+
+// NewStore creates a store, the archive is read on the first call to Events
+func NewStore() *Store {
+	return &Store{}
+}
+
+// Events returns the archived events sorted by datetime. If the archive can not be read the
+// previously read events are returned, so a half written archive does not stop the caller.
+// Events whose datetime can not be parsed are left out. The returned slice is shared and must not be modified.
+func (s *Store) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, err := os.Stat(ArchivePath)
+	if err != nil {
+		log.Println("An error occurred while reading the archive:", err)
+		return s.events
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return s.events
+	}
+	events, err := LoadArchive()
+	if err != nil {
+		log.Println("An error occurred while reading the archive:", err)
+		return s.events
+	}
+	events = withValidTime(events)
+	sort.Sort(ByDatetime(events))
+	s.events = events
+	s.index = NewSpatialIndex(events)
+	s.modTime = info.ModTime()
+	return s.events
+}
+
+// withValidTime leaves out and logs the events whose datetime can not be parsed, Event.Time would
+// stop the program when they are sorted, filtered or converted
+func withValidTime(events []Event) []Event {
+	valid := events[:0]
+	for _, event := range events {
+		if _, err := event.ParseTime(); err != nil {
+			log.Println("The event", event.Id, "is left out:", err)
+			continue
+		}
+		valid = append(valid, event)
+	}
+	return valid
+}
+
+// Query returns the archived events matching the filter that are not excluded, sorted by datetime.
+// Geographic filters are answered by a spatial index that is rebuilt when the archive changes.
+func (s *Store) Query(filter Filter) []Event {
+	events := s.Events()
+	s.mu.Lock()
+	index := s.index
+	s.mu.Unlock()
+	if index == nil {
+		events = filter.Apply(events)
+	} else {
+		events = filter.ApplyIndexed(index)
+	}
+	if s.Exclude != nil {
+		events = s.Exclude(events)
+	}
+	return events
+}
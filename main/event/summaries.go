@@ -0,0 +1,90 @@
+// This file caches the extended summaries scraped from polisen.se, so every event page is only
+// scraped once and the summaries can be used without network access
+package event
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// SummaryCachePath is the JSON file where the scraped extended summaries are stored by event Id
+const SummaryCachePath = "main/archive/summaries.json"
+
+// SummaryCache holds the extended summaries that have been scraped, it is safe for concurrent use
+type SummaryCache struct {
+	path      string
+	mu        sync.Mutex
+	summaries map[string]string
+	hits      int
+	misses    int
+}
+
+// OpenSummaryCache reads the cache stored at path, a missing file is an empty cache
+func OpenSummaryCache(path string) (*SummaryCache, error) {
+	cache := &SummaryCache{path: path, summaries: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cache.summaries); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// Get returns the cached extended summary of the event
+func (c *SummaryCache) Get(event Event) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	summary, ok := c.summaries[strconv.Itoa(event.Id)]
+	if ok {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	return summary, ok
+}
+
+// Stats returns how many lookups found a cached summary and how many did not
+func (c *SummaryCache) Stats() (hits int, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Fetch returns the cached extended summary of the event, or scrapes it and stores it in the cache
+func (c *SummaryCache) Fetch(event Event) (string, error) {
+	if summary, ok := c.Get(event); ok {
+		return summary, nil
+	}
+	summary, err := FetchExtendedSummary(event.Url)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.summaries[strconv.Itoa(event.Id)] = summary
+	return summary, c.save()
+}
+
+// save writes the cache to a temporary file that replaces the old file, so a crash never leaves half a cache
+func (c *SummaryCache) save() error {
+	data, err := json.MarshalIndent(c.summaries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	temporary := c.path + ".tmp"
+	if err := os.WriteFile(temporary, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temporary, c.path)
+}
@@ -0,0 +1,117 @@
+// This file maps the Swedish event types to categories, severity levels and English labels.
+// The mapping is read from an editable data file so new types can be classified without a new build.
+package event
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sort"
+	"sync"
+)
+
+// TaxonomyPath is the JSON file with the categories and the classification of every type
+const TaxonomyPath = "main/config/taxonomy.json"
+
+// OtherCategory is the category of types that are not in the taxonomy
+const OtherCategory = "other"
+
+// Category is a group of related types, Color is used for the category in charts and maps
+type Category struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Color string `json:"color"`
+}
+
+// TypeInfo is the classification of a type. Severity goes from 1 (minor) to 5 (most serious).
+type TypeInfo struct {
+	Category string `json:"category"`
+	Severity int    `json:"severity"`
+	Label    string `json:"label"`
+}
+
+// Taxonomy holds the categories and the classification of the types by their Swedish name
+type Taxonomy struct {
+	Categories []Category          `json:"categories"`
+	Types      map[string]TypeInfo `json:"types"`
+}
+
+var (
+	taxonomyOnce sync.Once
+	taxonomy     *Taxonomy
+)
+
+// LoadTaxonomy reads a taxonomy from a JSON file
+func LoadTaxonomy(path string) (*Taxonomy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Taxonomy
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetTaxonomy returns the taxonomy in TaxonomyPath, it is read the first time it is needed.
+// If the file can not be read every type is in the category "other".
+func GetTaxonomy() *Taxonomy {
+	taxonomyOnce.Do(func() {
+		t, err := LoadTaxonomy(TaxonomyPath)
+		if err != nil {
+			log.Println("The type taxonomy could not be read, all types are uncategorised:", err)
+			t = &Taxonomy{Types: make(map[string]TypeInfo)}
+		}
+		taxonomy = t
+	})
+	return taxonomy
+}
+
+// Info returns the classification of a type, types that are not in the taxonomy are
+// in the category "other" with severity 1 and their Swedish name as label
+func (t *Taxonomy) Info(eventType string) TypeInfo {
+	info, ok := t.Types[eventType]
+	if !ok {
+		return TypeInfo{Category: OtherCategory, Severity: 1, Label: eventType}
+	}
+	return info
+}
+
+// Category returns the category with the given id, unknown ids give a grey category labelled with the id
+func (t *Taxonomy) Category(id string) Category {
+	for _, category := range t.Categories {
+		if category.ID == id {
+			return category
+		}
+	}
+	return Category{ID: id, Label: id, Color: "#aaaaaa"}
+}
+
+// TypesIn returns the types of a category in alphabetical order
+func (t *Taxonomy) TypesIn(categoryID string) []string {
+	var types []string
+	for eventType, info := range t.Types {
+		if info.Category == categoryID {
+			types = append(types, eventType)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Category returns the category of the event's type
+func (e Event) Category() Category {
+	t := GetTaxonomy()
+	return t.Category(t.Info(e.Type).Category)
+}
+
+// Severity returns the severity of the event's type, from 1 (minor) to 5 (most serious)
+func (e Event) Severity() int {
+	return GetTaxonomy().Info(e.Type).Severity
+}
+
+// TypeLabel returns the English label of the event's type
+func (e Event) TypeLabel() string {
+	return GetTaxonomy().Info(e.Type).Label
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"project/main/export"
+	"strings"
+)
+
+// exportEvents writes the archived events matching the filter flags to a file or to stdout
+func exportEvents(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	formatName := flags.String("format", "geojson", "one of "+strings.Join(export.FormatNames(), ", "))
+	output := flags.String("o", "", "file to write, stdout if empty")
+	selection := addFilterFlags(flags)
+	flags.Parse(args)
+
+	format, ok := export.Formats[*formatName]
+	if !ok {
+		fmt.Println("Unknown format", *formatName+", expected one of", strings.Join(export.FormatNames(), ", "))
+		os.Exit(2)
+	}
+	data, err := format.Write(selection.Filter().Apply(reviewedArchive()))
+	if err != nil {
+		fmt.Println("An error occurred while exporting the events")
+		log.Fatal(err)
+	}
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Println("An error occurred while writing", *output)
+		log.Fatal(err)
+	}
+}
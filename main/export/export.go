@@ -0,0 +1,34 @@
+// This package exports events to file formats of other tools, for example GeoJSON for QGIS
+// and KML for Google Earth. The formats are shared by the CLI, the server and the GUI.
+package export
+
+import (
+	. "project/main/event"
+	"sort"
+)
+
+// Format is a file format that events can be exported to
+type Format struct {
+	Name        string
+	Extension   string
+	ContentType string
+	Write       func(events []Event) ([]byte, error)
+}
+
+// Formats are the available export formats by name
+var Formats = map[string]Format{
+	"geojson": {Name: "GeoJSON", Extension: ".geojson", ContentType: "application/geo+json", Write: GeoJSON},
+	"kml":     {Name: "KML", Extension: ".kml", ContentType: "application/vnd.google-earth.kml+xml", Write: KML},
+	"gpx":     {Name: "GPX", Extension: ".gpx", ContentType: "application/gpx+xml", Write: GPX},
+	"ics":     {Name: "iCalendar", Extension: ".ics", ContentType: "text/calendar; charset=utf-8", Write: ICalendar},
+}
+
+// FormatNames returns the names of the available formats in alphabetical order
+func FormatNames() []string {
+	var names []string
+	for name := range Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
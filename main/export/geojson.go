@@ -0,0 +1,76 @@
+package export
+
+import (
+	"encoding/json"
+	. "project/main/event"
+)
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string                 `json:"type"`
+	ID         int                    `json:"id"`
+	Geometry   *point                 `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type point struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// GeoJSON returns the events as a GeoJSON FeatureCollection with one Point feature per event.
+// The other fields of the event are properties, events without coordinates get a null geometry.
+func GeoJSON(events []Event) ([]byte, error) {
+	collection := featureCollection{Type: "FeatureCollection", Features: []feature{}}
+	for _, event := range events {
+		collection.Features = append(collection.Features, feature{
+			Type:       "Feature",
+			ID:         event.Id,
+			Geometry:   geometry(event),
+			Properties: properties(event),
+		})
+	}
+	return json.MarshalIndent(collection, "", "  ")
+}
+
+// geometry returns the GeoJSON point of the event, GeoJSON orders the coordinates as longitude, latitude
+func geometry(event Event) *point {
+	lat, lon, ok := event.Coordinates()
+	if !ok {
+		return nil
+	}
+	return &point{Type: "Point", Coordinates: [2]float64{lon, lat}}
+}
+
+// properties returns the fields of the event that are not part of the geometry
+func properties(event Event) map[string]interface{} {
+	category := event.Category()
+	props := map[string]interface{}{
+		"id":       event.Id,
+		"datetime": event.Datetime,
+		"name":     event.Name,
+		"summary":  event.Summary,
+		"url":      PageURL(event.Url),
+		"type":     event.Type,
+		"location": event.Location.Name,
+		"category": category.ID,
+		"severity": event.Severity(),
+		// simplestyle-spec property that map viewers such as geojson.io use for the marker colour
+		"marker-color": category.Color,
+	}
+	region := event.Region()
+	if region.Municipality != nil {
+		props["kommun"] = region.Municipality.Name
+		props["kommun_code"] = region.Municipality.Code
+	}
+	if region.County != nil {
+		props["lan"] = region.County.Name
+		props["lan_code"] = region.County.Code
+	}
+	props["location_mismatch"] = event.LocationMismatch()
+	return props
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"encoding/xml"
+	. "project/main/event"
+	"time"
+)
+
+type gpxDocument struct {
+	XMLName   xml.Name      `xml:"http://www.topografix.com/GPX/1/1 gpx"`
+	Version   string        `xml:"version,attr"`
+	Creator   string        `xml:"creator,attr"`
+	Waypoints []gpxWaypoint `xml:"wpt"`
+}
+
+type gpxWaypoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time"`
+	Name string  `xml:"name"`
+	Desc string  `xml:"desc"`
+	Link gpxLink `xml:"link"`
+	Type string  `xml:"type"`
+}
+
+type gpxLink struct {
+	Href string `xml:"href,attr"`
+}
+
+// GPX returns the events with coordinates as GPX waypoints
+func GPX(events []Event) ([]byte, error) {
+	document := gpxDocument{Version: "1.1", Creator: "swedish-police-events"}
+	for _, event := range events {
+		lat, lon, ok := event.Coordinates()
+		if !ok {
+			continue
+		}
+		document.Waypoints = append(document.Waypoints, gpxWaypoint{
+			Lat:  lat,
+			Lon:  lon,
+			Time: event.Time().UTC().Format(time.RFC3339),
+			Name: event.Name,
+			Desc: event.Summary,
+			Link: gpxLink{Href: PageURL(event.Url)},
+			Type: event.Type,
+		})
+	}
+	data, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,74 @@
+package export
+
+import (
+	"encoding/json"
+	"math"
+	"project/main/stats"
+)
+
+// Number of corners of the polygons that approximate the hotspot circles
+const circleCorners = 48
+
+// Smallest radius drawn for a hotspot, the events of a hotspot often share the same coordinates
+const minHotspotRadiusKm = 0.3
+
+type polygonFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   polygon                `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type polygon struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+// HotspotsGeoJSON returns the hotspots as a GeoJSON FeatureCollection with one Polygon feature per hotspot,
+// the circle around its centroid, so it can be shown as an overlay on a map of the events
+func HotspotsGeoJSON(hotspots []stats.Hotspot) ([]byte, error) {
+	collection := struct {
+		Type     string           `json:"type"`
+		Features []polygonFeature `json:"features"`
+	}{Type: "FeatureCollection", Features: []polygonFeature{}}
+
+	for i, hotspot := range hotspots {
+		ids := make([]int, len(hotspot.Events))
+		for j, event := range hotspot.Events {
+			ids[j] = event.Id
+		}
+		types := make(map[string]int)
+		for _, count := range stats.CountByType(hotspot.Events) {
+			types[count.Key] = count.Value
+		}
+		collection.Features = append(collection.Features, polygonFeature{
+			Type:     "Feature",
+			Geometry: circle(hotspot.Lat, hotspot.Lon, math.Max(hotspot.RadiusKm, minHotspotRadiusKm)),
+			Properties: map[string]interface{}{
+				"rank":      i + 1,
+				"count":     hotspot.Count(),
+				"radius_km": hotspot.RadiusKm,
+				"centroid":  [2]float64{hotspot.Lon, hotspot.Lat},
+				"event_ids": ids,
+				"types":     types,
+				// simplestyle-spec properties for the overlay
+				"fill":         "#e03131",
+				"fill-opacity": 0.3,
+				"stroke":       "#e03131",
+			},
+		})
+	}
+	return json.MarshalIndent(collection, "", "  ")
+}
+
+// circle returns a polygon approximating the circle, the corners are counter-clockwise as GeoJSON requires
+func circle(lat, lon, radiusKm float64) polygon {
+	const kmPerDegree = 111.32
+	ring := make([][2]float64, 0, circleCorners+1)
+	for i := 0; i <= circleCorners; i++ {
+		angle := 2 * math.Pi * float64(i%circleCorners) / circleCorners
+		dLat := radiusKm * math.Sin(angle) / kmPerDegree
+		dLon := radiusKm * math.Cos(angle) / (kmPerDegree * math.Cos(lat*math.Pi/180))
+		ring = append(ring, [2]float64{lon + dLon, lat + dLat})
+	}
+	return polygon{Type: "Polygon", Coordinates: [][][2]float64{ring}}
+}
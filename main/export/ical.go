@@ -0,0 +1,68 @@
+package export
+
+import (
+	"fmt"
+	. "project/main/event"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Layout of UTC date-times in iCalendar
+const icalTime = "20060102T150405Z"
+
+// ICalendar returns the events as an iCalendar document with one VEVENT per event
+func ICalendar(events []Event) ([]byte, error) {
+	var calendar strings.Builder
+	writeLine := func(line string) {
+		calendar.WriteString(foldLine(line))
+	}
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//swedish-police-events//EN")
+	writeLine("CALSCALE:GREGORIAN")
+	writeLine("METHOD:PUBLISH")
+	writeLine("X-WR-CALNAME:Swedish Police Events")
+	for _, event := range events {
+		start := event.Time().UTC().Format(icalTime)
+		writeLine("BEGIN:VEVENT")
+		writeLine("UID:" + strconv.Itoa(event.Id) + "@polisen.se")
+		writeLine("DTSTAMP:" + start)
+		writeLine("DTSTART:" + start)
+		writeLine("SUMMARY:" + escapeText(event.Name))
+		writeLine("DESCRIPTION:" + escapeText(event.Summary+"\n\n"+event.Type+", "+event.Location.Name))
+		writeLine("LOCATION:" + escapeText(event.Location.Name))
+		writeLine("CATEGORIES:" + escapeText(event.Type))
+		if lat, lon, ok := event.Coordinates(); ok {
+			writeLine(fmt.Sprintf("GEO:%s;%s", strconv.FormatFloat(lat, 'f', -1, 64), strconv.FormatFloat(lon, 'f', -1, 64)))
+		}
+		writeLine("URL:" + PageURL(event.Url))
+		writeLine("END:VEVENT")
+	}
+	writeLine("END:VCALENDAR")
+	return []byte(calendar.String()), nil
+}
+
+// escapeText escapes the characters that have a meaning in iCalendar text values
+func escapeText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// foldLine ends a content line with CRLF and folds it so that no line is longer than
+// 75 octets, without splitting a UTF-8 encoded character
+func foldLine(line string) string {
+	var folded strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		folded.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// The space that starts a continuation line counts towards its length
+		limit = 74
+	}
+	folded.WriteString(line + "\r\n")
+	return folded.String()
+}
@@ -0,0 +1,112 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"math"
+	. "project/main/event"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type kmlDocument struct {
+	XMLName  xml.Name `xml:"http://www.opengis.net/kml/2.2 kml"`
+	Document struct {
+		Name       string         `xml:"name"`
+		Styles     []kmlStyle     `xml:"Style"`
+		Placemarks []kmlPlacemark `xml:"Placemark"`
+	} `xml:"Document"`
+}
+
+type kmlStyle struct {
+	ID    string `xml:"id,attr"`
+	Color string `xml:"IconStyle>color"`
+}
+
+type kmlPlacemark struct {
+	Name        string    `xml:"name"`
+	Description string    `xml:"description"`
+	StyleURL    string    `xml:"styleUrl"`
+	When        string    `xml:"TimeStamp>when"`
+	Data        []kmlData `xml:"ExtendedData>Data"`
+	Coordinates string    `xml:"Point>coordinates"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// KML returns the events with coordinates as KML placemarks. Every type has its own style coloured
+// by the category of the type, the other fields of the event are stored as extended data.
+func KML(events []Event) ([]byte, error) {
+	var document kmlDocument
+	document.Document.Name = "Swedish Police Events"
+	styles := make(map[string]string)
+	for _, event := range events {
+		lat, lon, ok := event.Coordinates()
+		if !ok {
+			continue
+		}
+		if _, ok := styles[event.Type]; !ok {
+			styles[event.Type] = "type-" + strconv.Itoa(len(styles))
+		}
+		document.Document.Placemarks = append(document.Document.Placemarks, kmlPlacemark{
+			Name:        event.Name,
+			Description: event.Summary,
+			StyleURL:    "#" + styles[event.Type],
+			When:        event.Time().Format(time.RFC3339),
+			Data:        kmlExtendedData(event),
+			Coordinates: strconv.FormatFloat(lon, 'f', -1, 64) + "," + strconv.FormatFloat(lat, 'f', -1, 64),
+		})
+	}
+	for eventType, id := range styles {
+		document.Document.Styles = append(document.Document.Styles, kmlStyle{ID: id, Color: typeColor(eventType)})
+	}
+	sort.Slice(document.Document.Styles, func(i, j int) bool {
+		return document.Document.Styles[i].ID < document.Document.Styles[j].ID
+	})
+	data, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// kmlDataKeys are the properties of an event that are added to its placemark, in this order
+var kmlDataKeys = []string{"id", "datetime", "type", "category", "severity", "location", "url"}
+
+func kmlExtendedData(event Event) []kmlData {
+	props := properties(event)
+	data := make([]kmlData, 0, len(kmlDataKeys))
+	for _, key := range kmlDataKeys {
+		data = append(data, kmlData{Name: key, Value: fmt.Sprint(props[key])})
+	}
+	return data
+}
+
+// typeColor returns an opaque KML colour (aabbggrr) for a type. It is the colour of the type's
+// category in the taxonomy, or a colour derived from the type name if the category has no valid colour.
+func typeColor(eventType string) string {
+	color := Event{Type: eventType}.Category().Color
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(color, "#%02x%02x%02x", &r, &g, &b); err == nil && len(color) == 7 {
+		return fmt.Sprintf("ff%02x%02x%02x", b, g, r)
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(eventType))
+	r, g, b = hueToRGB(float64(hash.Sum32()%360) / 360)
+	return fmt.Sprintf("ff%02x%02x%02x", b, g, r)
+}
+
+// hueToRGB converts a hue between 0 and 1 to a fully saturated colour
+func hueToRGB(hue float64) (uint8, uint8, uint8) {
+	channel := func(offset float64) uint8 {
+		k := math.Mod(offset+hue*6, 6)
+		value := 1 - math.Max(0, math.Min(math.Min(k, 4-k), 1))
+		return uint8(value * 255)
+	}
+	return channel(5), channel(3), channel(1)
+}
@@ -0,0 +1,152 @@
+// This package generates Atom and RSS 2.0 feeds of events, for example of a saved filter,
+// so that the events can be followed in an ordinary feed reader
+package feed
+
+import (
+	"encoding/xml"
+	"net/url"
+	. "project/main/event"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MaxEntries is the number of events in a feed, the newest events are kept
+const MaxEntries = 200
+
+// GUID returns the stable identifier of an event in the feeds, it only depends on the event Id
+func GUID(event Event) string {
+	return "tag:polisen.se,2023:event/" + strconv.Itoa(event.Id)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title    string       `xml:"title"`
+	ID       string       `xml:"id"`
+	Updated  string       `xml:"updated"`
+	Link     atomLink     `xml:"link"`
+	Summary  string       `xml:"summary"`
+	Category atomCategory `xml:"category"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// Atom returns an Atom feed of the newest events. selfURL is the address the feed is published at, it
+// is also used as the id of the feed and may be empty.
+func Atom(title string, selfURL string, events []Event) ([]byte, error) {
+	events = newest(events)
+	feed := atomFeed{
+		Title:   title,
+		ID:      selfURL,
+		Updated: time.Now().Format(time.RFC3339),
+		Links:   []atomLink{{Href: "https://polisen.se/aktuellt/handelser/", Rel: "alternate"}},
+		Author:  atomAuthor{Name: "Polisen"},
+	}
+	if selfURL != "" {
+		feed.Links = append(feed.Links, atomLink{Href: selfURL, Rel: "self"})
+	} else {
+		feed.ID = "tag:polisen.se,2023:feed/" + url.PathEscape(title)
+	}
+	if len(events) > 0 {
+		feed.Updated = events[0].Time().Format(time.RFC3339)
+	}
+	for _, event := range events {
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:    event.Name,
+			ID:       GUID(event),
+			Updated:  event.Time().Format(time.RFC3339),
+			Link:     atomLink{Href: PageURL(event.Url), Rel: "alternate"},
+			Summary:  event.Summary,
+			Category: atomCategory{Term: event.Type},
+		})
+	}
+	return marshal(feed)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Category    string  `xml:"category"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// RSS returns an RSS 2.0 feed of the newest events
+func RSS(title string, events []Event) ([]byte, error) {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        "https://polisen.se/aktuellt/handelser/",
+			Description: "Händelser från polisen.se: " + title,
+		},
+	}
+	for _, event := range newest(events) {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       event.Name,
+			Link:        PageURL(event.Url),
+			Description: event.Summary,
+			GUID:        rssGUID{Value: GUID(event)},
+			PubDate:     event.Time().Format(time.RFC1123Z),
+			Category:    event.Type,
+		})
+	}
+	return marshal(feed)
+}
+
+// newest returns at most MaxEntries events sorted with the newest first, without changing the given slice
+func newest(events []Event) []Event {
+	sorted := append([]Event(nil), events...)
+	sort.Sort(sort.Reverse(ByDatetime(sorted)))
+	if len(sorted) > MaxEntries {
+		sorted = sorted[:MaxEntries]
+	}
+	return sorted
+}
+
+func marshal(feed interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	. "project/main/event"
+	"project/main/feed"
+	"strings"
+)
+
+// writeFeeds writes an Atom and an RSS feed for the saved filters to static files in a directory
+func writeFeeds(args []string) {
+	flags := flag.NewFlagSet("feeds", flag.ExitOnError)
+	dir := flags.String("dir", "feeds", "directory the feeds are written to")
+	name := flags.String("filter", "", "name of the saved filter, all saved filters if empty")
+	baseURL := flags.String("base-url", "", "URL the directory is published at, used for the self links")
+	flags.Parse(args)
+
+	filters, err := LoadSavedFilters()
+	if err != nil {
+		fmt.Println("An error occurred while reading the saved filters")
+		log.Fatal(err)
+	}
+	if *name != "" {
+		filter, ok := filters[*name]
+		if !ok {
+			fmt.Println("There is no saved filter named", *name)
+			os.Exit(1)
+		}
+		filters = map[string]Filter{*name: filter}
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	eventsInArchive := reviewedArchive()
+	for filterName, filter := range filters {
+		events := filter.Apply(eventsInArchive)
+		selfURL := ""
+		if *baseURL != "" {
+			selfURL = strings.TrimSuffix(*baseURL, "/") + "/" + filterName + ".atom"
+		}
+		atom, err := feed.Atom(filterName, selfURL, events)
+		if err != nil {
+			log.Fatal(err)
+		}
+		rss, err := feed.RSS(filterName, events)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for file, data := range map[string][]byte{filterName + ".atom": atom, filterName + ".rss": rss} {
+			if err := os.WriteFile(filepath.Join(*dir, file), data, 0644); err != nil {
+				fmt.Println("An error occurred while writing the feed", file)
+				log.Fatal(err)
+			}
+		}
+		fmt.Println("Wrote", len(events), "events for", filterName)
+	}
+}
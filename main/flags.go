@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	. "project/main/event"
+)
+
+// filterFlags are the flags that select events on a command
+type filterFlags struct {
+	filter Filter
+	near   string
+	radius string
+	box    string
+}
+
+// addFilterFlags defines the filter flags on a command, call Filter after the flags have been parsed
+func addFilterFlags(flags *flag.FlagSet) *filterFlags {
+	f := &filterFlags{}
+	flags.StringVar(&f.filter.Type, "type", "", "only events of this type")
+	flags.StringVar(&f.filter.Location, "location", "", "only events in this location, a län includes its kommuner")
+	flags.StringVar(&f.filter.Category, "category", "", "only events whose type is in this category, for example violence")
+	flags.IntVar(&f.filter.MinSeverity, "min-severity", 0, "only events whose type has at least this severity (1-5)")
+	flags.StringVar(&f.near, "near", "", "only events near this point, lat,lon or the name of a kommun or län")
+	flags.StringVar(&f.radius, "radius", "5km", "radius around the -near point, for example 5km or 500m")
+	flags.StringVar(&f.box, "box", "", "only events inside minLat,minLon,maxLat,maxLon")
+	flags.StringVar(&f.filter.Since, "since", "", "only events of the last period, for example 24h or 7d")
+	flags.StringVar(&f.filter.Facts, "facts", "", "only events whose extracted facts match, for example \"arrests > 0 and weapon = kniv\"")
+	return f
+}
+
+// Filter returns the filter described by the parsed flags, the program exits if a flag is invalid
+func (f *filterFlags) Filter() Filter {
+	filter := f.filter
+	var err error
+	if f.near != "" {
+		filter.Near, err = ParseCircle(f.near, f.radius)
+	}
+	if err == nil && f.box != "" {
+		filter.Box, err = ParseBoundingBox(f.box)
+	}
+	if err == nil {
+		err = filter.Validate()
+	}
+	if err != nil {
+		fmt.Println("Invalid filter:", err)
+		os.Exit(2)
+	}
+	return filter
+}
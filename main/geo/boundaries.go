@@ -0,0 +1,202 @@
+package geo
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"sync"
+)
+
+// boundaryFiles holds the boundaries directory, see its README.md for the expected file
+//
+//go:embed boundaries
+var boundaryFiles embed.FS
+
+// BoundariesFile is the embedded GeoJSON FeatureCollection with one Polygon or MultiPolygon feature
+// per municipality. A feature is matched to the gazetteer by a code or name property, see ParseBoundaries.
+const BoundariesFile = "boundaries/kommuner.geojson"
+
+// Side of the cells of the grid index in degrees
+const cellSize = 0.5
+
+// Property names that are used for the municipality code and name in common boundary files
+var (
+	codeProperties = []string{"code", "kommunkod", "KnKod", "kom_kod", "id"}
+	nameProperties = []string{"name", "kommunnamn", "KnNamn", "kom_namn"}
+)
+
+// BoundaryIndex finds the municipality that contains a point. The boundaries are registered in a
+// grid of cells covering their bounding boxes, so only a few polygons are tested for every point.
+type BoundaryIndex struct {
+	cells map[[2]int][]*boundary
+}
+
+type boundary struct {
+	municipality Municipality
+	// Polygons of the municipality, each a list of rings of [lon, lat] points where the first ring
+	// is the outer boundary and the others are holes
+	polygons [][][][2]float64
+	minLat   float64
+	minLon   float64
+	maxLat   float64
+	maxLon   float64
+}
+
+type geoJSONFeatures struct {
+	Features []struct {
+		Properties map[string]interface{} `json:"properties"`
+		Geometry   struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+// ParseBoundaries reads the municipality boundaries from a GeoJSON FeatureCollection. Features whose
+// code or name property is not a municipality in the gazetteer are skipped.
+func ParseBoundaries(data []byte) (*BoundaryIndex, error) {
+	var collection geoJSONFeatures
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, err
+	}
+
+	index := &BoundaryIndex{cells: make(map[[2]int][]*boundary)}
+	for _, feature := range collection.Features {
+		municipality, ok := featureMunicipality(feature.Properties)
+		if !ok {
+			continue
+		}
+		b := &boundary{municipality: municipality}
+		switch feature.Geometry.Type {
+		case "Polygon":
+			var polygon [][][2]float64
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &polygon); err != nil {
+				return nil, fmt.Errorf("%s: %w", municipality.Name, err)
+			}
+			b.polygons = [][][][2]float64{polygon}
+		case "MultiPolygon":
+			if err := json.Unmarshal(feature.Geometry.Coordinates, &b.polygons); err != nil {
+				return nil, fmt.Errorf("%s: %w", municipality.Name, err)
+			}
+		default:
+			continue
+		}
+		index.add(b)
+	}
+	return index, nil
+}
+
+// Locate returns the municipality whose boundary contains the point
+func (index *BoundaryIndex) Locate(lat, lon float64) (Municipality, bool) {
+	for _, b := range index.cells[cellOf(lat, lon)] {
+		if lat < b.minLat || lat > b.maxLat || lon < b.minLon || lon > b.maxLon {
+			continue
+		}
+		for _, polygon := range b.polygons {
+			if containsPoint(polygon, lat, lon) {
+				return b.municipality, true
+			}
+		}
+	}
+	return Municipality{}, false
+}
+
+// add computes the bounding box of the boundary and registers it in every cell the box overlaps
+func (index *BoundaryIndex) add(b *boundary) {
+	b.minLat, b.minLon = math.Inf(1), math.Inf(1)
+	b.maxLat, b.maxLon = math.Inf(-1), math.Inf(-1)
+	for _, polygon := range b.polygons {
+		for _, ring := range polygon {
+			for _, point := range ring {
+				b.minLon, b.maxLon = math.Min(b.minLon, point[0]), math.Max(b.maxLon, point[0])
+				b.minLat, b.maxLat = math.Min(b.minLat, point[1]), math.Max(b.maxLat, point[1])
+			}
+		}
+	}
+	low, high := cellOf(b.minLat, b.minLon), cellOf(b.maxLat, b.maxLon)
+	for i := low[0]; i <= high[0]; i++ {
+		for j := low[1]; j <= high[1]; j++ {
+			index.cells[[2]int{i, j}] = append(index.cells[[2]int{i, j}], b)
+		}
+	}
+}
+
+func cellOf(lat, lon float64) [2]int {
+	return [2]int{int(math.Floor(lat / cellSize)), int(math.Floor(lon / cellSize))}
+}
+
+// containsPoint tests the point against all rings of the polygon with the even-odd rule, so a point
+// inside a hole is outside the polygon
+func containsPoint(polygon [][][2]float64, lat, lon float64) bool {
+	inside := false
+	for _, ring := range polygon {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			lon1, lat1 := ring[i][0], ring[i][1]
+			lon2, lat2 := ring[j][0], ring[j][1]
+			if (lat1 > lat) != (lat2 > lat) && lon < (lon2-lon1)*(lat-lat1)/(lat2-lat1)+lon1 {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// featureMunicipality finds the municipality of a feature by its code property, or by its name property
+func featureMunicipality(properties map[string]interface{}) (Municipality, bool) {
+	for _, key := range codeProperties {
+		if value, ok := properties[key]; ok {
+			code := fmt.Sprint(value)
+			// Numeric codes lose their leading zero, 114 is 0114
+			if len(code) == 3 {
+				code = "0" + code
+			}
+			for _, municipality := range municipalities {
+				if municipality.Code == code {
+					return municipality, true
+				}
+			}
+		}
+	}
+	for _, key := range nameProperties {
+		if name, ok := properties[key].(string); ok {
+			if municipality, ok := LookupMunicipality(name); ok {
+				return municipality, true
+			}
+		}
+	}
+	return Municipality{}, false
+}
+
+var (
+	boundariesOnce  sync.Once
+	boundariesIndex *BoundaryIndex
+)
+
+// LocateMunicipality returns the municipality the point falls in according to the embedded boundaries.
+// If they are not embedded, or the point is outside all boundaries, the municipality with the closest
+// centre point is returned and exact is false.
+func LocateMunicipality(lat, lon float64) (municipality Municipality, exact bool) {
+	boundariesOnce.Do(func() {
+		data, err := boundaryFiles.ReadFile(BoundariesFile)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Println("Municipality boundaries are not embedded, events are placed in the municipality with the closest centre point")
+			return
+		}
+		if err == nil {
+			boundariesIndex, err = ParseBoundaries(data)
+		}
+		if err != nil {
+			log.Println("Municipality boundaries are not used:", BoundariesFile+":", err)
+		}
+	})
+	if boundariesIndex != nil {
+		if municipality, ok := boundariesIndex.Locate(lat, lon); ok {
+			return municipality, true
+		}
+	}
+	return Nearest(lat, lon), false
+}
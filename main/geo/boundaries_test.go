@@ -0,0 +1,73 @@
+package geo
+
+import "testing"
+
+// A square around Uppsala with a hole, matched by a numeric code, and a square matched by name.
+// The shapes are made up for the test, they are not the real boundaries.
+const testBoundaries = `{"type": "FeatureCollection", "features": [
+  {"type": "Feature", "properties": {"KnKod": 380},
+   "geometry": {"type": "Polygon", "coordinates": [
+     [[17.0, 59.5], [18.0, 59.5], [18.0, 60.0], [17.0, 60.0], [17.0, 59.5]],
+     [[17.4, 59.7], [17.6, 59.7], [17.6, 59.8], [17.4, 59.8], [17.4, 59.7]]]}},
+  {"type": "Feature", "properties": {"kommunnamn": "Sigtuna"},
+   "geometry": {"type": "MultiPolygon", "coordinates": [
+     [[[17.6, 59.4], [18.0, 59.4], [18.0, 59.5], [17.6, 59.5], [17.6, 59.4]]]]}},
+  {"type": "Feature", "properties": {"name": "Atlantis"},
+   "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}}
+]}`
+
+func TestParseBoundaries(t *testing.T) {
+	index, err := ParseBoundaries([]byte(testBoundaries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		lat, lon float64
+		want     string
+	}{
+		{59.86, 17.64, "Uppsala"},
+		{59.75, 17.5, ""}, // in the hole
+		{59.45, 17.8, "Sigtuna"},
+		{0.2, 0.5, ""}, // the feature is not a municipality of the gazetteer
+		{55.6, 13.0, ""},
+	}
+	for _, test := range tests {
+		municipality, ok := index.Locate(test.lat, test.lon)
+		if ok != (test.want != "") || municipality.Name != test.want {
+			t.Errorf("Locate(%v, %v) = %q, %v, want %q", test.lat, test.lon, municipality.Name, ok, test.want)
+		}
+	}
+}
+
+func TestParseBoundariesInvalid(t *testing.T) {
+	if _, err := ParseBoundaries([]byte(`{"features": [`)); err == nil {
+		t.Error("ParseBoundaries accepted invalid JSON")
+	}
+}
+
+func TestLocateMunicipalityInBundledBoundaries(t *testing.T) {
+	if _, err := boundaryFiles.ReadFile(BoundariesFile); err != nil {
+		t.Skip(BoundariesFile, "is not bundled, see boundaries/README.md:", err)
+	}
+	tests := []struct {
+		lat, lon float64
+		want     string
+	}{
+		{59.3303, 18.0586, "Stockholm"},
+		{59.3544, 17.9416, "Stockholm"}, // Bromma airport, closer to the centre point of Sundbyberg
+		{59.8586, 17.6389, "Uppsala"},
+		{57.6348, 18.2948, "Gotland"},
+		{55.6050, 13.0038, "Malmö"},
+		{67.8558, 20.2253, "Kiruna"},
+	}
+	for _, test := range tests {
+		municipality, exact := LocateMunicipality(test.lat, test.lon)
+		if !exact || municipality.Name != test.want {
+			t.Errorf("LocateMunicipality(%v, %v) = %q, %v, want %q", test.lat, test.lon, municipality.Name, exact, test.want)
+		}
+	}
+	// In the Baltic Sea
+	if _, exact := LocateMunicipality(58.0, 20.5); exact {
+		t.Error("a point in the sea is in a municipality")
+	}
+}
@@ -0,0 +1,139 @@
+// This package contains a gazetteer of the Swedish municipalities (kommuner) and counties (län)
+// with their official codes and centre points. It is used to resolve the free-form location names
+// of the events to a kommun and a län, so that events can be searched and aggregated by region.
+//
+// The centre points are the coordinates polisen.se uses for the region where available, and the
+// coordinates of the municipal seat otherwise, so they are approximations of the centroids.
+package geo
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"math"
+	"strings"
+)
+
+//go:embed gazetteer.json
+var gazetteerJSON []byte
+
+// County is a län, Code is the two digit county code
+type County struct {
+	Code string  `json:"code"`
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+}
+
+// Municipality is a kommun, Code is the four digit municipality code whose first two digits are the county code
+type Municipality struct {
+	Code string  `json:"code"`
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+}
+
+// CountyCode returns the code of the county the municipality belongs to
+func (m Municipality) CountyCode() string {
+	return m.Code[:2]
+}
+
+var (
+	counties       []County
+	municipalities []Municipality
+
+	countiesByName       = make(map[string]*County)
+	countiesByCode       = make(map[string]*County)
+	municipalitiesByName = make(map[string]*Municipality)
+)
+
+func init() {
+	var gazetteer struct {
+		Counties       []County       `json:"counties"`
+		Municipalities []Municipality `json:"municipalities"`
+	}
+	if err := json.Unmarshal(gazetteerJSON, &gazetteer); err != nil {
+		log.Fatal("The bundled gazetteer is invalid: ", err)
+	}
+	counties = gazetteer.Counties
+	municipalities = gazetteer.Municipalities
+
+	for i := range counties {
+		county := &counties[i]
+		countiesByCode[county.Code] = county
+		countiesByName[normalize(county.Name)] = county
+		// "Skåne län" can also be written "Skåne" and "Stockholms län" "Stockholms"
+		countiesByName[normalize(strings.TrimSuffix(county.Name, " län"))] = county
+	}
+	for i := range municipalities {
+		municipality := &municipalities[i]
+		municipalitiesByName[normalize(municipality.Name)] = municipality
+	}
+}
+
+// Counties returns all counties ordered by code
+func Counties() []County {
+	return counties
+}
+
+// Municipalities returns all municipalities ordered by code
+func Municipalities() []Municipality {
+	return municipalities
+}
+
+// CountyByCode returns the county with the given code
+func CountyByCode(code string) (County, bool) {
+	county, ok := countiesByCode[code]
+	if !ok {
+		return County{}, false
+	}
+	return *county, true
+}
+
+// LookupCounty returns the county with the given name, case and surrounding space are ignored
+func LookupCounty(name string) (County, bool) {
+	county, ok := countiesByName[normalize(name)]
+	if !ok {
+		return County{}, false
+	}
+	return *county, true
+}
+
+// LookupMunicipality returns the municipality with the given name, case and surrounding space are ignored
+func LookupMunicipality(name string) (Municipality, bool) {
+	municipality, ok := municipalitiesByName[normalize(name)]
+	if !ok {
+		return Municipality{}, false
+	}
+	return *municipality, true
+}
+
+// MunicipalitiesIn returns the municipalities of a county
+func MunicipalitiesIn(county County) []Municipality {
+	var inCounty []Municipality
+	for _, municipality := range municipalities {
+		if municipality.CountyCode() == county.Code {
+			inCounty = append(inCounty, municipality)
+		}
+	}
+	return inCounty
+}
+
+// Nearest returns the municipality whose centre point is closest to the coordinates
+func Nearest(lat, lon float64) Municipality {
+	best := municipalities[0]
+	bestDistance := math.Inf(1)
+	for _, municipality := range municipalities {
+		// Comparing squared degrees is enough to find the closest point, longitude is scaled by the latitude
+		dLat := municipality.Lat - lat
+		dLon := (municipality.Lon - lon) * math.Cos(lat*math.Pi/180)
+		if distance := dLat*dLat + dLon*dLon; distance < bestDistance {
+			best, bestDistance = municipality, distance
+		}
+	}
+	return best
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
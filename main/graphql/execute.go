@@ -0,0 +1,308 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// field is a field of an object type, resolve returns its value for the source object
+type field struct {
+	resolve func(source any, args arguments) (any, error)
+}
+
+// objectType is a type with fields, the Go values of the type are found by typeOf
+type objectType struct {
+	name   string
+	fields map[string]field
+}
+
+// arguments are the arguments of a field with the variables replaced by their values
+type arguments map[string]any
+
+// Error is an error of a request, Path is the response path of the field that failed
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Response is the result of a request
+type Response struct {
+	Data   any     `json:"data,omitempty"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// orderedMap is an object of the response, the fields are in the order of the selection set
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) set(key string, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buffer.Write(name)
+		buffer.WriteByte(':')
+		data, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(data)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// executor executes one operation of a document
+type executor struct {
+	document  *document
+	variables map[string]any
+	typeOf    func(value any) *objectType
+	errors    []Error
+	// fragments are the collected fields of the fragments by name and type, the same for every object
+	fragments map[[2]string][]selection
+}
+
+// selectOperation returns the operation with the name, or the only operation if the name is empty
+func (d *document) selectOperation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, fmt.Errorf("the document has several operations, the operationName is required")
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// coerceVariables fills in the default values of the variables that were not given
+func (op *operation) coerceVariables(given map[string]any) map[string]any {
+	variables := make(map[string]any)
+	for name, defaultValue := range op.variables {
+		if value, ok := given[name]; ok {
+			variables[name] = normalize(value)
+		} else if defaultValue != nil {
+			variables[name] = defaultValue.resolve(nil)
+		}
+	}
+	return variables
+}
+
+// normalize turns the float64 numbers of decoded JSON variables into int when they are whole numbers
+func normalize(value any) any {
+	switch value := value.(type) {
+	case float64:
+		if value == float64(int(value)) {
+			return int(value)
+		}
+	case []any:
+		for i := range value {
+			value[i] = normalize(value[i])
+		}
+	case map[string]any:
+		for key := range value {
+			value[key] = normalize(value[key])
+		}
+	}
+	return value
+}
+
+// selectionSet executes the selections on a source object of the type
+func (e *executor) selectionSet(selections []selection, typ *objectType, source any, path []any) *orderedMap {
+	result := &orderedMap{values: make(map[string]any)}
+	for _, s := range e.collectFields(selections, typ) {
+		key := s.name
+		if s.alias != "" {
+			key = s.alias
+		}
+		fieldPath := append(append([]any(nil), path...), key)
+		if s.name == "__typename" {
+			result.set(key, typ.name)
+			continue
+		}
+		f, ok := typ.fields[s.name]
+		if !ok {
+			e.fail(fieldPath, "cannot query field %q on type %s", s.name, typ.name)
+			result.set(key, nil)
+			continue
+		}
+		args := make(arguments)
+		for name, argument := range s.arguments {
+			args[name] = argument.resolve(e.variables)
+		}
+		value, err := f.resolve(source, args)
+		if err != nil {
+			e.fail(fieldPath, "%s", err)
+			result.set(key, nil)
+			continue
+		}
+		result.set(key, e.complete(s, value, fieldPath))
+	}
+	return result
+}
+
+// complete turns a resolved value into its response value, objects are executed with the sub selections
+func (e *executor) complete(s selection, value any, path []any) any {
+	if value == nil {
+		return nil
+	}
+	if t, ok := value.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	reflected := reflect.ValueOf(value)
+	if reflected.Kind() == reflect.Pointer {
+		if reflected.IsNil() {
+			return nil
+		}
+		return e.complete(s, reflected.Elem().Interface(), path)
+	}
+	if typ := e.typeOf(value); typ != nil {
+		if len(s.selections) == 0 {
+			e.fail(path, "field %q of type %s must have a selection of subfields", s.name, typ.name)
+			return nil
+		}
+		return e.selectionSet(s.selections, typ, value, path)
+	}
+	if reflected.Kind() == reflect.Slice {
+		list := make([]any, reflected.Len())
+		for i := range list {
+			list[i] = e.complete(s, reflected.Index(i).Interface(), append(append([]any(nil), path...), i))
+		}
+		return list
+	}
+	if len(s.selections) > 0 {
+		e.fail(path, "field %q is a scalar and can not have a selection of subfields", s.name)
+		return nil
+	}
+	return value
+}
+
+// collectFields flattens the fragments that apply to the type and leaves out the skipped selections.
+// The fields of a fragment are collected once per type, validate limits how many there are.
+func (e *executor) collectFields(selections []selection, typ *objectType) []selection {
+	var fields []selection
+	for _, s := range selections {
+		if !e.included(s.directives) {
+			continue
+		}
+		switch {
+		case s.spread != "":
+			f, ok := e.document.fragments[s.spread]
+			if !ok {
+				e.fail(nil, "unknown fragment %q", s.spread)
+				continue
+			}
+			if f.typeCondition != typ.name {
+				continue
+			}
+			key := [2]string{s.spread, typ.name}
+			collected, ok := e.fragments[key]
+			if !ok {
+				collected = e.collectFields(f.selections, typ)
+				if e.fragments == nil {
+					e.fragments = make(map[[2]string][]selection)
+				}
+				e.fragments[key] = collected
+			}
+			fields = append(fields, collected...)
+		case s.inline:
+			if s.typeCondition == "" || s.typeCondition == typ.name {
+				fields = append(fields, e.collectFields(s.selections, typ)...)
+			}
+		default:
+			fields = append(fields, s)
+		}
+	}
+	return fields
+}
+
+// included applies the @skip and @include directives
+func (e *executor) included(directives []directive) bool {
+	for _, d := range directives {
+		condition, ok := d.arguments["if"]
+		if !ok {
+			continue
+		}
+		value, _ := condition.resolve(e.variables).(bool)
+		if (d.name == "skip" && value) || (d.name == "include" && !value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *executor) fail(path []any, format string, args ...any) {
+	e.errors = append(e.errors, Error{Message: fmt.Sprintf(format, args...), Path: path})
+}
+
+// String returns an argument that is a string, or the default value if it is missing
+func (a arguments) String(name string, defaultValue string) (string, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return defaultValue, nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("argument %q must be a string", name)
+	}
+	return text, nil
+}
+
+// Int returns an argument that is an integer, or the default value if it is missing
+func (a arguments) Int(name string, defaultValue int) (int, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return defaultValue, nil
+	}
+	n, ok := value.(int)
+	if !ok {
+		return 0, fmt.Errorf("argument %q must be an integer", name)
+	}
+	return n, nil
+}
+
+// Float returns an argument that is a number, or the default value if it is missing
+func (a arguments) Float(name string, defaultValue float64) (float64, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return defaultValue, nil
+	}
+	switch n := value.(type) {
+	case int:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	}
+	return 0, fmt.Errorf("argument %q must be a number", name)
+}
+
+// Bool returns an argument that is a boolean, or the default value if it is missing
+func (a arguments) Bool(name string, defaultValue bool) (bool, error) {
+	value, ok := a[name]
+	if !ok || value == nil {
+		return defaultValue, nil
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("argument %q must be a boolean", name)
+	}
+	return b, nil
+}
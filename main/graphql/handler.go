@@ -0,0 +1,175 @@
+// This package serves a GraphQL API over the events, incidents and statistics of the archive, backed by
+// the same store as the REST API of the server. It implements the parts of GraphQL the API needs: queries
+// with arguments, variables, aliases, fragments and the @skip and @include directives, and subscriptions
+// delivered as server-sent events. Introspection is not supported, the schema is published as Schema.
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	. "project/main/event"
+	"strings"
+	"time"
+)
+
+// Handler serves GraphQL requests, as GET with the query parameters query, variables and operationName
+// or as POST with a JSON body holding the same fields
+type Handler struct {
+	// PollInterval is how often a subscription checks the store for new events
+	PollInterval time.Duration
+
+	store     *Store
+	resolvers *resolvers
+}
+
+// request is the body of a GraphQL request
+type request struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName"`
+}
+
+// NewHandler creates a handler of the events in the store
+func NewHandler(store *Store) *Handler {
+	h := &Handler{PollInterval: 5 * time.Second, store: store, resolvers: newResolvers(store)}
+	h.resolvers.add("Subscription", map[string]field{
+		"newEvents": {func(source any, _ arguments) (any, error) { return source, nil }},
+	})
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query, req.OperationName = query.Get("query"), query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid variables: "+err.Error())
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "GraphQL requests are GET or POST")
+		return
+	}
+
+	doc, err := parse(req.Query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	op, err := doc.selectOperation(req.OperationName)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if op.kind == "query" || op.kind == "subscription" {
+		if errors := h.resolvers.validate(doc, op); len(errors) > 0 {
+			writeJSON(w, http.StatusBadRequest, Response{Errors: errors})
+			return
+		}
+	}
+	exec := &executor{document: doc, variables: op.coerceVariables(req.Variables), typeOf: h.resolvers.typeOf}
+	switch op.kind {
+	case "query":
+		data := exec.selectionSet(op.selections, h.resolvers.types["Query"], nil, nil)
+		writeJSON(w, http.StatusOK, Response{Data: data, Errors: exec.errors})
+	case "subscription":
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "subscriptions are POST requests")
+			return
+		}
+		h.subscribe(w, r, exec, op)
+	default:
+		writeError(w, http.StatusBadRequest, "mutations are not supported")
+	}
+}
+
+// subscribe streams the events that are added to the store and match the arguments of newEvents,
+// as server-sent events named "next" until the client disconnects
+func (h *Handler) subscribe(w http.ResponseWriter, r *http.Request, exec *executor, op *operation) {
+	subscription := h.resolvers.types["Subscription"]
+	fields := exec.collectFields(op.selections, subscription)
+	if len(fields) != 1 || fields[0].name != "newEvents" {
+		writeError(w, http.StatusBadRequest, "a subscription selects exactly the field newEvents")
+		return
+	}
+	args := make(arguments)
+	for name, argument := range fields[0].arguments {
+		args[name] = argument.resolve(exec.variables)
+	}
+	filter, err := filterFromArguments(args)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	seen := make(map[int]bool)
+	for _, event := range h.store.Events() {
+		seen[event.Id] = true
+	}
+	ticker := time.NewTicker(h.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		for _, event := range h.store.Query(filter) {
+			if seen[event.Id] {
+				continue
+			}
+			seen[event.Id] = true
+			exec.errors = nil
+			data := exec.selectionSet(op.selections, subscription, event, nil)
+			payload, err := json.Marshal(Response{Data: data, Errors: exec.errors})
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: next\ndata: %s\n\n", payload); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// ServeSchema serves Schema as text
+func ServeSchema(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(Schema))
+}
+
+func writeJSON(w http.ResponseWriter, status int, response Response) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, Response{Errors: []Error{{Message: strings.TrimSpace(message)}}})
+}
@@ -0,0 +1,411 @@
+package graphql
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"project/main/duplicate"
+	. "project/main/event"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEvents are five events, one a day, the fires are 1, 3 and 5
+func testEvents() []Event {
+	var events []Event
+	for i, e := range []struct{ typ, location, gps string }{
+		{"Brand", "Uppsala", "59.858,17.638"},
+		{"Rån", "Stockholm", "59.329,18.068"},
+		{"Brand", "Stockholm", "59.330,18.070"},
+		{"Inbrott", "Uppsala", "59.860,17.640"},
+		{"Brand", "Uppsala", "59.859,17.639"},
+	} {
+		event := Event{Id: i + 1, Type: e.typ, Name: e.typ + ", " + e.location,
+			Datetime: "2023-04-2" + string(rune('0'+i)) + " 10:00:00 +02:00"}
+		event.Location.Name, event.Location.Gps = e.location, e.gps
+		events = append(events, event)
+	}
+	return events
+}
+
+// writeArchive writes the archive in the test directory, with a new modification time so the store reads it again
+func writeArchive(t *testing.T, events []Event) {
+	t.Helper()
+	data, err := json.Marshal(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ArchivePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(time.Duration(len(events)) * time.Minute)
+	if err := os.Chtimes(ArchivePath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testHandler runs the test in an empty directory with the events in the archive and returns a handler of them
+func testHandler(t *testing.T, events []Event) *Handler {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.MkdirAll(filepath.Dir(ArchivePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeArchive(t, events)
+	return NewHandler(NewStore())
+}
+
+// result is a decoded response
+type result struct {
+	Data   map[string]any `json:"data"`
+	Errors []Error        `json:"errors"`
+}
+
+// post executes the query and returns the status and the decoded response
+func post(t *testing.T, h *Handler, query string, variables map[string]any) (int, result) {
+	t.Helper()
+	body, _ := json.Marshal(request{Query: query, Variables: variables})
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+	var response result
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s: %v", recorder.Body, err)
+	}
+	return recorder.Code, response
+}
+
+// ids returns the ids of the nodes of a connection in the response
+func ids(connection any) []int {
+	var ids []int
+	for _, node := range connection.(map[string]any)["nodes"].([]any) {
+		ids = append(ids, int(node.(map[string]any)["id"].(float64)))
+	}
+	return ids
+}
+
+func equalIDs(a []int, b ...int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestVariables(t *testing.T) {
+	h := testHandler(t, testEvents())
+	query := `query Fires($type: String, $first: Int = 2) {
+		events(type: $type, first: $first) { totalCount nodes { id type } }
+	}`
+	status, response := post(t, h, query, map[string]any{"type": "Brand"})
+	if status != http.StatusOK || len(response.Errors) > 0 {
+		t.Fatalf("status %d, errors %v", status, response.Errors)
+	}
+	events := response.Data["events"].(map[string]any)
+	if events["totalCount"] != float64(3) || !equalIDs(ids(events), 5, 3) {
+		t.Errorf("events %v, want 3 fires and the newest 2", events)
+	}
+
+	_, response = post(t, h, query, map[string]any{"first": 10})
+	if events := response.Data["events"].(map[string]any); events["totalCount"] != float64(5) || !equalIDs(ids(events), 5, 4, 3, 2, 1) {
+		t.Errorf("events without a type %v", events)
+	}
+
+	_, response = post(t, h, query, map[string]any{"first": "two"})
+	if len(response.Errors) != 1 || !strings.Contains(response.Errors[0].Message, "first") {
+		t.Errorf("errors %v, want one about first", response.Errors)
+	}
+}
+
+func TestFragments(t *testing.T) {
+	h := testHandler(t, testEvents())
+	query := `query ($withName: Boolean!) {
+		event(id: 3) { ...identity ... on Event { type } ... @include(if: $withName) { name } location { name @skip(if: true) gps } }
+	}
+	fragment identity on Event { id __typename }`
+	_, response := post(t, h, query, map[string]any{"withName": false})
+	if len(response.Errors) > 0 {
+		t.Fatal(response.Errors)
+	}
+	event := response.Data["event"].(map[string]any)
+	if event["id"] != float64(3) || event["__typename"] != "Event" || event["type"] != "Brand" {
+		t.Errorf("event %v", event)
+	}
+	if _, ok := event["name"]; ok {
+		t.Error("the name was included with $withName false")
+	}
+	if location := event["location"].(map[string]any); len(location) != 1 || location["gps"] == nil {
+		t.Errorf("location %v, want only the gps", location)
+	}
+	_, response = post(t, h, query, map[string]any{"withName": true})
+	if event := response.Data["event"].(map[string]any); event["name"] != "Brand, Stockholm" {
+		t.Errorf("event %v, want the name", event)
+	}
+
+	for source, message := range map[string]string{
+		`{ event(id: 1) { ...a } } fragment a on Event { ...a }`:     "spreads itself",
+		`{ event(id: 1) { ...missing } }`:                            "unknown fragment",
+		`{ event(id: 1) { ...a } } fragment a on Nothing { id }`:     "unknown type",
+		`{ event(id: 1) { ...a } } fragment a on Event { unknown }`:  "cannot query field",
+		`{ event(id: 1) { ...a } } fragment a on Event { location }`: "selection of subfields",
+	} {
+		status, response := post(t, h, source, nil)
+		if status != http.StatusBadRequest || len(response.Errors) == 0 || !strings.Contains(response.Errors[0].Message, message) {
+			t.Errorf("%s: status %d, errors %v, want %q", source, status, response.Errors, message)
+		}
+	}
+}
+
+func TestFragmentExpansionLimit(t *testing.T) {
+	h := testHandler(t, testEvents())
+	// Every fragment spreads the previous one twice, 2^40 fields when expanded
+	var query strings.Builder
+	query.WriteString(`{ event(id: 1) { ...f40 } } fragment f0 on Event { id }`)
+	for i := 1; i <= 40; i++ {
+		fmt.Fprintf(&query, " fragment f%d on Event { ...f%d ... on Event { ...f%d } }", i, i-1, i-1)
+	}
+	start := time.Now()
+	status, response := post(t, h, query.String(), nil)
+	if status != http.StatusBadRequest || len(response.Errors) != 1 || !strings.Contains(response.Errors[0].Message, "more than 1000 fields") {
+		t.Errorf("status %d, errors %v, want too many fields", status, response.Errors)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the query was rejected after %v", elapsed)
+	}
+}
+
+func TestCursors(t *testing.T) {
+	h := testHandler(t, testEvents())
+	query := `query ($first: Int, $after: String) {
+		events(first: $first, after: $after) { nodes { id } edges { cursor } pageInfo { hasNextPage endCursor } }
+	}`
+	page := func(first int, after any) (map[string]any, []Error) {
+		_, response := post(t, h, query, map[string]any{"first": first, "after": after})
+		if response.Data == nil || response.Data["events"] == nil {
+			return nil, response.Errors
+		}
+		return response.Data["events"].(map[string]any), response.Errors
+	}
+	info := func(events map[string]any) (bool, any) {
+		pageInfo := events["pageInfo"].(map[string]any)
+		return pageInfo["hasNextPage"].(bool), pageInfo["endCursor"]
+	}
+
+	var after any
+	for _, want := range [][]int{{5, 4}, {3, 2}, {1}} {
+		events, errors := page(2, after)
+		if len(errors) > 0 {
+			t.Fatal(errors)
+		}
+		hasNext, endCursor := info(events)
+		if !equalIDs(ids(events), want...) || hasNext != (want[len(want)-1] != 1) {
+			t.Fatalf("page after %v is %v with hasNextPage %v, want %v", after, ids(events), hasNext, want)
+		}
+		edges := events["edges"].([]any)
+		if endCursor != edges[len(edges)-1].(map[string]any)["cursor"] {
+			t.Errorf("endCursor %v is not the cursor of the last edge", endCursor)
+		}
+		after = endCursor
+	}
+
+	// After the last event the page is empty and has no end cursor
+	events, _ := page(2, after)
+	if hasNext, endCursor := info(events); len(ids(events)) != 0 || hasNext || endCursor != nil {
+		t.Errorf("the page after the last event is %v", events)
+	}
+	// A page of 0 events only tells whether there are events
+	events, _ = page(0, nil)
+	if hasNext, _ := info(events); len(ids(events)) != 0 || !hasNext {
+		t.Errorf("the page of 0 events is %v", events)
+	}
+	events, _ = page(maxPageSize, nil)
+	if len(ids(events)) != 5 {
+		t.Errorf("a page of %d has %d events", maxPageSize, len(ids(events)))
+	}
+
+	for _, invalid := range []struct {
+		first int
+		after any
+	}{
+		{-1, nil},
+		{maxPageSize + 1, nil},
+		{2, "not a cursor"},
+		{2, encodeCursor("Incident", 3)},
+		{2, encodeCursor("Event", 99)},
+	} {
+		if _, errors := page(invalid.first, invalid.after); len(errors) != 1 {
+			t.Errorf("first %d after %v: errors %v, want one", invalid.first, invalid.after, errors)
+		}
+	}
+}
+
+func TestFilterFromArguments(t *testing.T) {
+	filter, err := filterFromArguments(arguments{"type": "Brand", "location": "Uppsala", "category": "fire",
+		"minSeverity": 2, "near": "59.858,17.638", "box": "59,17,60,18", "since": "7d", "facts": "arrests > 0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.Type != "Brand" || filter.Location != "Uppsala" || filter.Category != "fire" || filter.MinSeverity != 2 ||
+		filter.Since != "7d" || filter.Facts != "arrests > 0" {
+		t.Errorf("filter %+v", filter)
+	}
+	if filter.Near == nil || filter.Near.Lat != 59.858 || filter.Near.Lon != 17.638 || filter.Near.RadiusKm != 5 {
+		t.Errorf("near %+v, want the default radius of 5 km", filter.Near)
+	}
+	if filter.Box == nil || *filter.Box != (BoundingBox{MinLat: 59, MinLon: 17, MaxLat: 60, MaxLon: 18}) {
+		t.Errorf("box %+v", filter.Box)
+	}
+	if filter, err := filterFromArguments(arguments{"near": "59.858,17.638", "radius": "500m"}); err != nil || filter.Near.RadiusKm != 0.5 {
+		t.Errorf("radius 500m: %+v, %v", filter.Near, err)
+	}
+	if filter, err := filterFromArguments(arguments{}); err != nil || !filter.IsEmpty() {
+		t.Errorf("no arguments: %+v, %v", filter, err)
+	}
+
+	for _, args := range []arguments{
+		{"type": 1},
+		{"minSeverity": "high"},
+		{"near": "59.858,17.638", "radius": "far"},
+		{"near": "nowhere at all"},
+		{"box": "60,17,59,18"},
+		{"since": "yesterday"},
+		{"facts": "arrests >"},
+	} {
+		if _, err := filterFromArguments(args); err == nil {
+			t.Errorf("filterFromArguments(%v) succeeded", args)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	h := testHandler(t, testEvents())
+	_, response := post(t, h, `{ stats(location: "Uppsala") { total byType(top: 1) { key value } perDay { key value } } }`, nil)
+	if len(response.Errors) > 0 {
+		t.Fatal(response.Errors)
+	}
+	stats := response.Data["stats"].(map[string]any)
+	byType := stats["byType"].([]any)
+	if stats["total"] != float64(3) || len(byType) != 1 || byType[0].(map[string]any)["key"] != "Brand" {
+		t.Errorf("stats %v", stats)
+	}
+	if perDay := stats["perDay"].([]any); len(perDay) != 5 {
+		t.Errorf("perDay has %d days, want the 5 days from the first to the last event", len(perDay))
+	}
+}
+
+func TestStatsWithoutMergedDuplicates(t *testing.T) {
+	h := testHandler(t, testEvents())
+	h.store.Exclude = duplicate.MergedRemover(duplicate.DecisionsPath)
+	query := `{ stats { total byType { key value } } events { totalCount } }`
+	counts := func() (float64, float64, map[string]any) {
+		t.Helper()
+		_, response := post(t, h, query, nil)
+		if len(response.Errors) > 0 {
+			t.Fatal(response.Errors)
+		}
+		stats := response.Data["stats"].(map[string]any)
+		byType := make(map[string]any)
+		for _, count := range stats["byType"].([]any) {
+			byType[count.(map[string]any)["key"].(string)] = count.(map[string]any)["value"]
+		}
+		return stats["total"].(float64), response.Data["events"].(map[string]any)["totalCount"].(float64), byType
+	}
+
+	if total, _, _ := counts(); total != 5 {
+		t.Fatalf("total %v without any decisions, want 5", total)
+	}
+	decide := func(decisions string, modTime time.Time) {
+		if err := os.WriteFile(duplicate.DecisionsPath, []byte(decisions), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(duplicate.DecisionsPath, modTime, modTime)
+	}
+	// The later event of a merged pair is left out, a dismissed pair counts twice
+	decide(`{"1-5": "merged", "2-3": "dismissed"}`, time.Now())
+	total, totalCount, byType := counts()
+	if total != 4 || totalCount != 4 || byType["Brand"] != float64(2) || byType["Rån"] != float64(1) {
+		t.Errorf("total %v, totalCount %v and byType %v with 5 merged into 1", total, totalCount, byType)
+	}
+	// A decision made while the server runs is applied
+	decide(`{"1-5": "merged", "2-3": "merged"}`, time.Now().Add(time.Minute))
+	if total, _, byType := counts(); total != 3 || byType["Brand"] != float64(1) || byType["Rån"] != float64(1) {
+		t.Errorf("total %v and byType %v after 3 was merged into 2", total, byType)
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	events := testEvents()
+	h := testHandler(t, events)
+	h.PollInterval = 10 * time.Millisecond
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	query := `subscription ($type: String) { newEvents(type: $type) { id name } }`
+	if resp, err := http.Get(server.URL + "?query=" + strings.ReplaceAll(query, " ", "+")); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("a subscription with GET has the status %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	body, _ := json.Marshal(request{Query: query, Variables: map[string]any{"type": "Brand"}})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader(string(body)))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Content-Type %q", resp.Header.Get("Content-Type"))
+	}
+
+	robbery, fire := events[1], events[0]
+	robbery.Id, fire.Id = 6, 7
+	writeArchive(t, append(events, robbery, fire))
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	var received []string
+	timeout := time.After(2 * time.Second)
+	for len(received) == 0 {
+		select {
+		case line := <-lines:
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				received = append(received, data)
+			}
+		case <-timeout:
+			t.Fatal("the subscription received no event")
+		}
+	}
+	var response result
+	if err := json.Unmarshal([]byte(received[0]), &response); err != nil {
+		t.Fatal(err)
+	}
+	event := response.Data["newEvents"].(map[string]any)
+	if event["id"] != float64(7) || event["name"] != fire.Name {
+		t.Errorf("the subscription received %v, want the new fire", event)
+	}
+}
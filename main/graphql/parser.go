@@ -0,0 +1,433 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed request with its operations and fragment definitions
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query or a subscription, the types of the variables are not checked
+type operation struct {
+	kind       string
+	name       string
+	variables  map[string]*value
+	selections []selection
+}
+
+// fragment is a named fragment that is spread into selection sets with ...name
+type fragment struct {
+	typeCondition string
+	selections    []selection
+}
+
+// selection is a field, a fragment spread (spread is set) or an inline fragment (inline is true)
+type selection struct {
+	alias         string
+	name          string
+	arguments     map[string]*value
+	directives    []directive
+	selections    []selection
+	spread        string
+	inline        bool
+	typeCondition string
+}
+
+type directive struct {
+	name      string
+	arguments map[string]*value
+}
+
+// Kinds of values
+const (
+	valueVariable = iota
+	valueInt
+	valueFloat
+	valueString
+	valueBoolean
+	valueNull
+	valueEnum
+	valueList
+	valueObject
+)
+
+// value is a literal or a variable in the document
+type value struct {
+	kind   int
+	raw    string
+	list   []*value
+	fields map[string]*value
+}
+
+// resolve returns the value as a Go value, with the variables replaced by their values
+func (v *value) resolve(variables map[string]any) any {
+	switch v.kind {
+	case valueVariable:
+		return variables[v.raw]
+	case valueInt:
+		n, _ := strconv.Atoi(v.raw)
+		return n
+	case valueFloat:
+		f, _ := strconv.ParseFloat(v.raw, 64)
+		return f
+	case valueString, valueEnum:
+		return v.raw
+	case valueBoolean:
+		return v.raw == "true"
+	case valueList:
+		list := make([]any, len(v.list))
+		for i, item := range v.list {
+			list[i] = item.resolve(variables)
+		}
+		return list
+	case valueObject:
+		object := make(map[string]any)
+		for name, field := range v.fields {
+			object[name] = field.resolve(variables)
+		}
+		return object
+	}
+	return nil
+}
+
+// Kinds of tokens
+const (
+	tokenEOF = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  int
+	value string
+}
+
+// parser is a recursive descent parser of the executable definitions of GraphQL
+type parser struct {
+	source string
+	offset int
+	token  token
+}
+
+// parse parses a request document
+func parse(source string) (doc *document, err error) {
+	p := &parser{source: source}
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxErr, ok := r.(syntaxError)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, syntaxErr
+		}
+	}()
+	p.next()
+	doc = &document{fragments: make(map[string]*fragment)}
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: p.selectionSet()})
+		case p.peekName("query"), p.peekName("subscription"), p.peekName("mutation"):
+			doc.operations = append(doc.operations, p.operation())
+		case p.peekName("fragment"):
+			p.next()
+			name := p.name()
+			p.expectName("on")
+			f := &fragment{typeCondition: p.name()}
+			p.directives()
+			f.selections = p.selectionSet()
+			doc.fragments[name] = f
+		default:
+			p.fail("unexpected %q", p.token.value)
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, syntaxError("the document has no operation")
+	}
+	return doc, nil
+}
+
+// syntaxError is panicked by the parser and returned by parse
+type syntaxError string
+
+func (e syntaxError) Error() string {
+	return string(e)
+}
+
+func (p *parser) fail(format string, args ...any) {
+	line := strings.Count(p.source[:p.offset], "\n") + 1
+	panic(syntaxError(fmt.Sprintf("syntax error on line %d: %s", line, fmt.Sprintf(format, args...))))
+}
+
+func (p *parser) operation() *operation {
+	op := &operation{kind: p.name(), variables: make(map[string]*value)}
+	if p.token.kind == tokenName {
+		op.name = p.name()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			p.expect("$")
+			name := p.name()
+			p.expect(":")
+			p.typeReference()
+			if p.skip("=") {
+				op.variables[name] = p.value(true)
+			} else {
+				op.variables[name] = nil
+			}
+		}
+	}
+	p.directives()
+	op.selections = p.selectionSet()
+	return op
+}
+
+// typeReference skips the type of a variable, for example [String!]!
+func (p *parser) typeReference() {
+	if p.skip("[") {
+		p.typeReference()
+		p.expect("]")
+	} else {
+		p.name()
+	}
+	p.skip("!")
+}
+
+func (p *parser) selectionSet() []selection {
+	p.expect("{")
+	var selections []selection
+	for !p.skip("}") {
+		if p.skip("...") {
+			if p.peekName("on") || p.peek("{") || p.peek("@") {
+				s := selection{inline: true}
+				if p.skip("on") {
+					s.typeCondition = p.name()
+				}
+				s.directives = p.directives()
+				s.selections = p.selectionSet()
+				selections = append(selections, s)
+			} else {
+				selections = append(selections, selection{spread: p.name(), directives: p.directives()})
+			}
+			continue
+		}
+		s := selection{name: p.name()}
+		if p.skip(":") {
+			s.alias, s.name = s.name, p.name()
+		}
+		s.arguments = p.arguments()
+		s.directives = p.directives()
+		if p.peek("{") {
+			s.selections = p.selectionSet()
+		}
+		selections = append(selections, s)
+	}
+	if len(selections) == 0 {
+		p.fail("empty selection set")
+	}
+	return selections
+}
+
+func (p *parser) arguments() map[string]*value {
+	arguments := make(map[string]*value)
+	if p.skip("(") {
+		for !p.skip(")") {
+			name := p.name()
+			p.expect(":")
+			arguments[name] = p.value(false)
+		}
+	}
+	return arguments
+}
+
+func (p *parser) directives() []directive {
+	var directives []directive
+	for p.skip("@") {
+		directives = append(directives, directive{name: p.name(), arguments: p.arguments()})
+	}
+	return directives
+}
+
+// value parses a value, constant values can not contain variables
+func (p *parser) value(constant bool) *value {
+	t := p.token
+	switch {
+	case p.skip("$"):
+		if constant {
+			p.fail("variable in a constant value")
+		}
+		return &value{kind: valueVariable, raw: p.name()}
+	case p.skip("["):
+		v := &value{kind: valueList}
+		for !p.skip("]") {
+			v.list = append(v.list, p.value(constant))
+		}
+		return v
+	case p.skip("{"):
+		v := &value{kind: valueObject, fields: make(map[string]*value)}
+		for !p.skip("}") {
+			name := p.name()
+			p.expect(":")
+			v.fields[name] = p.value(constant)
+		}
+		return v
+	case t.kind == tokenInt:
+		p.next()
+		return &value{kind: valueInt, raw: t.value}
+	case t.kind == tokenFloat:
+		p.next()
+		return &value{kind: valueFloat, raw: t.value}
+	case t.kind == tokenString:
+		p.next()
+		return &value{kind: valueString, raw: t.value}
+	case t.kind == tokenName:
+		p.next()
+		switch t.value {
+		case "true", "false":
+			return &value{kind: valueBoolean, raw: t.value}
+		case "null":
+			return &value{kind: valueNull}
+		}
+		return &value{kind: valueEnum, raw: t.value}
+	}
+	p.fail("expected a value, found %q", t.value)
+	return nil
+}
+
+func (p *parser) name() string {
+	if p.token.kind != tokenName {
+		p.fail("expected a name, found %q", p.token.value)
+	}
+	name := p.token.value
+	p.next()
+	return name
+}
+
+func (p *parser) peek(punctuator string) bool {
+	return p.token.kind == tokenPunctuator && p.token.value == punctuator
+}
+
+func (p *parser) peekName(name string) bool {
+	return p.token.kind == tokenName && p.token.value == name
+}
+
+// skip consumes the punctuator or keyword if it is the current token
+func (p *parser) skip(text string) bool {
+	if p.peek(text) || p.peekName(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punctuator string) {
+	if !p.skip(punctuator) {
+		p.fail("expected %q, found %q", punctuator, p.token.value)
+	}
+}
+
+func (p *parser) expectName(name string) {
+	if !p.peekName(name) {
+		p.fail("expected %q, found %q", name, p.token.value)
+	}
+	p.next()
+}
+
+// next reads the next token, white space, commas and comments are ignored
+func (p *parser) next() {
+	for p.offset < len(p.source) {
+		c := p.source[p.offset]
+		if c == '#' {
+			for p.offset < len(p.source) && p.source[p.offset] != '\n' {
+				p.offset++
+			}
+		} else if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.offset++
+		} else {
+			break
+		}
+	}
+	if p.offset >= len(p.source) {
+		p.token = token{kind: tokenEOF, value: "end of document"}
+		return
+	}
+	start := p.offset
+	c := p.source[p.offset]
+	switch {
+	case strings.HasPrefix(p.source[p.offset:], "..."):
+		p.offset += 3
+		p.token = token{kind: tokenPunctuator, value: "..."}
+	case strings.ContainsRune("!$()=:@[]{}|&", rune(c)):
+		p.offset++
+		p.token = token{kind: tokenPunctuator, value: string(c)}
+	case c == '_' || isLetter(c):
+		for p.offset < len(p.source) && (p.source[p.offset] == '_' || isLetter(p.source[p.offset]) || isDigit(p.source[p.offset])) {
+			p.offset++
+		}
+		p.token = token{kind: tokenName, value: p.source[start:p.offset]}
+	case c == '-' || isDigit(c):
+		p.offset++
+		kind := tokenInt
+		for p.offset < len(p.source) {
+			c := p.source[p.offset]
+			if c == '.' || c == 'e' || c == 'E' || ((c == '+' || c == '-') && kind == tokenFloat) {
+				kind = tokenFloat
+			} else if !isDigit(c) {
+				break
+			}
+			p.offset++
+		}
+		p.token = token{kind: kind, value: p.source[start:p.offset]}
+	case c == '"':
+		p.token = token{kind: tokenString, value: p.string()}
+	default:
+		r, _ := utf8.DecodeRuneInString(p.source[p.offset:])
+		p.fail("unexpected character %q", r)
+	}
+}
+
+// string reads a string literal, block strings with triple quotes are not supported
+func (p *parser) string() string {
+	if strings.HasPrefix(p.source[p.offset:], `"""`) {
+		p.fail("block strings are not supported")
+	}
+	start := p.offset
+	p.offset++
+	for p.offset < len(p.source) {
+		switch p.source[p.offset] {
+		case '\\':
+			p.offset += 2
+			continue
+		case '\n':
+			p.fail("unterminated string")
+		case '"':
+			p.offset++
+			// The escapes of GraphQL strings are those of JSON strings
+			text, err := strconv.Unquote(strings.ReplaceAll(p.source[start:p.offset], `\/`, "/"))
+			if err != nil {
+				p.fail("invalid string %s", p.source[start:p.offset])
+			}
+			return text
+		}
+		p.offset++
+	}
+	p.fail("unterminated string")
+	return ""
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
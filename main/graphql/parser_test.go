@@ -0,0 +1,83 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+		# The newest fire
+		query Fires($type: String = "Brand", $first: Int!) {
+			latest: events(type: $type, first: $first, box: "59,17,60,18") {
+				nodes { ...eventFields @include(if: true) }
+			}
+		}
+		subscription { newEvents(location: "Uppsala") { ... on Event { id } } }
+		fragment eventFields on Event { id, name }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.operations) != 2 || doc.operations[0].kind != "query" || doc.operations[1].kind != "subscription" {
+		t.Fatalf("operations %+v", doc.operations)
+	}
+	query := doc.operations[0]
+	if query.name != "Fires" || len(query.variables) != 2 || query.variables["first"] != nil {
+		t.Errorf("operation %q with the variables %v", query.name, query.variables)
+	}
+	latest := query.selections[0]
+	if latest.alias != "latest" || latest.name != "events" || latest.arguments["type"].kind != valueVariable {
+		t.Errorf("selection %+v", latest)
+	}
+	spread := latest.selections[0].selections[0]
+	if spread.spread != "eventFields" || len(spread.directives) != 1 || spread.directives[0].name != "include" {
+		t.Errorf("spread %+v", spread)
+	}
+	if f := doc.fragments["eventFields"]; f == nil || f.typeCondition != "Event" || len(f.selections) != 2 {
+		t.Errorf("fragment %+v", f)
+	}
+	if _, err := doc.selectOperation(""); err == nil {
+		t.Error("selectOperation without a name chose one of two operations")
+	}
+	if op, err := doc.selectOperation("Fires"); err != nil || op != query {
+		t.Errorf("selectOperation(Fires) = %v, %v", op, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, source := range []string{
+		``,
+		`fragment f on Event { id }`,
+		`{ events `,
+		`{ events { } }`,
+		`{ events(first: ) { totalCount } }`,
+		`query ($first: Int = $other) { events { totalCount } }`,
+		`{ event(id: "1) { id } }`,
+		`{ event(id: """1""") { id } }`,
+		`{ events ? }`,
+		`{ a } }`,
+	} {
+		if _, err := parse(source); err == nil {
+			t.Errorf("parse(%q) succeeded", source)
+		} else if !strings.Contains(err.Error(), "syntax error") && !strings.Contains(err.Error(), "no operation") {
+			t.Errorf("parse(%q) = %v", source, err)
+		}
+	}
+}
+
+func TestCoerceVariables(t *testing.T) {
+	doc, err := parse(`query ($type: String = "Brand", $first: Int = 2, $after: String) { events { totalCount } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	variables := doc.operations[0].coerceVariables(map[string]any{"first": float64(5), "radius": "1km"})
+	if variables["type"] != "Brand" || variables["first"] != 5 {
+		t.Errorf("variables %v, want the default type and first 5 as an int", variables)
+	}
+	if _, ok := variables["after"]; ok {
+		t.Error("a variable without a value or a default is set")
+	}
+	if _, ok := variables["radius"]; ok {
+		t.Error("a variable that is not declared is set")
+	}
+}
@@ -0,0 +1,515 @@
+package graphql
+
+import (
+	"encoding/base64"
+	"fmt"
+	. "project/main/event"
+	"project/main/incident"
+	"project/main/stats"
+	"strconv"
+	"strings"
+)
+
+// Schema is the schema of the API in the schema definition language, it is served at /graphql/schema.graphql
+const Schema = `# The arguments type, location, category, minSeverity, near, radius, box, since and facts
+# select the events like the query parameters of the feeds and exports, for example
+# events(location: "Uppsala län", since: "7d", facts: "arrests > 0")
+
+type Query {
+  events(type: String, location: String, category: String, minSeverity: Int, near: String, radius: String,
+         box: String, since: String, facts: String, first: Int = 20, after: String): EventConnection!
+  event(id: Int!): Event
+  incidents(type: String, location: String, category: String, minSeverity: Int, near: String, radius: String,
+            box: String, since: String, facts: String, linkedOnly: Boolean = true, first: Int = 20, after: String): IncidentConnection!
+  stats(type: String, location: String, category: String, minSeverity: Int, near: String, radius: String,
+        box: String, since: String, facts: String): Stats!
+}
+
+# Subscriptions are served as server-sent events, POST the request with Accept: text/event-stream
+type Subscription {
+  newEvents(type: String, location: String, category: String, minSeverity: Int, near: String, radius: String,
+            box: String, since: String, facts: String): Event!
+}
+
+type Event {
+  id: Int!
+  # As published by the police, for example "2023-04-20 13:19:17 +02:00"
+  datetime: String!
+  # RFC 3339
+  time: String!
+  name: String!
+  summary: String!
+  # The page of the event on polisen.se
+  url: String!
+  type: String!
+  typeLabel: String!
+  category: Category!
+  severity: Int!
+  location: Location!
+  facts: Facts!
+}
+
+type Location {
+  name: String!
+  gps: String!
+  latitude: Float
+  longitude: Float
+  municipality: String
+  county: String
+}
+
+type Category {
+  id: String!
+  label: String!
+  color: String!
+}
+
+type Facts {
+  injured: Int!
+  arrested: Int!
+  ages: [Int!]!
+  vehicles: [String!]!
+  weapons: [String!]!
+  streets: [String!]!
+}
+
+type Incident {
+  id: Int!
+  type: String!
+  location: String!
+  start: String!
+  end: String!
+  events: [Event!]!
+}
+
+type Count {
+  key: String!
+  value: Int!
+}
+
+type Stats {
+  total: Int!
+  byCategory: [Count!]!
+  byType(top: Int): [Count!]!
+  byLocation(top: Int): [Count!]!
+  byCounty: [Count!]!
+  perDay: [Count!]!
+}
+
+type PageInfo {
+  hasNextPage: Boolean!
+  endCursor: String
+}
+
+type EventConnection {
+  totalCount: Int!
+  edges: [EventEdge!]!
+  nodes: [Event!]!
+  pageInfo: PageInfo!
+}
+
+type EventEdge {
+  cursor: String!
+  node: Event!
+}
+
+type IncidentConnection {
+  totalCount: Int!
+  edges: [IncidentEdge!]!
+  nodes: [Incident!]!
+  pageInfo: PageInfo!
+}
+
+type IncidentEdge {
+  cursor: String!
+  node: Incident!
+}
+`
+
+// maxPageSize is the largest page of a connection
+const maxPageSize = 100
+
+// location is the location of an event
+type location struct {
+	event Event
+}
+
+// statistics are the counts of the events selected by the arguments of the stats field
+type statistics struct {
+	events []Event
+}
+
+// connection is a page of events or incidents, typeName is EventConnection or IncidentConnection
+type connection struct {
+	typeName string
+	total    int
+	edges    []edge
+	hasNext  bool
+}
+
+type edge struct {
+	typeName string
+	cursor   string
+	node     any
+}
+
+type pageInfo struct {
+	hasNextPage bool
+	endCursor   string
+}
+
+// resolvers holds the object types of the schema, which resolve the root fields from the store
+type resolvers struct {
+	store *Store
+	types map[string]*objectType
+}
+
+func newResolvers(store *Store) *resolvers {
+	r := &resolvers{store: store, types: make(map[string]*objectType)}
+	r.add("Query", map[string]field{
+		"events":    {r.events},
+		"event":     {r.event},
+		"incidents": {r.incidents},
+		"stats":     {r.stats},
+	})
+	r.add("Event", map[string]field{
+		"id":        eventField(func(e Event) any { return e.Id }),
+		"datetime":  eventField(func(e Event) any { return e.Datetime }),
+		"time":      eventField(func(e Event) any { return e.Time() }),
+		"name":      eventField(func(e Event) any { return e.Name }),
+		"summary":   eventField(func(e Event) any { return e.Summary }),
+		"url":       eventField(func(e Event) any { return PageURL(e.Url) }),
+		"type":      eventField(func(e Event) any { return e.Type }),
+		"typeLabel": eventField(func(e Event) any { return e.TypeLabel() }),
+		"category":  eventField(func(e Event) any { return e.Category() }),
+		"severity":  eventField(func(e Event) any { return e.Severity() }),
+		"location":  eventField(func(e Event) any { return location{event: e} }),
+		"facts":     eventField(func(e Event) any { return e.ExtractedFacts() }),
+	})
+	r.add("Location", map[string]field{
+		"name": locationField(func(e Event) any { return e.Location.Name }),
+		"gps":  locationField(func(e Event) any { return e.Location.Gps }),
+		"latitude": locationField(func(e Event) any {
+			if lat, _, ok := e.Coordinates(); ok {
+				return lat
+			}
+			return nil
+		}),
+		"longitude": locationField(func(e Event) any {
+			if _, lon, ok := e.Coordinates(); ok {
+				return lon
+			}
+			return nil
+		}),
+		"municipality": locationField(func(e Event) any {
+			if municipality := e.Region().Municipality; municipality != nil {
+				return municipality.Name
+			}
+			return nil
+		}),
+		"county": locationField(func(e Event) any {
+			if county := e.Region().County; county != nil {
+				return county.Name
+			}
+			return nil
+		}),
+	})
+	r.add("Category", map[string]field{
+		"id":    categoryField(func(c Category) any { return c.ID }),
+		"label": categoryField(func(c Category) any { return c.Label }),
+		"color": categoryField(func(c Category) any { return c.Color }),
+	})
+	r.add("Facts", map[string]field{
+		"injured":  factsField(func(f Facts) any { return f.Injured }),
+		"arrested": factsField(func(f Facts) any { return f.Arrested }),
+		"ages":     factsField(func(f Facts) any { return f.Ages }),
+		"vehicles": factsField(func(f Facts) any { return f.Vehicles }),
+		"weapons":  factsField(func(f Facts) any { return f.Weapons }),
+		"streets":  factsField(func(f Facts) any { return f.Streets }),
+	})
+	r.add("Incident", map[string]field{
+		"id":       incidentField(func(i incident.Incident) any { return i.ID }),
+		"type":     incidentField(func(i incident.Incident) any { return i.Type }),
+		"location": incidentField(func(i incident.Incident) any { return i.Location }),
+		"start":    incidentField(func(i incident.Incident) any { return i.Start }),
+		"end":      incidentField(func(i incident.Incident) any { return i.End }),
+		"events":   incidentField(func(i incident.Incident) any { return i.Timeline() }),
+	})
+	r.add("Count", map[string]field{
+		"key":   countField(func(c stats.Count) any { return c.Key }),
+		"value": countField(func(c stats.Count) any { return c.Value }),
+	})
+	r.add("Stats", map[string]field{
+		"total":      statsField(func(events []Event, _ int) any { return len(events) }),
+		"byCategory": statsField(func(events []Event, _ int) any { return stats.CountByCategory(events) }),
+		"byType":     statsField(func(events []Event, top int) any { return stats.Top(stats.CountByType(events), top) }),
+		"byLocation": statsField(func(events []Event, top int) any { return stats.Top(stats.CountByLocation(events), top) }),
+		"byCounty":   statsField(func(events []Event, _ int) any { return stats.CountByCounty(events) }),
+		"perDay":     statsField(func(events []Event, _ int) any { return stats.PerDay(events) }),
+	})
+	r.add("PageInfo", map[string]field{
+		"hasNextPage": {func(source any, _ arguments) (any, error) { return source.(pageInfo).hasNextPage, nil }},
+		"endCursor": {func(source any, _ arguments) (any, error) {
+			if cursor := source.(pageInfo).endCursor; cursor != "" {
+				return cursor, nil
+			}
+			return nil, nil
+		}},
+	})
+	connectionFields := map[string]field{
+		"totalCount": {func(source any, _ arguments) (any, error) { return source.(connection).total, nil }},
+		"edges":      {func(source any, _ arguments) (any, error) { return source.(connection).edges, nil }},
+		"nodes": {func(source any, _ arguments) (any, error) {
+			nodes := []any{}
+			for _, e := range source.(connection).edges {
+				nodes = append(nodes, e.node)
+			}
+			return nodes, nil
+		}},
+		"pageInfo": {func(source any, _ arguments) (any, error) {
+			c := source.(connection)
+			info := pageInfo{hasNextPage: c.hasNext}
+			if len(c.edges) > 0 {
+				info.endCursor = c.edges[len(c.edges)-1].cursor
+			}
+			return info, nil
+		}},
+	}
+	edgeFields := map[string]field{
+		"cursor": {func(source any, _ arguments) (any, error) { return source.(edge).cursor, nil }},
+		"node":   {func(source any, _ arguments) (any, error) { return source.(edge).node, nil }},
+	}
+	r.add("EventConnection", connectionFields)
+	r.add("IncidentConnection", connectionFields)
+	r.add("EventEdge", edgeFields)
+	r.add("IncidentEdge", edgeFields)
+	return r
+}
+
+func (r *resolvers) add(name string, fields map[string]field) {
+	r.types[name] = &objectType{name: name, fields: fields}
+}
+
+// typeOf returns the object type of a resolved value, or nil if it is a scalar or a list
+func (r *resolvers) typeOf(value any) *objectType {
+	switch value := value.(type) {
+	case Event:
+		return r.types["Event"]
+	case location:
+		return r.types["Location"]
+	case Category:
+		return r.types["Category"]
+	case Facts:
+		return r.types["Facts"]
+	case incident.Incident:
+		return r.types["Incident"]
+	case stats.Count:
+		return r.types["Count"]
+	case statistics:
+		return r.types["Stats"]
+	case pageInfo:
+		return r.types["PageInfo"]
+	case connection:
+		return r.types[value.typeName]
+	case edge:
+		return r.types[value.typeName]
+	}
+	return nil
+}
+
+// events resolves Query.events, the newest events first
+func (r *resolvers) events(_ any, args arguments) (any, error) {
+	filter, err := filterFromArguments(args)
+	if err != nil {
+		return nil, err
+	}
+	events := newestFirst(r.store.Query(filter))
+	nodes := make([]any, len(events))
+	for i, event := range events {
+		nodes[i] = event
+	}
+	return paginate("Event", nodes, func(node any) int { return node.(Event).Id }, args)
+}
+
+// event resolves Query.event
+func (r *resolvers) event(_ any, args arguments) (any, error) {
+	id, err := args.Int("id", 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range r.store.Events() {
+		if event.Id == id {
+			return event, nil
+		}
+	}
+	return nil, nil
+}
+
+// incidents resolves Query.incidents, the latest incidents first
+func (r *resolvers) incidents(_ any, args arguments) (any, error) {
+	filter, err := filterFromArguments(args)
+	if err != nil {
+		return nil, err
+	}
+	linkedOnly, err := args.Bool("linkedOnly", true)
+	if err != nil {
+		return nil, err
+	}
+	incidents := incident.Link(r.store.Query(filter), incident.DefaultOptions)
+	if linkedOnly {
+		incidents = incident.Linked(incidents)
+	}
+	var nodes []any
+	for i := len(incidents) - 1; i >= 0; i-- {
+		nodes = append(nodes, incidents[i])
+	}
+	return paginate("Incident", nodes, func(node any) int { return node.(incident.Incident).ID }, args)
+}
+
+// stats resolves Query.stats
+func (r *resolvers) stats(_ any, args arguments) (any, error) {
+	filter, err := filterFromArguments(args)
+	if err != nil {
+		return nil, err
+	}
+	return statistics{events: r.store.Query(filter)}, nil
+}
+
+// paginate returns the page of the nodes after the cursor in the arguments, a cursor is the encoded id of a node
+func paginate(typeName string, nodes []any, id func(any) int, args arguments) (connection, error) {
+	first, err := args.Int("first", 20)
+	if err != nil {
+		return connection{}, err
+	}
+	if first < 0 || first > maxPageSize {
+		return connection{}, fmt.Errorf("first must be between 0 and %d", maxPageSize)
+	}
+	after, err := args.String("after", "")
+	if err != nil {
+		return connection{}, err
+	}
+	start := 0
+	if after != "" {
+		afterID, err := decodeCursor(typeName, after)
+		if err != nil {
+			return connection{}, err
+		}
+		start = -1
+		for i, node := range nodes {
+			if id(node) == afterID {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return connection{}, fmt.Errorf("the cursor %q is no longer in the result", after)
+		}
+	}
+	end := start + first
+	if end > len(nodes) {
+		end = len(nodes)
+	}
+	page := connection{typeName: typeName + "Connection", total: len(nodes), hasNext: end < len(nodes), edges: []edge{}}
+	for _, node := range nodes[start:end] {
+		page.edges = append(page.edges, edge{typeName: typeName + "Edge", cursor: encodeCursor(typeName, id(node)), node: node})
+	}
+	return page, nil
+}
+
+func encodeCursor(typeName string, id int) string {
+	return base64.StdEncoding.EncodeToString([]byte(typeName + ":" + strconv.Itoa(id)))
+}
+
+func decodeCursor(typeName string, cursor string) (int, error) {
+	data, err := base64.StdEncoding.DecodeString(cursor)
+	if err == nil {
+		if id, ok := strings.CutPrefix(string(data), typeName+":"); ok {
+			if n, err := strconv.Atoi(id); err == nil {
+				return n, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid cursor %q", cursor)
+}
+
+// filterFromArguments reads the filter arguments like the REST API reads its query parameters
+func filterFromArguments(args arguments) (Filter, error) {
+	var filter Filter
+	var err error
+	for name, target := range map[string]*string{"type": &filter.Type, "location": &filter.Location,
+		"category": &filter.Category, "since": &filter.Since, "facts": &filter.Facts} {
+		if *target, err = args.String(name, ""); err != nil {
+			return filter, err
+		}
+	}
+	if filter.MinSeverity, err = args.Int("minSeverity", 0); err != nil {
+		return filter, err
+	}
+	near, err := args.String("near", "")
+	if err != nil {
+		return filter, err
+	}
+	if near != "" {
+		radius, err := args.String("radius", "5km")
+		if err != nil {
+			return filter, err
+		}
+		if filter.Near, err = ParseCircle(near, radius); err != nil {
+			return filter, err
+		}
+	}
+	box, err := args.String("box", "")
+	if err != nil {
+		return filter, err
+	}
+	if box != "" {
+		if filter.Box, err = ParseBoundingBox(box); err != nil {
+			return filter, err
+		}
+	}
+	return filter, filter.Validate()
+}
+
+// newestFirst returns the events sorted by datetime in reverse
+func newestFirst(events []Event) []Event {
+	reversed := make([]Event, len(events))
+	for i, event := range events {
+		reversed[len(events)-1-i] = event
+	}
+	return reversed
+}
+
+func eventField(value func(Event) any) field {
+	return field{func(source any, _ arguments) (any, error) { return value(source.(Event)), nil }}
+}
+
+func locationField(value func(Event) any) field {
+	return field{func(source any, _ arguments) (any, error) { return value(source.(location).event), nil }}
+}
+
+func categoryField(value func(Category) any) field {
+	return field{func(source any, _ arguments) (any, error) { return value(source.(Category)), nil }}
+}
+
+func factsField(value func(Facts) any) field {
+	return field{func(source any, _ arguments) (any, error) { return value(source.(Facts)), nil }}
+}
+
+func incidentField(value func(incident.Incident) any) field {
+	return field{func(source any, _ arguments) (any, error) { return value(source.(incident.Incident)), nil }}
+}
+
+func countField(value func(stats.Count) any) field {
+	return field{func(source any, _ arguments) (any, error) { return value(source.(stats.Count)), nil }}
+}
+
+// statsField resolves a field of Stats, top is the argument of the fields that take the top counts
+func statsField(value func(events []Event, top int) any) field {
+	return field{func(source any, args arguments) (any, error) {
+		top, err := args.Int("top", 10)
+		if err != nil {
+			return nil, err
+		}
+		return value(source.(statistics).events, top), nil
+	}}
+}
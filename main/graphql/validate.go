@@ -0,0 +1,122 @@
+package graphql
+
+import "fmt"
+
+// fieldTypes are the object types returned by the fields that are objects or lists of objects,
+// by type and field name. The other fields are scalars or lists of scalars.
+var fieldTypes = map[string]map[string]string{
+	"Query":              {"events": "EventConnection", "event": "Event", "incidents": "IncidentConnection", "stats": "Stats"},
+	"Subscription":       {"newEvents": "Event"},
+	"Event":              {"category": "Category", "location": "Location", "facts": "Facts"},
+	"Incident":           {"events": "Event"},
+	"Stats":              {"byCategory": "Count", "byType": "Count", "byLocation": "Count", "byCounty": "Count", "perDay": "Count"},
+	"EventConnection":    {"edges": "EventEdge", "nodes": "Event", "pageInfo": "PageInfo"},
+	"EventEdge":          {"node": "Event"},
+	"IncidentConnection": {"edges": "IncidentEdge", "nodes": "Incident", "pageInfo": "PageInfo"},
+	"IncidentEdge":       {"node": "Incident"},
+}
+
+// Limits of the selections of an operation after the fragments are expanded, a few nested fragments
+// that each spread the previous one twice would otherwise select an exponential number of fields
+const (
+	maxSelectedFields = 1000
+	maxSelectionDepth = 12
+)
+
+// selectionSize is the number of fields and the depth of a selection set with the fragments expanded
+type selectionSize struct {
+	fields int
+	depth  int
+}
+
+func (size *selectionSize) add(other selectionSize, depth int) {
+	// The count saturates above the limit so that it can not overflow
+	size.fields = size.fields + other.fields
+	if size.fields > maxSelectedFields {
+		size.fields = maxSelectedFields + 1
+	}
+	if other.depth+depth > size.depth {
+		size.depth = other.depth + depth
+	}
+}
+
+// validate checks the selections of the operation against the schema before it is executed, so that a
+// mistake is reported once instead of for every object in a list. Every fragment is checked once per type
+// it is spread into, and the operation is rejected if it selects too many fields or too deeply.
+func (r *resolvers) validate(doc *document, op *operation) []Error {
+	root := "Query"
+	if op.kind == "subscription" {
+		root = "Subscription"
+	}
+	var errors []Error
+	sizes := make(map[[2]string]selectionSize)
+	var check func(selections []selection, typeName string, path []any, fragments map[string]bool) selectionSize
+	check = func(selections []selection, typeName string, path []any, fragments map[string]bool) selectionSize {
+		typ := r.types[typeName]
+		var size selectionSize
+		for _, s := range selections {
+			switch {
+			case s.spread != "":
+				f, ok := doc.fragments[s.spread]
+				key := [2]string{s.spread, typeName}
+				if !ok {
+					errors = append(errors, Error{Message: fmt.Sprintf("unknown fragment %q", s.spread), Path: path})
+				} else if fragments[s.spread] {
+					errors = append(errors, Error{Message: fmt.Sprintf("fragment %q spreads itself", s.spread), Path: path})
+				} else if fragmentSize, ok := sizes[key]; ok {
+					size.add(fragmentSize, 0)
+				} else if r.checkTypeCondition(f.typeCondition, path, &errors) {
+					fragments[s.spread] = true
+					sizes[key] = check(f.selections, typeName, path, fragments)
+					delete(fragments, s.spread)
+					size.add(sizes[key], 0)
+				} else {
+					sizes[key] = selectionSize{}
+				}
+			case s.inline:
+				if s.typeCondition == "" || r.checkTypeCondition(s.typeCondition, path, &errors) {
+					size.add(check(s.selections, typeName, path, fragments), 0)
+				}
+			case s.name == "__typename":
+				size.add(selectionSize{fields: 1, depth: 1}, 0)
+			default:
+				key := s.name
+				if s.alias != "" {
+					key = s.alias
+				}
+				fieldPath := append(append([]any(nil), path...), key)
+				if _, ok := typ.fields[s.name]; !ok {
+					errors = append(errors, Error{Message: fmt.Sprintf("cannot query field %q on type %s", s.name, typeName), Path: fieldPath})
+					continue
+				}
+				size.add(selectionSize{fields: 1, depth: 1}, 0)
+				returned, isObject := fieldTypes[typeName][s.name]
+				if isObject && len(s.selections) == 0 {
+					errors = append(errors, Error{Message: fmt.Sprintf("field %q of type %s must have a selection of subfields", s.name, returned), Path: fieldPath})
+				} else if !isObject && len(s.selections) > 0 {
+					errors = append(errors, Error{Message: fmt.Sprintf("field %q is a scalar and can not have a selection of subfields", s.name), Path: fieldPath})
+				} else if isObject {
+					size.add(check(s.selections, returned, fieldPath, fragments), 1)
+				}
+			}
+		}
+		return size
+	}
+	size := check(op.selections, root, nil, make(map[string]bool))
+	if size.fields > maxSelectedFields {
+		errors = append(errors, Error{Message: fmt.Sprintf("the operation selects more than %d fields", maxSelectedFields)})
+	}
+	if size.depth > maxSelectionDepth {
+		errors = append(errors, Error{Message: fmt.Sprintf("the operation selects fields more than %d levels deep", maxSelectionDepth)})
+	}
+	return errors
+}
+
+// checkTypeCondition reports whether the type of a fragment exists
+func (r *resolvers) checkTypeCondition(typeName string, path []any, errors *[]Error) bool {
+	if _, ok := r.types[typeName]; !ok {
+		*errors = append(*errors, Error{Message: fmt.Sprintf("unknown type %q", typeName), Path: path})
+		return false
+	}
+	return true
+}
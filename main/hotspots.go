@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	. "project/main/event"
+	"project/main/export"
+	. "project/main/stats"
+)
+
+// listHotspots clusters the archived events matching the filter flags, for example -type Inbrott -since 30d,
+// and prints the hotspots or writes them as GeoJSON
+func listHotspots(args []string) {
+	flags := flag.NewFlagSet("hotspots", flag.ExitOnError)
+	selection := addFilterFlags(flags)
+	clusterRadius := flags.String("cluster-radius", "2km", "distance within which events are neighbours")
+	minEvents := flags.Int("min-events", DefaultHotspotOptions.MinEvents, "fewest neighbours of the core event of a hotspot")
+	geoJSON := flags.Bool("geojson", false, "write the hotspots as GeoJSON instead of text")
+	output := flags.String("o", "", "file to write, stdout if empty")
+	flags.Parse(args)
+
+	radiusKm, err := ParseDistance(*clusterRadius)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	options := HotspotOptions{RadiusKm: radiusKm, MinEvents: *minEvents}
+	if err := options.Validate(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	hotspots := Hotspots(selection.Filter().Apply(reviewedArchive()), options)
+
+	if !*geoJSON {
+		for i, hotspot := range hotspots {
+			fmt.Printf("%d. %d events within %.1f km of %.4f,%.4f (%s)\n", i+1, hotspot.Count(), hotspot.RadiusKm,
+				hotspot.Lat, hotspot.Lon, hotspot.Events[0].Region())
+		}
+		return
+	}
+	data, err := export.HotspotsGeoJSON(hotspots)
+	if err != nil {
+		fmt.Println("An error occurred while exporting the hotspots")
+		log.Fatal(err)
+	}
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Println("An error occurred while writing", *output)
+		log.Fatal(err)
+	}
+}
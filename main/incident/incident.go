@@ -0,0 +1,260 @@
+// This package links events that are about the same incident, for example the "Uppdatering" events
+// and the repeated events polisen.se publishes while an incident develops, into incident records
+// with a timeline of their events
+package incident
+
+import (
+	. "project/main/event"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Fewest words two event texts need to share to be similar
+const minSharedWords = 3
+
+// UpdateType is the type polisen.se uses for events that follow up on an earlier event
+const UpdateType = "Uppdatering"
+
+// Options controls how events are linked into incidents
+type Options struct {
+	// Window is the longest time between two consecutive events of an incident
+	Window time.Duration
+	// MinSimilarity is the text similarity, between 0 and 1, an event of the same type needs to join an incident
+	MinSimilarity float64
+	// MinUpdateSimilarity is the text similarity an update event needs to join an incident of another type
+	MinUpdateSimilarity float64
+}
+
+// DefaultOptions links events within 12 hours of each other
+var DefaultOptions = Options{
+	Window:              12 * time.Hour,
+	MinSimilarity:       0.6,
+	MinUpdateSimilarity: 0.2,
+}
+
+// Incident is a group of events about the same incident. ID is the Id of the first event,
+// Type is the type of the first event that is not an update.
+type Incident struct {
+	ID       int
+	Type     string
+	Location string
+	Start    time.Time
+	End      time.Time
+	Events   []Event
+}
+
+// Timeline returns the events of the incident from the first to the last
+func (i Incident) Timeline() []Event {
+	return i.Events
+}
+
+// Contains reports whether the event with the given id is part of the incident
+func (i Incident) Contains(id int) bool {
+	for _, event := range i.Events {
+		if event.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the incident as for example "Trafikolycka, Stockholm (3 events)"
+func (i Incident) String() string {
+	text := i.Type + ", " + i.Location
+	if len(i.Events) == 1 {
+		return text + " (1 event)"
+	}
+	return text + " (" + strconv.Itoa(len(i.Events)) + " events)"
+}
+
+// linked is an incident while it is built, with the words of every event for the text similarity
+type linked struct {
+	incident *Incident
+	words    []map[string]bool
+	places   map[string]bool
+}
+
+// similarity returns the highest text similarity between the words and one of the events of the incident
+func (l *linked) similarity(words map[string]bool) float64 {
+	best := 0.0
+	for _, eventWords := range l.words {
+		if score := jaccard(eventWords, words); score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// Link groups the events into incidents, ordered by their first event. Every event is in exactly one
+// incident. An event joins the incident with the most similar event text that was last updated within
+// options.Window at the same place and that has the same type, or any type if the event is an update.
+// Events that are republished with the same time of occurrence in their Name always join.
+func Link(events []Event, options Options) []Incident {
+	sorted := make([]Event, len(events))
+	copy(sorted, events)
+	sort.Stable(ByDatetime(sorted))
+
+	var open []*linked
+	var all []*linked
+	for _, event := range sorted {
+		datetime := event.Time()
+		eventWords := words(event)
+		eventPlaces := places(event)
+
+		var best *linked
+		bestScore := 0.0
+		for _, candidate := range open {
+			if datetime.Sub(candidate.incident.End) > options.Window || !overlaps(candidate.places, eventPlaces) {
+				continue
+			}
+			minimum := options.MinSimilarity
+			if event.Type != candidate.incident.Type {
+				if event.Type != UpdateType && candidate.incident.Type != UpdateType {
+					continue
+				}
+				minimum = options.MinUpdateSimilarity
+			}
+			score := 0.0
+			if sameOccurrence(candidate.incident.Events[0], event) {
+				score = 1
+			} else if !isReport(event) {
+				score = candidate.similarity(eventWords)
+			}
+			if score >= minimum && score > bestScore {
+				best, bestScore = candidate, score
+			}
+		}
+
+		if best == nil {
+			best = &linked{
+				incident: &Incident{ID: event.Id, Type: event.Type, Location: event.Location.Name, Start: datetime},
+				places:   make(map[string]bool),
+			}
+			open = append(open, best)
+			all = append(all, best)
+		}
+		best.incident.Events = append(best.incident.Events, event)
+		best.incident.End = datetime
+		if best.incident.Type == UpdateType && event.Type != UpdateType {
+			best.incident.Type = event.Type
+		}
+		best.words = append(best.words, eventWords)
+		for place := range eventPlaces {
+			best.places[place] = true
+		}
+
+		// Incidents that have been quiet for longer than the window can not be joined any more
+		stillOpen := open[:0]
+		for _, candidate := range open {
+			if datetime.Sub(candidate.incident.End) <= options.Window {
+				stillOpen = append(stillOpen, candidate)
+			}
+		}
+		open = stillOpen
+	}
+
+	incidents := make([]Incident, len(all))
+	for i, l := range all {
+		incidents[i] = *l.incident
+	}
+	return incidents
+}
+
+// Linked returns the incidents that consist of more than one event
+func Linked(incidents []Incident) []Incident {
+	var linked []Incident
+	for _, incident := range incidents {
+		if len(incident.Events) > 1 {
+			linked = append(linked, incident)
+		}
+	}
+	return linked
+}
+
+// Find returns the incident the event with the given id is part of
+func Find(incidents []Incident, id int) (Incident, bool) {
+	for _, incident := range incidents {
+		if incident.Contains(id) {
+			return incident, true
+		}
+	}
+	return Incident{}, false
+}
+
+// reportCategories are the categories of the taxonomy whose events are recurring reports, such as
+// the summaries of a night or the results of traffic checks, rather than incidents
+var reportCategories = map[string]bool{"summary": true, "control": true}
+
+// isReport reports whether the event is a recurring report. Reports share most of their text with
+// the previous report of the same place, so they are only linked when they are republished.
+func isReport(event Event) bool {
+	return event.Type != UpdateType && reportCategories[event.Category().ID]
+}
+
+// places returns the location name and the municipality of the event, two events are at the same
+// place if they share one of them
+func places(event Event) map[string]bool {
+	result := map[string]bool{strings.ToLower(event.Location.Name): true}
+	if region := event.Region(); region.Municipality != nil {
+		result["kommun:"+region.Municipality.Code] = true
+	}
+	return result
+}
+
+func overlaps(a, b map[string]bool) bool {
+	for key := range b {
+		if a[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// sameOccurrence reports whether two events state the same time of occurrence in the first part of their
+// Name, for example "20 april 10:31", which polisen.se keeps when it republishes an incident
+func sameOccurrence(a, b Event) bool {
+	occurrenceA, _, okA := strings.Cut(a.Name, ",")
+	occurrenceB, _, okB := strings.Cut(b.Name, ",")
+	return okA && okB && occurrenceA == occurrenceB && strings.EqualFold(a.Location.Name, b.Location.Name)
+}
+
+// words returns the lower case words of the summary. Words shorter than four letters are left out because
+// they are mostly prepositions, and so are the words of the type and location that every event of an
+// incident shares with the unrelated events of the same type and place.
+func words(event Event) map[string]bool {
+	common := make(map[string]bool)
+	for _, word := range splitWords(event.Type + " " + event.Location.Name) {
+		common[word] = true
+	}
+	result := make(map[string]bool)
+	for _, word := range splitWords(event.Summary) {
+		if len([]rune(word)) >= 4 && !common[word] {
+			result[word] = true
+		}
+	}
+	return result
+}
+
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// jaccard returns the number of shared words divided by the number of distinct words. Short texts that
+// share fewer than minSharedWords words are not similar, "person omhändertagen" is not an incident.
+func jaccard(a, b map[string]bool) float64 {
+	shared := 0
+	for word := range b {
+		if a[word] {
+			shared++
+		}
+	}
+	if shared < minSharedWords {
+		return 0
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
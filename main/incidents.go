@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"project/main/incident"
+)
+
+// Layout of the times in the output of the incidents command
+const incidentTimeLayout = "2006-01-02 15:04"
+
+// listIncidents links the archived events matching the filter flags into incidents and prints every
+// incident with the timeline of its events. By default only incidents with more than one event are printed.
+func listIncidents(args []string) {
+	flags := flag.NewFlagSet("incidents", flag.ExitOnError)
+	selection := addFilterFlags(flags)
+	id := flags.Int("id", 0, "only print the incident of the event with this Id")
+	all := flags.Bool("all", false, "also print the incidents that consist of a single event")
+	window := flags.Duration("window", incident.DefaultOptions.Window, "longest time between two events of an incident")
+	flags.Parse(args)
+
+	options := incident.DefaultOptions
+	options.Window = *window
+	incidents := incident.Link(selection.Filter().Apply(reviewedArchive()), options)
+
+	if *id != 0 {
+		found, ok := incident.Find(incidents, *id)
+		if !ok {
+			fmt.Println("No event with Id", *id, "matches the filter")
+			os.Exit(1)
+		}
+		printIncident(found)
+		return
+	}
+	if !*all {
+		incidents = incident.Linked(incidents)
+	}
+	for _, found := range incidents {
+		printIncident(found)
+	}
+}
+
+// printIncident prints the incident followed by its events from the first to the last
+func printIncident(found incident.Incident) {
+	fmt.Printf("Incident %d: %s, %s – %s\n", found.ID, found, found.Start.Format(incidentTimeLayout), found.End.Format(incidentTimeLayout))
+	for _, event := range found.Timeline() {
+		fmt.Println("  ", event.Time().Format(incidentTimeLayout), "----", event.Id, "----", event.Name)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	. "project/main/event"
+)
+
+// Layout of the first and last seen dates in the output of the keys command
+const keysDateLayout = "2006-01-02"
+
+// listKeys prints the types or locations of the archived events with their number of events and
+// when they were first and last seen. The keys missing from the curated list are printed as a separate group.
+func listKeys(args []string) {
+	flags := flag.NewFlagSet("keys", flag.ExitOnError)
+	kind := flags.String("kind", "type", "type or location")
+	unknownOnly := flags.Bool("unknown", false, "only print the unknown keys")
+	flags.Parse(args)
+
+	var usages []KeyUsage
+	var knownTitle, unknownTitle string
+	switch *kind {
+	case "type":
+		usages = TypeUsage(reviewedArchive())
+		knownTitle, unknownTitle = "Known types", "Unknown types (not in TypeKeys)"
+	case "location":
+		usages = LocationUsage(reviewedArchive())
+		knownTitle, unknownTitle = "Kommuner and län", "Other locations (not in the gazetteer)"
+	default:
+		fmt.Println("Unknown kind", *kind+", expected type or location")
+		os.Exit(2)
+	}
+
+	if !*unknownOnly {
+		printKeyUsages(knownTitle, usages, true)
+		fmt.Println()
+	}
+	printKeyUsages(unknownTitle, usages, false)
+}
+
+// printKeyUsages prints the usages that are known or unknown under a title
+func printKeyUsages(title string, usages []KeyUsage, known bool) {
+	fmt.Println(title)
+	printed := 0
+	for _, usage := range usages {
+		if usage.Known != known {
+			continue
+		}
+		fmt.Printf("  %-40s %5d  first seen %s  last seen %s\n", usage.Key, usage.Count,
+			usage.FirstSeen.Format(keysDateLayout), usage.LastSeen.Format(keysDateLayout))
+		printed++
+	}
+	if printed == 0 {
+		fmt.Println("  none")
+	}
+}
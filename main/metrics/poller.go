@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	. "project/main/event"
+	"sync"
+	"time"
+)
+
+// Poller holds the metrics of the fetches of the watch command and the archive they are merged into
+type Poller struct {
+	Registry *Registry
+
+	fetchDuration  *Histogram
+	fetchErrors    *Counter
+	fetched        *Counter
+	newEvents      *Counter
+	duplicates     *Counter
+	lastNew        *Gauge
+	lastDuplicates *Gauge
+	lastSuccess    *Gauge
+	archiveSize    *Gauge
+	byType         *GaugeVec
+	byCounty       *GaugeVec
+
+	mu      sync.Mutex
+	success time.Time
+}
+
+// NewPoller registers the metrics of the poller. The hits and misses of the summary cache are
+// exposed too if cache is not nil.
+func NewPoller(cache *SummaryCache) *Poller {
+	r := NewRegistry()
+	p := &Poller{
+		Registry: r,
+		fetchDuration: r.NewHistogram("police_fetch_duration_seconds", "Duration of the fetches from the API.",
+			[]float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}),
+		fetchErrors:    r.NewCounter("police_fetch_errors_total", "Fetches from the API that failed."),
+		fetched:        r.NewCounter("police_events_fetched_total", "Events returned by the API."),
+		newEvents:      r.NewCounter("police_events_new_total", "Fetched events that were not in the archive."),
+		duplicates:     r.NewCounter("police_events_duplicate_total", "Fetched events that were already in the archive."),
+		lastNew:        r.NewGauge("police_last_poll_new_events", "New events of the last successful poll."),
+		lastDuplicates: r.NewGauge("police_last_poll_duplicate_events", "Duplicate events of the last successful poll."),
+		lastSuccess:    r.NewGauge("police_last_successful_fetch_timestamp_seconds", "Unix time of the last successful fetch."),
+		archiveSize:    r.NewGauge("police_archive_events", "Events in the archive."),
+		byType:         r.NewGaugeVec("police_archive_events_by_type", "Events in the archive per type.", "type"),
+		byCounty:       r.NewGaugeVec("police_archive_events_by_county", "Events in the archive per län.", "county"),
+	}
+	if cache != nil {
+		r.NewCounterFunc("police_summary_cache_hits_total", "Lookups of extended summaries that were cached.", func() float64 {
+			hits, _ := cache.Stats()
+			return float64(hits)
+		})
+		r.NewCounterFunc("police_summary_cache_misses_total", "Lookups of extended summaries that were not cached.", func() float64 {
+			_, misses := cache.Stats()
+			return float64(misses)
+		})
+	}
+	return p
+}
+
+// ObserveFetch records a fetch that took the duration and returned the events or failed with err
+func (p *Poller) ObserveFetch(duration time.Duration, fetched int, err error) {
+	p.fetchDuration.Observe(duration.Seconds())
+	if err != nil {
+		p.fetchErrors.Inc()
+		return
+	}
+	now := time.Now()
+	p.mu.Lock()
+	p.success = now
+	p.mu.Unlock()
+	p.lastSuccess.Set(float64(now.Unix()))
+	p.fetched.Add(float64(fetched))
+}
+
+// ObserveMerge records how many fetched events were new and how many duplicates MergeEvents found,
+// and the size of the merged archive per type and län
+func (p *Poller) ObserveMerge(newEvents int, duplicates int, archive []Event) {
+	p.newEvents.Add(float64(newEvents))
+	p.duplicates.Add(float64(duplicates))
+	p.lastNew.Set(float64(newEvents))
+	p.lastDuplicates.Set(float64(duplicates))
+	p.archiveSize.Set(float64(len(archive)))
+	byType := make(map[string]float64)
+	byCounty := make(map[string]float64)
+	for _, event := range archive {
+		byType[event.Type]++
+		if county := event.Region().County; county != nil {
+			byCounty[county.Name]++
+		}
+	}
+	p.byType.Reset(byType)
+	p.byCounty.Reset(byCounty)
+}
+
+// Health returns a handler for /healthz that fails with 503 Service Unavailable when there has been
+// no successful fetch within maxAge
+func (p *Poller) Health(maxAge time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		success := p.success
+		p.mu.Unlock()
+		if success.IsZero() {
+			http.Error(w, "no successful fetch yet", http.StatusServiceUnavailable)
+			return
+		}
+		age := time.Since(success).Round(time.Second)
+		if age > maxAge {
+			http.Error(w, fmt.Sprintf("last successful fetch %s ago, more than %s", age, maxAge), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, "ok, last successful fetch %s ago\n", age)
+	}
+}
+
+// Serve serves /metrics and /healthz on the address until the server fails
+func (p *Poller) Serve(addr string, maxAge time.Duration) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", p.Registry)
+	mux.HandleFunc("/healthz", p.Health(maxAge))
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,223 @@
+// This package exposes metrics in the Prometheus text format, for example of the fetches of the watch
+// command, so that a poller running unattended can be monitored and alerted on
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// metric is anything that can write its samples in the text format
+type metric interface {
+	write(w io.Writer)
+}
+
+// Registry holds the metrics that are exposed together
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// ServeHTTP writes every metric in the Prometheus text exposition format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Counter is a value that only increases
+type Counter struct {
+	name, help string
+	mu         sync.Mutex
+	value      float64
+}
+
+// NewCounter registers a counter
+func (r *Registry) NewCounter(name string, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	r.register(c)
+	return c
+}
+
+// Add increases the counter
+func (c *Counter) Add(value float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value += value
+}
+
+// Inc increases the counter by one
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeHeader(w, c.name, c.help, "counter")
+	fmt.Fprintf(w, "%s %s\n", c.name, formatValue(c.value))
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	name, help string
+	mu         sync.Mutex
+	value      float64
+}
+
+// NewGauge registers a gauge
+func (r *Registry) NewGauge(name string, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	r.register(g)
+	return g
+}
+
+// Set sets the value of the gauge
+func (g *Gauge) Set(value float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.value = value
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatValue(g.value))
+}
+
+// funcMetric is a counter or gauge whose value is read when the metrics are scraped
+type funcMetric struct {
+	name, help, kind string
+	value            func() float64
+}
+
+// NewCounterFunc registers a counter whose value is returned by the function
+func (r *Registry) NewCounterFunc(name string, help string, value func() float64) {
+	r.register(&funcMetric{name: name, help: help, kind: "counter", value: value})
+}
+
+// NewGaugeFunc registers a gauge whose value is returned by the function
+func (r *Registry) NewGaugeFunc(name string, help string, value func() float64) {
+	r.register(&funcMetric{name: name, help: help, kind: "gauge", value: value})
+}
+
+func (f *funcMetric) write(w io.Writer) {
+	writeHeader(w, f.name, f.help, f.kind)
+	fmt.Fprintf(w, "%s %s\n", f.name, formatValue(f.value()))
+}
+
+// GaugeVec is a gauge with one label, such as the number of events per type
+type GaugeVec struct {
+	name, help, label string
+	mu                sync.Mutex
+	values            map[string]float64
+}
+
+// NewGaugeVec registers a gauge with a label
+func (r *Registry) NewGaugeVec(name string, help string, label string) *GaugeVec {
+	g := &GaugeVec{name: name, help: help, label: label, values: make(map[string]float64)}
+	r.register(g)
+	return g
+}
+
+// Reset replaces every value of the gauge, label values that are missing are no longer exposed
+func (g *GaugeVec) Reset(values map[string]float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values = values
+}
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	writeHeader(w, g.name, g.help, "gauge")
+	labels := make([]string, 0, len(g.values))
+	for label := range g.values {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %s\n", g.name, g.label, escapeLabel(label), formatValue(g.values[label]))
+	}
+}
+
+// Histogram counts observations, such as durations in seconds, in cumulative buckets
+type Histogram struct {
+	name, help string
+	buckets    []float64
+	mu         sync.Mutex
+	counts     []uint64
+	sum        float64
+	count      uint64
+}
+
+// NewHistogram registers a histogram with the upper bounds of its buckets in increasing order
+func (r *Registry) NewHistogram(name string, help string, buckets []float64) *Histogram {
+	h := &Histogram{name: name, help: help, buckets: buckets, counts: make([]uint64, len(buckets))}
+	r.register(h)
+	return h
+}
+
+// Observe adds an observation
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.name, h.help, "histogram")
+	for i, bound := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.name, formatValue(bound), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.name, formatValue(h.sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.name, h.count)
+}
+
+func writeHeader(w io.Writer, name string, help string, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help), name, kind)
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"project/main/mqtt"
+)
+
+// mqttFlags are the flags of the watch command that publish the new events to an MQTT broker
+type mqttFlags struct {
+	broker   *string
+	prefix   *string
+	qos      *int
+	clientID *string
+	username *string
+	password *string
+	caFile   *string
+}
+
+// addMQTTFlags defines the MQTT flags on a command
+func addMQTTFlags(flags *flag.FlagSet) *mqttFlags {
+	return &mqttFlags{
+		broker:   flags.String("mqtt", "", "broker the new events are published to, for example tcp://localhost:1883 or ssl://host:8883"),
+		prefix:   flags.String("mqtt-topic", mqtt.DefaultPrefix, "first levels of the topics, the events are published to <topic>/<län>/<type>"),
+		qos:      flags.Int("mqtt-qos", 1, "QoS of the published messages, 0, 1 or 2"),
+		clientID: flags.String("mqtt-client-id", "police-events", "client identifier at the broker"),
+		username: flags.String("mqtt-username", "", "user name at the broker"),
+		password: flags.String("mqtt-password", os.Getenv("MQTT_PASSWORD"), "password at the broker, MQTT_PASSWORD by default"),
+		caFile:   flags.String("mqtt-ca", "", "PEM file of the certificate authority of a TLS broker, the system roots if empty"),
+	}
+}
+
+// Publisher connects to the broker of the flags, it returns nil if no broker was given. The program exits
+// if a flag is invalid, a broker that can not be reached is logged and retried by Publish.
+func (f *mqttFlags) Publisher() *mqtt.Publisher {
+	if *f.broker == "" {
+		return nil
+	}
+	if *f.qos < 0 || *f.qos > 2 {
+		fmt.Println("Invalid -mqtt-qos", *f.qos, "expected 0, 1 or 2")
+		os.Exit(2)
+	}
+	options := mqtt.Options{Broker: *f.broker, ClientID: *f.clientID, Username: *f.username, Password: *f.password}
+	if *f.caFile != "" {
+		pem, err := os.ReadFile(*f.caFile)
+		if err != nil {
+			fmt.Println("An error occurred while reading the certificate authority")
+			log.Fatal(err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			fmt.Println("No certificates in", *f.caFile)
+			os.Exit(2)
+		}
+		options.TLSConfig = &tls.Config{RootCAs: roots}
+	}
+	publisher, err := mqtt.NewPublisher(options, *f.prefix, byte(*f.qos))
+	if err != nil {
+		log.Println("The MQTT broker could not be reached, the next poll tries again:", err)
+	}
+	return publisher
+}
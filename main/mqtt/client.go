@@ -0,0 +1,301 @@
+// This package publishes the newly merged events to an MQTT broker, for example Mosquitto, for home
+// automation and dashboards. It contains a small MQTT 3.1.1 client that can only publish.
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Message is a message that is published, or the last will the broker publishes when the client disappears
+type Message struct {
+	Topic   string
+	Payload []byte
+	QoS     byte
+	Retain  bool
+}
+
+// Options describe the connection to the broker
+type Options struct {
+	// Broker is the URL of the broker, tcp://host:1883 or, with TLS, ssl://host:8883 (tls:// and mqtts:// also work)
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// TLSConfig is used for the TLS brokers, the server name is the host if it is nil
+	TLSConfig *tls.Config
+	// KeepAlive is the longest time between two packets, the client pings the broker when it has nothing to send
+	KeepAlive time.Duration
+	// Will is published by the broker if the connection is lost without a disconnect
+	Will *Message
+	// Timeout is how long to wait for the broker to connect and to acknowledge a message
+	Timeout time.Duration
+}
+
+// Client is a connection to a broker. Messages are published one at a time, a message with QoS 1 or 2
+// is acknowledged by the broker before Publish returns.
+type Client struct {
+	conn    net.Conn
+	timeout time.Duration
+
+	mu     sync.Mutex
+	nextID uint16
+	acks   map[uint16]chan packet
+	err    error
+	done   chan struct{}
+}
+
+// connectReturnCodes explain the refusals of a connection
+var connectReturnCodes = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Connect connects to the broker and starts to keep the connection alive
+func Connect(options Options) (*Client, error) {
+	if options.Timeout == 0 {
+		options.Timeout = 10 * time.Second
+	}
+	if options.KeepAlive == 0 {
+		options.KeepAlive = time.Minute
+	}
+	broker, err := url.Parse(options.Broker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker %q: %w", options.Broker, err)
+	}
+	dialer := &net.Dialer{Timeout: options.Timeout}
+	var conn net.Conn
+	switch broker.Scheme {
+	case "tcp", "mqtt":
+		conn, err = dialer.Dial("tcp", hostPort(broker, "1883"))
+	case "ssl", "tls", "mqtts":
+		config := options.TLSConfig
+		if config == nil {
+			config = &tls.Config{ServerName: broker.Hostname()}
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostPort(broker, "8883"), config)
+	default:
+		return nil, fmt.Errorf("invalid broker %q, expected tcp:// or ssl://", options.Broker)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newClient(conn, options)
+}
+
+// newClient connects over an open connection to the broker, the options have their defaults
+func newClient(conn net.Conn, options Options) (*Client, error) {
+	client := &Client{conn: conn, timeout: options.Timeout, acks: make(map[uint16]chan packet), done: make(chan struct{})}
+	reader := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(options.Timeout))
+	if err := client.write(connectPacket(options)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	connack, err := readPacket(reader)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if connack.header != packetConnack || len(connack.body) != 2 {
+		conn.Close()
+		return nil, errors.New("the broker did not acknowledge the connection")
+	}
+	if code := connack.body[1]; code != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("the broker refused the connection: %s", connectReturnCodes[code])
+	}
+	conn.SetDeadline(time.Time{})
+
+	go client.read(reader)
+	go client.keepAlive(options.KeepAlive)
+	return client, nil
+}
+
+// connectPacket builds the CONNECT packet of a clean session
+func connectPacket(options Options) packet {
+	flags := byte(0x02)
+	if options.Will != nil {
+		flags |= 0x04 | options.Will.QoS<<3
+		if options.Will.Retain {
+			flags |= 0x20
+		}
+	}
+	if options.Password != "" {
+		flags |= 0x40
+	}
+	if options.Username != "" {
+		flags |= 0x80
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(options.KeepAlive/time.Second))
+	body = appendString(body, options.ClientID)
+	if options.Will != nil {
+		body = appendString(body, options.Will.Topic)
+		body = appendString(body, string(options.Will.Payload))
+	}
+	if options.Username != "" {
+		body = appendString(body, options.Username)
+	}
+	if options.Password != "" {
+		body = appendString(body, options.Password)
+	}
+	return packet{header: packetConnect, body: body}
+}
+
+// Publish publishes the message and waits for the acknowledgement of its QoS
+func (c *Client) Publish(message Message) error {
+	if message.QoS > 2 {
+		return fmt.Errorf("invalid QoS %d", message.QoS)
+	}
+	header := byte(packetPublish) | message.QoS<<1
+	if message.Retain {
+		header |= 1
+	}
+	body := appendString(nil, message.Topic)
+	var id uint16
+	var acks chan packet
+	if message.QoS > 0 {
+		id, acks = c.expect()
+		defer c.forget(id)
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+	body = append(body, message.Payload...)
+	if err := c.write(packet{header: header, body: body}); err != nil {
+		return err
+	}
+	switch message.QoS {
+	case 1:
+		_, err := c.await(acks, packetPuback)
+		return err
+	case 2:
+		if _, err := c.await(acks, packetPubrec); err != nil {
+			return err
+		}
+		if err := c.write(packet{header: packetPubrel, body: binary.BigEndian.AppendUint16(nil, id)}); err != nil {
+			return err
+		}
+		_, err := c.await(acks, packetPubcomp)
+		return err
+	}
+	return nil
+}
+
+// Close disconnects from the broker, the broker does not publish the last will
+func (c *Client) Close() error {
+	c.write(packet{header: packetDisconnect})
+	return c.conn.Close()
+}
+
+// Err returns the error that broke the connection, or nil while it works
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// write writes a packet, the connection is shared by Publish and the keep alive
+func (c *Client) write(p packet) error {
+	encoded, err := p.encode()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	_, err = c.conn.Write(encoded)
+	return err
+}
+
+// expect reserves a packet identifier and the channel its acknowledgements are delivered to
+func (c *Client) expect() (uint16, chan packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	acks := make(chan packet, 2)
+	c.acks[c.nextID] = acks
+	return c.nextID, acks
+}
+
+func (c *Client) forget(id uint16) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.acks, id)
+}
+
+// await waits for an acknowledgement of the given type
+func (c *Client) await(acks chan packet, header byte) (packet, error) {
+	select {
+	case ack := <-acks:
+		if ack.header != header {
+			return ack, fmt.Errorf("unexpected mqtt packet %#x", ack.header)
+		}
+		return ack, nil
+	case <-c.done:
+		return packet{}, c.Err()
+	case <-time.After(c.timeout):
+		return packet{}, errors.New("the broker did not acknowledge the message in time")
+	}
+}
+
+// read delivers the acknowledgements until the connection fails
+func (c *Client) read(reader *bufio.Reader) {
+	for {
+		p, err := readPacket(reader)
+		if err != nil {
+			c.mu.Lock()
+			c.err = fmt.Errorf("mqtt connection lost: %w", err)
+			c.mu.Unlock()
+			close(c.done)
+			return
+		}
+		switch p.header {
+		case packetPuback, packetPubrec, packetPubcomp:
+			c.mu.Lock()
+			acks, ok := c.acks[packetID(p)]
+			c.mu.Unlock()
+			if ok {
+				acks <- p
+			}
+		}
+	}
+}
+
+// keepAlive pings the broker at half the keep alive interval so that it never closes the connection
+func (c *Client) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.write(packet{header: packetPingreq})
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// hostPort returns host:port of the broker with the default port if it has none
+func hostPort(broker *url.URL, defaultPort string) string {
+	if broker.Port() != "" {
+		return broker.Host
+	}
+	return net.JoinHostPort(broker.Hostname(), defaultPort)
+}
@@ -0,0 +1,196 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBroker is the broker end of a net.Pipe, it acknowledges the packets of the client as a broker would
+// and records them
+type fakeBroker struct {
+	conn net.Conn
+	// returnCode is the return code of the CONNACK
+	returnCode byte
+	// silent brokers do not acknowledge the messages
+	silent   bool
+	received chan packet
+}
+
+// connectFake connects a client to a fake broker over a pipe
+func connectFake(t *testing.T, broker *fakeBroker, options Options) (*Client, error) {
+	t.Helper()
+	clientConn, brokerConn := net.Pipe()
+	broker.conn = brokerConn
+	broker.received = make(chan packet, 100)
+	go broker.serve()
+	t.Cleanup(func() { brokerConn.Close() })
+	if options.Timeout == 0 {
+		options.Timeout = time.Second
+	}
+	if options.KeepAlive == 0 {
+		options.KeepAlive = time.Minute
+	}
+	return newClient(clientConn, options)
+}
+
+func (b *fakeBroker) serve() {
+	reader := bufio.NewReader(b.conn)
+	for {
+		p, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		b.received <- p
+		switch {
+		case p.header == packetConnect:
+			b.send(packet{header: packetConnack, body: []byte{0, b.returnCode}})
+		case p.header&0xf0 == packetPublish && !b.silent:
+			qos := p.header >> 1 & 3
+			_, rest := splitString(p.body)
+			if qos == 1 {
+				b.send(packet{header: packetPuback, body: rest[:2]})
+			} else if qos == 2 {
+				b.send(packet{header: packetPubrec, body: rest[:2]})
+			}
+		case p.header == packetPubrel:
+			b.send(packet{header: packetPubcomp, body: p.body})
+		case p.header == packetPingreq:
+			b.send(packet{header: packetPingresp})
+		}
+	}
+}
+
+func (b *fakeBroker) send(p packet) {
+	encoded, _ := p.encode()
+	b.conn.Write(encoded)
+}
+
+// next returns the next packet the broker received
+func (b *fakeBroker) next(t *testing.T) packet {
+	t.Helper()
+	select {
+	case p := <-b.received:
+		return p
+	case <-time.After(time.Second):
+		t.Fatal("the broker received no packet")
+		return packet{}
+	}
+}
+
+func splitString(b []byte) (string, []byte) {
+	length := int(binary.BigEndian.Uint16(b))
+	return string(b[2 : 2+length]), b[2+length:]
+}
+
+func TestConnectRefused(t *testing.T) {
+	_, err := connectFake(t, &fakeBroker{returnCode: 4}, Options{ClientID: "events"})
+	if err == nil || !strings.Contains(err.Error(), "bad user name or password") {
+		t.Fatalf("connecting = %v, want a refusal", err)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	for _, message := range []Message{
+		{Topic: "police/events/uppsala-län/brand", Payload: []byte(`{"id":1}`)},
+		{Topic: "police/events/status", Payload: []byte("online"), QoS: 1, Retain: true},
+		{Topic: "police/events/stockholms-län/rån", Payload: []byte(`{"id":2}`), QoS: 2},
+	} {
+		broker := &fakeBroker{}
+		client, err := connectFake(t, broker, Options{ClientID: "events"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		broker.next(t)
+		if err := client.Publish(message); err != nil {
+			t.Fatalf("Publish with QoS %d: %v", message.QoS, err)
+		}
+
+		publish := broker.next(t)
+		if publish.header&0xf0 != packetPublish || publish.header>>1&3 != message.QoS || (publish.header&1 == 1) != message.Retain {
+			t.Errorf("the PUBLISH header is %08b for QoS %d retained %v", publish.header, message.QoS, message.Retain)
+		}
+		topic, rest := splitString(publish.body)
+		if topic != message.Topic {
+			t.Errorf("topic %q, want %q", topic, message.Topic)
+		}
+		var id uint16
+		if message.QoS > 0 {
+			if id = binary.BigEndian.Uint16(rest); id == 0 {
+				t.Error("a message with QoS > 0 has the packet identifier 0")
+			}
+			rest = rest[2:]
+		}
+		if string(rest) != string(message.Payload) {
+			t.Errorf("payload %q, want %q", rest, message.Payload)
+		}
+		if message.QoS == 2 {
+			pubrel := broker.next(t)
+			if pubrel.header != packetPubrel || packetID(pubrel) != id {
+				t.Errorf("PUBREL %#x for the identifier %d", pubrel.header, packetID(pubrel))
+			}
+		}
+		client.Close()
+		if disconnect := broker.next(t); disconnect.header != packetDisconnect {
+			t.Errorf("Close sent %#x, want DISCONNECT", disconnect.header)
+		}
+	}
+}
+
+func TestPublishInvalidQoS(t *testing.T) {
+	client, err := connectFake(t, &fakeBroker{}, Options{ClientID: "events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Publish(Message{Topic: "t", QoS: 3}); err == nil {
+		t.Error("Publish accepted QoS 3")
+	}
+}
+
+func TestPublishAckTimeout(t *testing.T) {
+	for _, qos := range []byte{1, 2} {
+		client, err := connectFake(t, &fakeBroker{silent: true}, Options{ClientID: "events", Timeout: 50 * time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = client.Publish(Message{Topic: "t", Payload: []byte("x"), QoS: qos})
+		if err == nil || !strings.Contains(err.Error(), "in time") {
+			t.Errorf("Publish with QoS %d to a silent broker = %v, want a timeout", qos, err)
+		}
+	}
+}
+
+func TestConnectionLost(t *testing.T) {
+	broker := &fakeBroker{silent: true}
+	client, err := connectFake(t, broker, Options{ClientID: "events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker.next(t)
+	done := make(chan error)
+	go func() { done <- client.Publish(Message{Topic: "t", QoS: 1}) }()
+	broker.next(t)
+	broker.conn.Close()
+	select {
+	case err := <-done:
+		if err == nil || client.Err() == nil {
+			t.Errorf("Publish = %v and Err = %v after the connection was lost", err, client.Err())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Publish did not return when the connection was lost")
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	broker := &fakeBroker{}
+	if _, err := connectFake(t, broker, Options{ClientID: "events", KeepAlive: 20 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	broker.next(t)
+	if ping := broker.next(t); ping.header != packetPingreq {
+		t.Errorf("the client sent %#x, want PINGREQ", ping.header)
+	}
+}
@@ -0,0 +1,94 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Control packet types of MQTT 3.1.1, shifted into the upper four bits of the fixed header
+const (
+	packetConnect    = 1 << 4
+	packetConnack    = 2 << 4
+	packetPublish    = 3 << 4
+	packetPuback     = 4 << 4
+	packetPubrec     = 5 << 4
+	packetPubrel     = 6<<4 | 2
+	packetPubcomp    = 7 << 4
+	packetPingreq    = 12 << 4
+	packetPingresp   = 13 << 4
+	packetDisconnect = 14 << 4
+)
+
+// maxRemainingLength is the largest remaining length that can be encoded in four bytes
+const maxRemainingLength = 268435455
+
+// packet is a control packet, header is the first byte of the fixed header
+type packet struct {
+	header byte
+	body   []byte
+}
+
+// encode returns the packet with its fixed header
+func (p packet) encode() ([]byte, error) {
+	length := len(p.body)
+	if length > maxRemainingLength {
+		return nil, errors.New("mqtt packet too large")
+	}
+	encoded := []byte{p.header}
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 128
+		}
+		encoded = append(encoded, digit)
+		if length == 0 {
+			break
+		}
+	}
+	return append(encoded, p.body...), nil
+}
+
+// readPacket reads one control packet
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		if i == 4 {
+			return packet{}, errors.New("malformed mqtt remaining length")
+		}
+		length += int(digit&127) * multiplier
+		multiplier *= 128
+		if digit&128 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{header: header, body: body}, nil
+}
+
+// appendString appends a string prefixed by its length as MQTT encodes strings and binary data
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// packetID returns the packet identifier at the start of an acknowledgement
+func packetID(p packet) uint16 {
+	if len(p.body) < 2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(p.body)
+}
@@ -0,0 +1,106 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestPacketRemainingLength(t *testing.T) {
+	tests := []struct {
+		length int
+		header []byte
+	}{
+		{0, []byte{0x30, 0x00}},
+		{127, []byte{0x30, 0x7f}},
+		{128, []byte{0x30, 0x80, 0x01}},
+		{16383, []byte{0x30, 0xff, 0x7f}},
+		{16384, []byte{0x30, 0x80, 0x80, 0x01}},
+		{2097152, []byte{0x30, 0x80, 0x80, 0x80, 0x01}},
+	}
+	for _, test := range tests {
+		body := bytes.Repeat([]byte{'x'}, test.length)
+		encoded, err := packet{header: packetPublish, body: body}.encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(encoded, test.header) || len(encoded) != len(test.header)+test.length {
+			t.Errorf("the header of %d bytes is %x, want %x", test.length, encoded[:len(test.header)], test.header)
+		}
+		decoded, err := readPacket(bufio.NewReader(bytes.NewReader(encoded)))
+		if err != nil {
+			t.Fatalf("readPacket of %d bytes: %v", test.length, err)
+		}
+		if decoded.header != packetPublish || !bytes.Equal(decoded.body, body) {
+			t.Errorf("readPacket of %d bytes returned %#x with %d bytes", test.length, decoded.header, len(decoded.body))
+		}
+	}
+}
+
+func TestReadPacketErrors(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"five length bytes": {0x30, 0xff, 0xff, 0xff, 0xff, 0x01},
+		"short body":        {0x30, 0x05, 'a', 'b'},
+		"no length":         {0x30},
+	} {
+		if _, err := readPacket(bufio.NewReader(bytes.NewReader(data))); err == nil {
+			t.Errorf("readPacket accepted %s", name)
+		}
+	}
+}
+
+// readString reads a length prefixed string from the start of b and returns the rest
+func readString(t *testing.T, b []byte) (string, []byte) {
+	t.Helper()
+	if len(b) < 2 || len(b) < 2+int(binary.BigEndian.Uint16(b)) {
+		t.Fatalf("no string in %x", b)
+	}
+	length := int(binary.BigEndian.Uint16(b))
+	return string(b[2 : 2+length]), b[2+length:]
+}
+
+func TestConnectPacket(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		options Options
+		flags   byte
+		fields  []string
+	}{
+		{"clean session", Options{ClientID: "events"}, 0x02, []string{"events"}},
+		{"will", Options{ClientID: "events", Will: &Message{Topic: "police/events/status", Payload: []byte("offline"), QoS: 1, Retain: true}},
+			0x02 | 0x04 | 0x08 | 0x20, []string{"events", "police/events/status", "offline"}},
+		{"will with QoS 2", Options{ClientID: "events", Will: &Message{Topic: "s", Payload: []byte("offline"), QoS: 2}},
+			0x02 | 0x04 | 0x10, []string{"events", "s", "offline"}},
+		{"credentials", Options{ClientID: "events", Username: "user", Password: "secret"}, 0x02 | 0x40 | 0x80,
+			[]string{"events", "user", "secret"}},
+	} {
+		test.options.KeepAlive = 90 * time.Second
+		p := connectPacket(test.options)
+		if p.header != packetConnect {
+			t.Errorf("%s: header %#x", test.name, p.header)
+		}
+		protocol, rest := readString(t, p.body)
+		if protocol != "MQTT" || len(rest) < 4 || rest[0] != 4 {
+			t.Fatalf("%s: protocol %q level %v", test.name, protocol, rest)
+		}
+		if rest[1] != test.flags {
+			t.Errorf("%s: flags %08b, want %08b", test.name, rest[1], test.flags)
+		}
+		if keepAlive := binary.BigEndian.Uint16(rest[2:]); keepAlive != 90 {
+			t.Errorf("%s: keep alive %d, want 90", test.name, keepAlive)
+		}
+		rest = rest[4:]
+		for _, want := range test.fields {
+			var field string
+			field, rest = readString(t, rest)
+			if field != want {
+				t.Errorf("%s: field %q, want %q", test.name, field, want)
+			}
+		}
+		if len(rest) != 0 {
+			t.Errorf("%s: %d bytes after the payload", test.name, len(rest))
+		}
+	}
+}
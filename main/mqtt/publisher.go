@@ -0,0 +1,93 @@
+package mqtt
+
+import (
+	"encoding/json"
+	. "project/main/event"
+	"strings"
+)
+
+// DefaultPrefix is the first levels of the topics of the events
+const DefaultPrefix = "police/events"
+
+// UnknownCounty is the topic level of the events whose location could not be resolved to a län
+const UnknownCounty = "okänt-län"
+
+// Publisher publishes every event as JSON to the topic <prefix>/<län>/<type>. The messages are retained,
+// so a new subscriber of a topic immediately gets the latest event of the topic. The topic <prefix>/status
+// is "online" while the publisher is connected and "offline", the last will, when it is not.
+type Publisher struct {
+	Prefix  string
+	QoS     byte
+	options Options
+	client  *Client
+}
+
+// NewPublisher connects to the broker and announces that the publisher is online
+func NewPublisher(options Options, prefix string, qos byte) (*Publisher, error) {
+	p := &Publisher{Prefix: strings.TrimSuffix(prefix, "/"), QoS: qos, options: options}
+	p.options.Will = &Message{Topic: p.StatusTopic(), Payload: []byte("offline"), QoS: qos, Retain: true}
+	return p, p.connect()
+}
+
+// StatusTopic is the topic of the online status of the publisher
+func (p *Publisher) StatusTopic() string {
+	return p.Prefix + "/status"
+}
+
+// Topic returns the topic of an event
+func (p *Publisher) Topic(event Event) string {
+	county := UnknownCounty
+	if region := event.Region(); region.County != nil {
+		county = topicLevel(region.County.Name)
+	}
+	return p.Prefix + "/" + county + "/" + topicLevel(event.Type)
+}
+
+// Publish publishes the events, the publisher reconnects first if the connection was lost
+func (p *Publisher) Publish(events []Event) error {
+	if p.client == nil || p.client.Err() != nil {
+		if p.client != nil {
+			p.client.Close()
+		}
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := p.client.Publish(Message{Topic: p.Topic(event), Payload: payload, QoS: p.QoS, Retain: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close announces that the publisher is offline and disconnects
+func (p *Publisher) Close() error {
+	if p.client == nil {
+		return nil
+	}
+	p.client.Publish(Message{Topic: p.StatusTopic(), Payload: []byte("offline"), QoS: p.QoS, Retain: true})
+	return p.client.Close()
+}
+
+// connect connects to the broker and publishes the online status
+func (p *Publisher) connect() error {
+	client, err := Connect(p.options)
+	if err != nil {
+		p.client = nil
+		return err
+	}
+	p.client = client
+	return client.Publish(Message{Topic: p.StatusTopic(), Payload: []byte("online"), QoS: p.QoS, Retain: true})
+}
+
+// topicLevel turns a name into a topic level, in lower case with the characters that have a meaning
+// in topics, the separators and the wildcards, and the spaces replaced by dashes
+func topicLevel(name string) string {
+	level := strings.ToLower(strings.NewReplacer(", ", "-", ",", "-", " ", "-", "/", "-", "+", "-", "#", "-").Replace(name))
+	return strings.Trim(level, "-")
+}
@@ -0,0 +1,123 @@
+package report
+
+import (
+	"math"
+	. "project/main/event"
+	"project/main/stats"
+	"sort"
+)
+
+// Side is one of the two compared selections of events
+type Side struct {
+	Label  string
+	Total  int
+	Events []Event
+}
+
+// Change is the number of events with a key, a type or a location, in both selections
+type Change struct {
+	Key    string
+	Before int
+	After  int
+}
+
+// Difference is the number of events after minus the number before
+func (c Change) Difference() int {
+	return c.After - c.Before
+}
+
+// Percent is the change in percent of the number before, ok is false if there were no events before
+func (c Change) Percent() (percent float64, ok bool) {
+	if c.Before == 0 {
+		return 0, false
+	}
+	return 100 * float64(c.Difference()) / float64(c.Before), true
+}
+
+// Comparison is the difference between the events of two periods or two filters. Before is the selection
+// that is compared against, usually the earlier period.
+type Comparison struct {
+	Before           Side
+	After            Side
+	Types            []Change
+	Locations        []Change
+	NewTypes         []string
+	DisappearedTypes []string
+	TypeMovers       []Change
+	LocationMovers   []Change
+}
+
+// Total is the change of the number of events
+func (c Comparison) Total() Change {
+	return Change{Key: "Total", Before: c.Before.Total, After: c.After.Total}
+}
+
+// Compare compares the events of the two selections per type and location, with the top types and
+// locations whose number of events changed the most
+func Compare(before Side, after Side, top int) Comparison {
+	before.Total, after.Total = len(before.Events), len(after.Events)
+	comparison := Comparison{
+		Before:    before,
+		After:     after,
+		Types:     changes(stats.CountByType(before.Events), stats.CountByType(after.Events)),
+		Locations: changes(stats.CountByLocation(before.Events), stats.CountByLocation(after.Events)),
+	}
+	for _, change := range comparison.Types {
+		if change.Before == 0 {
+			comparison.NewTypes = append(comparison.NewTypes, change.Key)
+		} else if change.After == 0 {
+			comparison.DisappearedTypes = append(comparison.DisappearedTypes, change.Key)
+		}
+	}
+	sort.Strings(comparison.NewTypes)
+	sort.Strings(comparison.DisappearedTypes)
+	comparison.TypeMovers = movers(comparison.Types, top)
+	comparison.LocationMovers = movers(comparison.Locations, top)
+	return comparison
+}
+
+// changes joins the counts of both selections, sorted with the most common key after first
+func changes(before []stats.Count, after []stats.Count) []Change {
+	byKey := make(map[string]*Change)
+	var joined []Change
+	for _, count := range before {
+		byKey[count.Key] = &Change{Key: count.Key, Before: count.Value}
+	}
+	for _, count := range after {
+		if change, ok := byKey[count.Key]; ok {
+			change.After = count.Value
+		} else {
+			byKey[count.Key] = &Change{Key: count.Key, After: count.Value}
+		}
+	}
+	for _, change := range byKey {
+		joined = append(joined, *change)
+	}
+	sort.Slice(joined, func(i, j int) bool {
+		if joined[i].After != joined[j].After {
+			return joined[i].After > joined[j].After
+		}
+		if joined[i].Before != joined[j].Before {
+			return joined[i].Before > joined[j].Before
+		}
+		return joined[i].Key < joined[j].Key
+	})
+	return joined
+}
+
+// movers returns at most n of the changes whose difference is largest, increases and decreases alike
+func movers(changes []Change, n int) []Change {
+	var moved []Change
+	for _, change := range changes {
+		if change.Difference() != 0 {
+			moved = append(moved, change)
+		}
+	}
+	sort.SliceStable(moved, func(i, j int) bool {
+		return math.Abs(float64(moved[i].Difference())) > math.Abs(float64(moved[j].Difference()))
+	})
+	if len(moved) > n {
+		return moved[:n]
+	}
+	return moved
+}
@@ -0,0 +1,83 @@
+// This package generates reports that compare the events of two periods or two filters, with the
+// changes per type and location, as terminal text, Markdown or HTML
+package report
+
+import (
+	"fmt"
+	. "project/main/event"
+	"project/main/stats"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout of the dates of a period
+const dateLayout = "2006-01-02"
+
+// Period is a range of time from From (inclusive) to To (exclusive)
+type Period struct {
+	Label string
+	From  time.Time
+	To    time.Time
+}
+
+// Contains reports whether the event happened in the period
+func (p Period) Contains(event Event) bool {
+	datetime := event.Time()
+	return !datetime.Before(p.From) && datetime.Before(p.To)
+}
+
+// Apply returns the events of the period
+func (p Period) Apply(events []Event) []Event {
+	var inPeriod []Event
+	for _, event := range events {
+		if p.Contains(event) {
+			inPeriod = append(inPeriod, event)
+		}
+	}
+	return inPeriod
+}
+
+// ParsePeriod parses "this-week", "last-week", "this-month", "last-month", a month such as "2023-10",
+// a number of days before now such as "7d", or dates such as "2023-04-10..2023-04-16" where both dates
+// are included. Weeks start on Monday.
+func ParsePeriod(text string, now time.Time) (Period, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monday := today.AddDate(0, 0, -stats.Weekday(today))
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	switch text {
+	case "this-week":
+		return Period{Label: "This week", From: monday, To: monday.AddDate(0, 0, 7)}, nil
+	case "last-week":
+		return Period{Label: "Last week", From: monday.AddDate(0, 0, -7), To: monday}, nil
+	case "this-month":
+		return Period{Label: month.Format("January 2006"), From: month, To: month.AddDate(0, 1, 0)}, nil
+	case "last-month":
+		last := month.AddDate(0, -1, 0)
+		return Period{Label: last.Format("January 2006"), From: last, To: month}, nil
+	}
+	if days, ok := strings.CutSuffix(text, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return Period{Label: "Last " + text, From: now.AddDate(0, 0, -n), To: now}, nil
+		}
+	}
+	if first, err := time.ParseInLocation("2006-01", text, now.Location()); err == nil {
+		return Period{Label: first.Format("January 2006"), From: first, To: first.AddDate(0, 1, 0)}, nil
+	}
+	if from, to, ok := strings.Cut(text, ".."); ok {
+		start, errFrom := time.ParseInLocation(dateLayout, from, now.Location())
+		end, errTo := time.ParseInLocation(dateLayout, to, now.Location())
+		if errFrom == nil && errTo == nil && !end.Before(start) {
+			return Period{Label: from + " – " + to, From: start, To: end.AddDate(0, 0, 1)}, nil
+		}
+	}
+	return Period{}, fmt.Errorf("invalid period %q, expected for example last-week, 2023-10, 7d or 2023-04-10..2023-04-16", text)
+}
+
+// Previous returns the period of the same length that ends where the period starts
+func (p Period) Previous() Period {
+	length := p.To.Sub(p.From)
+	from := p.From.Add(-length)
+	return Period{Label: from.Format(dateLayout) + " – " + p.From.Add(-time.Nanosecond).Format(dateLayout), From: from, To: p.From}
+}
@@ -0,0 +1,194 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// FormatNames are the formats a comparison can be rendered in
+var FormatNames = []string{"text", "markdown", "html"}
+
+// Render renders the comparison in one of FormatNames
+func (c Comparison) Render(format string) ([]byte, error) {
+	switch format {
+	case "text":
+		return []byte(c.Text()), nil
+	case "markdown":
+		return []byte(c.Markdown()), nil
+	case "html":
+		return c.HTML()
+	}
+	return nil, fmt.Errorf("unknown format %q, expected text, markdown or html", format)
+}
+
+// FormatDifference formats the difference with its sign, for example +3 or -2
+func (c Change) FormatDifference() string {
+	return fmt.Sprintf("%+d", c.Difference())
+}
+
+// FormatPercent formats the change in percent, for example +50%, or "new" if there were no events before
+func (c Change) FormatPercent() string {
+	percent, ok := c.Percent()
+	if !ok {
+		if c.After == 0 {
+			return "–"
+		}
+		return "new"
+	}
+	return fmt.Sprintf("%+.0f%%", percent)
+}
+
+// Text renders the comparison as aligned columns for the terminal
+func (c Comparison) Text() string {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "%s compared with %s\n", c.After.Label, c.Before.Label)
+	writeTable := func(title string, changes []Change) {
+		fmt.Fprintf(&buffer, "\n%s\n", title)
+		// The numbers are right aligned, the keys are padded to be left aligned
+		width := 0
+		for _, change := range changes {
+			width = maxInt(width, utf8.RuneCountInString(change.Key))
+		}
+		table := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(table, "%*s\t%s\t%s\tChange\t%%\t\n", width, "", c.Before.Label, c.After.Label)
+		for _, change := range changes {
+			fmt.Fprintf(table, "%-*s\t%d\t%d\t%s\t%s\t\n", width, change.Key, change.Before, change.After,
+				change.FormatDifference(), change.FormatPercent())
+		}
+		table.Flush()
+	}
+	writeTable("Total", []Change{c.Total()})
+	writeTable("Top movers by type", c.TypeMovers)
+	writeTable("Top movers by location", c.LocationMovers)
+	fmt.Fprintf(&buffer, "\nNew types: %s\n", joinOrNone(c.NewTypes))
+	fmt.Fprintf(&buffer, "Disappeared types: %s\n", joinOrNone(c.DisappearedTypes))
+	writeTable("Per type", c.Types)
+	writeTable("Per location", c.Locations)
+	return buffer.String()
+}
+
+// Markdown renders the comparison as a Markdown document with a table per section
+func (c Comparison) Markdown() string {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "# %s compared with %s\n", escapeMarkdown(c.After.Label), escapeMarkdown(c.Before.Label))
+	writeTable := func(title string, changes []Change) {
+		fmt.Fprintf(&buffer, "\n## %s\n\n", title)
+		if len(changes) == 0 {
+			buffer.WriteString("No changes.\n")
+			return
+		}
+		fmt.Fprintf(&buffer, "| | %s | %s | Change | %% |\n|---|--:|--:|--:|--:|\n",
+			escapeMarkdown(c.Before.Label), escapeMarkdown(c.After.Label))
+		for _, change := range changes {
+			fmt.Fprintf(&buffer, "| %s | %d | %d | %s | %s |\n", escapeMarkdown(change.Key), change.Before, change.After,
+				change.FormatDifference(), change.FormatPercent())
+		}
+	}
+	writeTable("Total", []Change{c.Total()})
+	writeTable("Top movers by type", c.TypeMovers)
+	writeTable("Top movers by location", c.LocationMovers)
+	writeList := func(title string, keys []string) {
+		fmt.Fprintf(&buffer, "\n## %s\n\n", title)
+		if len(keys) == 0 {
+			buffer.WriteString("None.\n")
+		}
+		for _, key := range keys {
+			fmt.Fprintf(&buffer, "- %s\n", escapeMarkdown(key))
+		}
+	}
+	writeList("New types", c.NewTypes)
+	writeList("Disappeared types", c.DisappearedTypes)
+	writeTable("Per type", c.Types)
+	writeTable("Per location", c.Locations)
+	return buffer.String()
+}
+
+// HTML renders the comparison as a standalone HTML page
+func (c Comparison) HTML() ([]byte, error) {
+	var buffer bytes.Buffer
+	err := htmlTemplate.Execute(&buffer, c)
+	return buffer.Bytes(), err
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.After.Label}} compared with {{.Before.Label}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { padding: 0.2em 0.8em; border-bottom: 1px solid #ddd; }
+td.number { text-align: right; }
+.increase { color: #b00020; }
+.decrease { color: #1b7f3b; }
+</style>
+</head>
+<body>
+<h1>{{.After.Label}} compared with {{.Before.Label}}</h1>
+{{define "table"}}<table>
+<tr><th></th><th>{{.Before}}</th><th>{{.After}}</th><th>Change</th><th>%</th></tr>
+{{range .Changes}}<tr><td>{{.Key}}</td><td class="number">{{.Before}}</td><td class="number">{{.After}}</td><td class="number {{if gt .Difference 0}}increase{{else if lt .Difference 0}}decrease{{end}}">{{.FormatDifference}}</td><td class="number">{{.FormatPercent}}</td></tr>
+{{else}}<tr><td colspan="5">No changes</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Total</h2>
+{{template "table" (.Table .Total)}}
+<h2>Top movers by type</h2>
+{{template "table" (.Table .TypeMovers)}}
+<h2>Top movers by location</h2>
+{{template "table" (.Table .LocationMovers)}}
+<h2>New types</h2>
+{{if .NewTypes}}<ul>{{range .NewTypes}}<li>{{.}}</li>{{end}}</ul>{{else}}<p>None</p>{{end}}
+<h2>Disappeared types</h2>
+{{if .DisappearedTypes}}<ul>{{range .DisappearedTypes}}<li>{{.}}</li>{{end}}</ul>{{else}}<p>None</p>{{end}}
+<h2>Per type</h2>
+{{template "table" (.Table .Types)}}
+<h2>Per location</h2>
+{{template "table" (.Table .Locations)}}
+</body>
+</html>
+`))
+
+// table is the data of a table of the HTML template
+type table struct {
+	Before  string
+	After   string
+	Changes []Change
+}
+
+// Table returns the data of an HTML table of the changes, which may be a single change
+func (c Comparison) Table(changes any) table {
+	t := table{Before: c.Before.Label, After: c.After.Label}
+	switch changes := changes.(type) {
+	case Change:
+		t.Changes = []Change{changes}
+	case []Change:
+		t.Changes = changes
+	}
+	return t
+}
+
+func joinOrNone(keys []string) string {
+	if len(keys) == 0 {
+		return "none"
+	}
+	return strings.Join(keys, "; ")
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "*", `\*`, "_", `\_`, "#", `\#`)
+
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
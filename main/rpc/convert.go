@@ -0,0 +1,90 @@
+package rpc
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+	. "project/main/event"
+	"project/main/rpc/eventspb"
+	"project/main/stats"
+)
+
+// eventToProto converts an event to its message, with the derived fields the other APIs also have
+func eventToProto(e Event) *eventspb.Event {
+	category := e.Category()
+	facts := e.ExtractedFacts()
+	message := &eventspb.Event{
+		Id:        int64(e.Id),
+		Datetime:  e.Datetime,
+		Time:      timestamppb.New(e.Time()),
+		Name:      e.Name,
+		Summary:   e.Summary,
+		Url:       PageURL(e.Url),
+		Type:      e.Type,
+		TypeLabel: e.TypeLabel(),
+		Category:  &eventspb.Category{Id: category.ID, Label: category.Label, Color: category.Color},
+		Severity:  int32(e.Severity()),
+		Location:  &eventspb.Location{Name: e.Location.Name, Gps: e.Location.Gps},
+		Facts: &eventspb.Facts{
+			Injured:  int32(facts.Injured),
+			Arrested: int32(facts.Arrested),
+			Vehicles: facts.Vehicles,
+			Weapons:  facts.Weapons,
+			Streets:  facts.Streets,
+		},
+	}
+	for _, age := range facts.Ages {
+		message.Facts.Ages = append(message.Facts.Ages, int32(age))
+	}
+	if lat, lon, ok := e.Coordinates(); ok {
+		message.Location.Latitude, message.Location.Longitude, message.Location.HasCoordinates = lat, lon, true
+	}
+	region := e.Region()
+	if region.Municipality != nil {
+		message.Location.Municipality = region.Municipality.Name
+	}
+	if region.County != nil {
+		message.Location.County = region.County.Name
+	}
+	return message
+}
+
+func countsToProto(counts []stats.Count) []*eventspb.Count {
+	messages := make([]*eventspb.Count, len(counts))
+	for i, count := range counts {
+		messages[i] = &eventspb.Count{Key: count.Key, Value: int32(count.Value)}
+	}
+	return messages
+}
+
+// filterFromProto converts a filter message, a missing message matches every event
+func filterFromProto(message *eventspb.Filter) (Filter, error) {
+	if message == nil {
+		return Filter{}, nil
+	}
+	filter := Filter{
+		Type:        message.Type,
+		Location:    message.Location,
+		Category:    message.Category,
+		MinSeverity: int(message.MinSeverity),
+		Since:       message.Since,
+		Facts:       message.Facts,
+	}
+	if message.Near != "" {
+		radius := message.Radius
+		if radius == "" {
+			radius = "5km"
+		}
+		circle, err := ParseCircle(message.Near, radius)
+		if err != nil {
+			return filter, err
+		}
+		filter.Near = circle
+	}
+	if message.Box != "" {
+		box, err := ParseBoundingBox(message.Box)
+		if err != nil {
+			return filter, err
+		}
+		filter.Box = box
+	}
+	return filter, filter.Validate()
+}
@@ -0,0 +1,13 @@
+// This package is the generated client and the messages of the gRPC API of the archived events, see
+// events.proto. Other programs import it to query the server started with "serve -grpc", for example
+//
+//	conn, err := grpc.Dial("localhost:9090", grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	client := eventspb.NewEventsClient(conn)
+//	page, err := client.ListEvents(ctx, &eventspb.ListEventsRequest{Filter: &eventspb.Filter{Location: "Uppsala"}})
+//
+// Regenerate the code after changing events.proto with
+//
+//	protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative events.proto
+package eventspb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative events.proto
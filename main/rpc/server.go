@@ -0,0 +1,181 @@
+// This package serves the archived events over gRPC, for the backend services that only speak gRPC.
+// The messages and the service are defined in eventspb/events.proto, the generated package eventspb
+// is also the client that other programs import, it does not depend on the event package.
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+	"net"
+	"project/main/duplicate"
+	. "project/main/event"
+	"project/main/rpc/eventspb"
+	"project/main/stats"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPageSize is the largest page of ListEvents
+const maxPageSize = 100
+
+// Server implements the Events service over the events of a store
+type Server struct {
+	eventspb.UnimplementedEventsServer
+
+	// PollInterval is how often WatchEvents checks the store for new events
+	PollInterval time.Duration
+
+	store *Store
+}
+
+// NewServer creates a server of the events in the store
+func NewServer(store *Store) *Server {
+	return &Server{PollInterval: 5 * time.Second, store: store}
+}
+
+// Run serves the archive over gRPC on the address until the server fails
+func Run(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer()
+	eventspb.RegisterEventsServer(server, NewServer(archiveStore()))
+	log.Println("Serving events over gRPC on", addr)
+	return server.Serve(listener)
+}
+
+// archiveStore returns the store of the archive without the merged duplicates, like the other APIs
+func archiveStore() *Store {
+	store := NewStore()
+	store.Exclude = duplicate.MergedRemover(duplicate.DecisionsPath)
+	return store
+}
+
+// ListEvents returns a page of the events matching the filter, the newest events first
+func (s *Server) ListEvents(_ context.Context, req *eventspb.ListEventsRequest) (*eventspb.ListEventsResponse, error) {
+	filter, err := filterFromProto(req.Filter)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	size := int(req.PageSize)
+	if size == 0 {
+		size = 20
+	}
+	if size < 0 || size > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page_size must be between 0 and %d", maxPageSize)
+	}
+	events := s.store.Query(filter)
+	// The events are sorted by datetime, the page starts after the event of the token counted from the end
+	start := len(events) - 1
+	if req.PageToken != "" {
+		id, err := decodePageToken(req.PageToken)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		start = -2
+		for i := len(events) - 1; i >= 0; i-- {
+			if events[i].Id == id {
+				start = i - 1
+				break
+			}
+		}
+		if start == -2 {
+			return nil, status.Errorf(codes.InvalidArgument, "the page_token %q is no longer in the result", req.PageToken)
+		}
+	}
+	response := &eventspb.ListEventsResponse{TotalSize: int32(len(events))}
+	for i := start; i >= 0 && len(response.Events) < size; i-- {
+		response.Events = append(response.Events, eventToProto(events[i]))
+		if i > 0 && len(response.Events) == size {
+			response.NextPageToken = encodePageToken(events[i].Id)
+		}
+	}
+	return response, nil
+}
+
+// GetEvent returns the event with the id
+func (s *Server) GetEvent(_ context.Context, req *eventspb.GetEventRequest) (*eventspb.Event, error) {
+	for _, e := range s.store.Events() {
+		if int64(e.Id) == req.Id {
+			return eventToProto(e), nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "no event with the id %d", req.Id)
+}
+
+// GetStats counts the events matching the filter
+func (s *Server) GetStats(_ context.Context, req *eventspb.GetStatsRequest) (*eventspb.Stats, error) {
+	filter, err := filterFromProto(req.Filter)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	top := int(req.Top)
+	if top == 0 {
+		top = 10
+	}
+	if top < 0 {
+		return nil, status.Error(codes.InvalidArgument, "top can not be negative")
+	}
+	events := s.store.Query(filter)
+	return &eventspb.Stats{
+		Total:      int32(len(events)),
+		ByCategory: countsToProto(stats.CountByCategory(events)),
+		ByType:     countsToProto(stats.Top(stats.CountByType(events), top)),
+		ByLocation: countsToProto(stats.Top(stats.CountByLocation(events), top)),
+		ByCounty:   countsToProto(stats.CountByCounty(events)),
+		PerDay:     countsToProto(stats.PerDay(events)),
+	}, nil
+}
+
+// WatchEvents streams the events matching the filter that are added to the store, until the client cancels
+func (s *Server) WatchEvents(req *eventspb.WatchEventsRequest, stream eventspb.Events_WatchEventsServer) error {
+	filter, err := filterFromProto(req.Filter)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	seen := make(map[int]bool)
+	for _, e := range s.store.Events() {
+		seen[e.Id] = true
+	}
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+		for _, e := range s.store.Query(filter) {
+			if seen[e.Id] {
+				continue
+			}
+			seen[e.Id] = true
+			if err := stream.Send(eventToProto(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func encodePageToken(id int) string {
+	return base64.StdEncoding.EncodeToString([]byte("Event:" + strconv.Itoa(id)))
+}
+
+func decodePageToken(token string) (int, error) {
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err == nil {
+		if text, ok := strings.CutPrefix(string(data), "Event:"); ok {
+			if id, err := strconv.Atoi(text); err == nil {
+				return id, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid page_token %q", token)
+}
@@ -0,0 +1,91 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"os"
+	"path/filepath"
+	"project/main/duplicate"
+	. "project/main/event"
+	"project/main/rpc/eventspb"
+	"testing"
+)
+
+// inArchive runs the test in an empty directory with the events in the archive and the duplicate decisions
+func inArchive(t *testing.T, events []Event, decisions string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.MkdirAll(filepath.Dir(ArchivePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ArchivePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(duplicate.DecisionsPath, []byte(decisions), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetStatsWithoutMergedDuplicates(t *testing.T) {
+	var events []Event
+	for i, typ := range []string{"Brand", "Brand", "Rån"} {
+		event := Event{Id: i + 1, Type: typ, Datetime: "2023-04-25 10:00:00 +02:00"}
+		event.Location.Name = "Uppsala"
+		events = append(events, event)
+	}
+	inArchive(t, events, `{"1-2": "merged"}`)
+	server := NewServer(archiveStore())
+
+	stats, err := server.GetStats(context.Background(), &eventspb.GetStatsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 2 {
+		t.Errorf("Total = %d, want 2 without the merged fire", stats.Total)
+	}
+	for _, count := range stats.ByType {
+		if count.Key == "Brand" && count.Value != 1 {
+			t.Errorf("%d fires, want 1", count.Value)
+		}
+	}
+	list, err := server.ListEvents(context.Background(), &eventspb.ListEventsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.TotalSize != 2 {
+		t.Errorf("ListEvents has %d events, want 2", list.TotalSize)
+	}
+}
+
+func TestEventWithInvalidDatetime(t *testing.T) {
+	events := []Event{
+		{Id: 1, Type: "Brand", Datetime: "2023-04-25 10:00:00 +02:00"},
+		{Id: 2, Type: "Rån", Datetime: "25 april 2023"},
+	}
+	inArchive(t, events, `{}`)
+	server := NewServer(archiveStore())
+
+	list, err := server.ListEvents(context.Background(), &eventspb.ListEventsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.TotalSize != 1 || list.Events[0].Id != 1 {
+		t.Errorf("ListEvents has %d events, want only the event with a valid datetime", list.TotalSize)
+	}
+	if _, err := server.GetEvent(context.Background(), &eventspb.GetEventRequest{Id: 2}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetEvent of the invalid event = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	. "project/main/event"
+	"sort"
+)
+
+// search prints the names and Id:s of the archived events matching the filter flags, sorted by datetime
+func search(args []string) {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	selection := addFilterFlags(flags)
+	flags.Parse(args)
+
+	eventsInArchive := reviewedArchive()
+	sort.Sort(ByDatetime(eventsInArchive))
+	for _, event := range selection.Filter().Apply(eventsInArchive) {
+		fmt.Println(event.Id, "----", event.Name)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"project/main/rpc"
+	"project/main/server"
+)
+
+// serve runs the server mode until the server fails
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	grpcAddr := flags.String("grpc", "", "also serve the gRPC API on this address, for example :9090")
+	flags.Parse(args)
+
+	if *grpcAddr != "" {
+		go func() {
+			if err := rpc.Run(*grpcAddr); err != nil {
+				fmt.Println("An error occurred while running the gRPC server")
+				log.Fatal(err)
+			}
+		}()
+	}
+	if err := server.Run(*addr); err != nil {
+		fmt.Println("An error occurred while running the server")
+		log.Fatal(err)
+	}
+}
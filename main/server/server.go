@@ -0,0 +1,239 @@
+// This package runs the server mode, an HTTP server that publishes the archived events,
+// for example as feeds of saved filters. The archive is kept up to date by the watch command.
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"project/main/duplicate"
+	. "project/main/event"
+	"project/main/export"
+	"project/main/feed"
+	"project/main/graphql"
+	"project/main/stats"
+	"strconv"
+	"strings"
+)
+
+// Server serves the events of a store over HTTP
+type Server struct {
+	store *Store
+	mux   *http.ServeMux
+}
+
+// New creates a server with all endpoints registered
+func New(store *Store) *Server {
+	s := &Server{store: store, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/feed.atom", s.handleFeed)
+	s.mux.HandleFunc("/feed.rss", s.handleFeed)
+	s.mux.HandleFunc("/feeds/", s.handleSavedFeed)
+	for name := range export.Formats {
+		s.mux.HandleFunc("/export."+name, s.handleExport)
+	}
+	s.mux.HandleFunc("/calendars/", s.handleSavedCalendar)
+	s.mux.HandleFunc("/hotspots.geojson", s.handleHotspots)
+	s.mux.Handle("/graphql", graphql.NewHandler(store))
+	s.mux.HandleFunc("/graphql/schema.graphql", graphql.ServeSchema)
+	return s
+}
+
+// Run serves the archive on the address until the server fails
+func Run(addr string) error {
+	store := NewStore()
+	store.Exclude = duplicate.MergedRemover(duplicate.DecisionsPath)
+	log.Println("Serving events on", addr)
+	return http.ListenAndServe(addr, New(store))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleFeed serves /feed.atom and /feed.rss with the filter given by the query parameters
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	filter, err := FilterFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.writeFeed(w, r, filter.String(), filter, strings.TrimPrefix(r.URL.Path, "/feed."))
+}
+
+// handleSavedFeed serves /feeds/<name>.atom and /feeds/<name>.rss for the saved filter with that name
+func (s *Server) handleSavedFeed(w http.ResponseWriter, r *http.Request) {
+	name, format, filter, ok := savedFilter(w, r, "/feeds/")
+	if ok {
+		s.writeFeed(w, r, name, filter, format)
+	}
+}
+
+// handleSavedCalendar serves /calendars/<name>.ics, a calendar of the saved filter with that name that
+// calendar applications can subscribe to. It always contains the events of the current archive.
+func (s *Server) handleSavedCalendar(w http.ResponseWriter, r *http.Request) {
+	_, format, filter, ok := savedFilter(w, r, "/calendars/")
+	if !ok {
+		return
+	}
+	if format != "ics" {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := export.ICalendar(s.store.Query(filter))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", export.Formats["ics"].ContentType)
+	w.Write(data)
+}
+
+// savedFilter looks up the saved filter named by a path such as <prefix><name>.<format>.
+// If ok is false an error response has been written.
+func savedFilter(w http.ResponseWriter, r *http.Request, prefix string) (name string, format string, filter Filter, ok bool) {
+	file := strings.TrimPrefix(r.URL.Path, prefix)
+	dot := strings.LastIndex(file, ".")
+	if dot < 0 {
+		http.NotFound(w, r)
+		return "", "", filter, false
+	}
+	name, format = file[:dot], file[dot+1:]
+	filters, err := LoadSavedFilters()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return "", "", filter, false
+	}
+	filter, ok = filters[name]
+	if !ok {
+		http.NotFound(w, r)
+	}
+	return name, format, filter, ok
+}
+
+// writeFeed writes the feed of the filtered events in the format "atom" or "rss"
+func (s *Server) writeFeed(w http.ResponseWriter, r *http.Request, title string, filter Filter, format string) {
+	events := s.store.Query(filter)
+	var data []byte
+	var err error
+	switch format {
+	case "atom":
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		data, err = feed.Atom(title, requestURL(r), events)
+	case "rss":
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		data, err = feed.RSS(title, events)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(data)
+}
+
+// handleExport serves /export.<format>, for example /export.geojson, with the filter given by the query parameters
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	format, ok := export.Formats[strings.TrimPrefix(r.URL.Path, "/export.")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	filter, err := FilterFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := format.Write(s.store.Query(filter))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Write(data)
+}
+
+// handleHotspots serves /hotspots.geojson, the hotspots of the events matching the filter given by the query
+// parameters. "cluster_radius" (for example 2km) and "min_events" override the clustering defaults.
+func (s *Server) handleHotspots(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := FilterFromQuery(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	options := stats.DefaultHotspotOptions
+	if radius := query.Get("cluster_radius"); radius != "" {
+		if options.RadiusKm, err = ParseDistance(radius); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if minEvents := query.Get("min_events"); minEvents != "" {
+		if options.MinEvents, err = strconv.Atoi(minEvents); err != nil {
+			http.Error(w, "invalid min_events "+strconv.Quote(minEvents), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := options.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := export.HotspotsGeoJSON(stats.Hotspots(s.store.Query(filter), options))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", export.Formats["geojson"].ContentType)
+	w.Write(data)
+}
+
+// FilterFromQuery reads a filter from the query parameters "type", "location", "category", "min_severity",
+// "near" and "radius" (for example near=59.33,18.06&radius=5km), "box" (minLat,minLon,maxLat,maxLon)
+// "since" (for example 24h) and "facts" (for example arrests > 0)
+func FilterFromQuery(query url.Values) (Filter, error) {
+	filter := Filter{
+		Type:     query.Get("type"),
+		Location: query.Get("location"),
+		Category: query.Get("category"),
+		Since:    query.Get("since"),
+		Facts:    query.Get("facts"),
+	}
+	if severity := query.Get("min_severity"); severity != "" {
+		minSeverity, err := strconv.Atoi(severity)
+		if err != nil {
+			return filter, fmt.Errorf("invalid min_severity %q", severity)
+		}
+		filter.MinSeverity = minSeverity
+	}
+	if near := query.Get("near"); near != "" {
+		radius := query.Get("radius")
+		if radius == "" {
+			radius = "5km"
+		}
+		circle, err := ParseCircle(near, radius)
+		if err != nil {
+			return filter, err
+		}
+		filter.Near = circle
+	}
+	if box := query.Get("box"); box != "" {
+		boundingBox, err := ParseBoundingBox(box)
+		if err != nil {
+			return filter, err
+		}
+		filter.Box = boundingBox
+	}
+	return filter, filter.Validate()
+}
+
+// requestURL returns the absolute URL of the request as seen by the client
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
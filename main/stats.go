@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"project/main/digest"
+	"project/main/duplicate"
+	. "project/main/event"
+	. "project/main/stats"
+	"time"
+)
+
+// printStats prints the number of archived events matching the filter flags per category, type and location.
+// Merged duplicates are not counted. The -digests flag decides whether the digest events are counted
+// as they are, left out or split into their items. Finally the (location, type) pairs whose number of
+// events in the window before -at is anomalous compared with their history are printed.
+func printStats(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	selection := addFilterFlags(flags)
+	digestMode := flags.String("digests", string(digest.Include), "include, exclude, expand or items")
+	fetch := flags.Bool("fetch", false, "scrape the extended summaries of digests that are not cached")
+	top := flags.Int("top", 10, "number of types and locations to print")
+	window := flags.Duration("window", DefaultAnomalyOptions.Window, "window that is checked for anomalies")
+	at := flags.String("at", "", "end of the anomaly window as 2006-01-02 15:04, the newest event if empty")
+	flags.Parse(args)
+
+	mode, err := digest.ParseMode(*digestMode)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	decisions, err := duplicate.LoadDecisions(duplicate.DecisionsPath)
+	if err != nil {
+		fmt.Println("An error occurred while reading the reviewed duplicates")
+		log.Fatal(err)
+	}
+	events := selectDigests(decisions.RemoveMerged(GetArchive()), mode, *fetch)
+	events = selection.Filter().Apply(events)
+
+	fmt.Println("Events:", len(events))
+	printCounts("Per category", CountByCategory(events))
+	printCounts("Top types", Top(CountByType(events), *top))
+	printCounts("Top locations", Top(CountByLocation(events), *top))
+
+	end := Newest(events)
+	if *at != "" {
+		if end, err = time.ParseInLocation("2006-01-02 15:04", *at, time.Local); err != nil {
+			fmt.Println("Invalid -at", *at+", expected for example 2023-04-25 18:00")
+			os.Exit(2)
+		}
+	}
+	options := DefaultAnomalyOptions
+	options.Window = *window
+	fmt.Println()
+	fmt.Println("Anomalies in the", *window, "before", end.Format("2006-01-02 15:04"))
+	anomalies := DetectAnomalies(events, end, options)
+	for _, anomaly := range anomalies {
+		fmt.Println("  ", anomaly)
+	}
+	if len(anomalies) == 0 {
+		fmt.Println("   none")
+	}
+}
+
+// selectDigests applies the digest mode with the summaries in SummaryCachePath
+func selectDigests(events []Event, mode digest.Mode, fetch bool) []Event {
+	cache, err := OpenSummaryCache(SummaryCachePath)
+	if err != nil {
+		fmt.Println("An error occurred while reading the cached summaries")
+		log.Fatal(err)
+	}
+	selected, err := digest.Apply(events, mode, cache, fetch)
+	if err != nil {
+		log.Println("Not every digest could be scraped:", err)
+	}
+	return selected
+}
+
+func printCounts(title string, counts []Count) {
+	fmt.Println()
+	fmt.Println(title)
+	for _, count := range counts {
+		fmt.Printf("  %-40s %5d\n", count.Key, count.Value)
+	}
+}
@@ -0,0 +1,191 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	. "project/main/event"
+	"sort"
+	"time"
+	_ "time/tzdata"
+)
+
+// AnomalyOptions controls when the number of events in a window is anomalous
+type AnomalyOptions struct {
+	// Window is the length of the period that is compared with the baseline
+	Window time.Duration
+	// MinCount is the fewest events of an anomalous window, so single events in quiet areas are not reported
+	MinCount int
+	// MaxProbability is the highest probability, under the baseline, of seeing at least as many events
+	MaxProbability float64
+}
+
+// DefaultAnomalyOptions reports windows of 24 hours with at least three events that are less likely than 1 in 100
+var DefaultAnomalyOptions = AnomalyOptions{
+	Window:         24 * time.Hour,
+	MinCount:       3,
+	MaxProbability: 0.01,
+}
+
+// Weight of the average rate in the rate of an hour of the week, in number of observed weeks.
+// It keeps the baseline of sparse series from being zero in the hours without events.
+const profilePrior = 4
+
+// stockholm is the time zone of the hours of the week of the baseline. The events have the offset of when they
+// were reported and the end of the window may be in any zone, so they are all converted to Swedish time.
+var stockholm = mustLoadLocation("Europe/Stockholm")
+
+func mustLoadLocation(name string) *time.Location {
+	// The zone database is embedded by time/tzdata, so this only fails for a misspelled name
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+// Anomaly is a window where a type of event occurred in a location far more often than its baseline predicts
+type Anomaly struct {
+	Location    string
+	Type        string
+	Start       time.Time
+	End         time.Time
+	Observed    int
+	Expected    float64
+	Probability float64
+	Events      []Event
+}
+
+// ZScore returns how many standard deviations the observed count is above the expected count
+func (a Anomaly) ZScore() float64 {
+	return (float64(a.Observed) - a.Expected) / math.Sqrt(math.Max(a.Expected, 1e-9))
+}
+
+// String returns the anomaly as for example "Inbrott in Uppsala: 7 events in 24h, 1.2 expected (p = 0.0003)"
+func (a Anomaly) String() string {
+	return fmt.Sprintf("%s in %s: %d events in %s, %.1f expected (p = %.2g)",
+		a.Type, a.Location, a.Observed, formatWindow(a.End.Sub(a.Start)), a.Expected, a.Probability)
+}
+
+// DetectAnomalies compares the events of every (location, type) in the window that ends at end with
+// a baseline built from the events before the window. The baseline is a Poisson rate for every hour
+// of the week, so a type that is common at night or in weekends is compared with its own nights and weekends.
+// Locations are municipalities where the event can be resolved to one. The most unlikely windows come first.
+func DetectAnomalies(events []Event, end time.Time, options AnomalyOptions) []Anomaly {
+	start := end.Add(-options.Window)
+	type series struct {
+		history [7][24]int
+		total   int
+		window  []Event
+	}
+	all := make(map[[2]string]*series)
+	var first time.Time
+	for _, event := range events {
+		datetime := event.Time()
+		if datetime.After(end) {
+			continue
+		}
+		if first.IsZero() || datetime.Before(first) {
+			first = datetime
+		}
+		key := [2]string{anomalyLocation(event), event.Type}
+		s, ok := all[key]
+		if !ok {
+			s = &series{}
+			all[key] = s
+		}
+		if datetime.After(start) {
+			s.window = append(s.window, event)
+		} else {
+			local := datetime.In(stockholm)
+			s.history[Weekday(local)][local.Hour()]++
+			s.total++
+		}
+	}
+	historyHours := start.Sub(first).Hours()
+	if historyHours < options.Window.Hours() {
+		return nil
+	}
+
+	// Number of times every hour of the week occurs in the history
+	var slotHours [7][24]float64
+	for t := first.Truncate(time.Hour); t.Before(start); t = t.Add(time.Hour) {
+		local := t.In(stockholm)
+		slotHours[Weekday(local)][local.Hour()]++
+	}
+
+	var anomalies []Anomaly
+	for key, s := range all {
+		if len(s.window) < options.MinCount {
+			continue
+		}
+		// A series without history is treated as if it had one event, a new series is not anomalous by itself
+		averageRate := math.Max(float64(s.total), 1) / historyHours
+		expected := 0.0
+		for t := start.Truncate(time.Hour); t.Before(end); t = t.Add(time.Hour) {
+			local := t.In(stockholm)
+			day, hour := Weekday(local), local.Hour()
+			expected += (float64(s.history[day][hour]) + profilePrior*averageRate) / (slotHours[day][hour] + profilePrior)
+		}
+		probability := poissonTail(expected, len(s.window))
+		if probability > options.MaxProbability {
+			continue
+		}
+		anomalies = append(anomalies, Anomaly{
+			Location:    key[0],
+			Type:        key[1],
+			Start:       start,
+			End:         end,
+			Observed:    len(s.window),
+			Expected:    expected,
+			Probability: probability,
+			Events:      s.window,
+		})
+	}
+	sort.Slice(anomalies, func(i, j int) bool {
+		if anomalies[i].Probability != anomalies[j].Probability {
+			return anomalies[i].Probability < anomalies[j].Probability
+		}
+		return anomalies[i].Location+anomalies[i].Type < anomalies[j].Location+anomalies[j].Type
+	})
+	return anomalies
+}
+
+// Newest returns the time of the newest event, it is the end of the window when an archive is analysed
+func Newest(events []Event) time.Time {
+	var newest time.Time
+	for _, event := range events {
+		if datetime := event.Time(); datetime.After(newest) {
+			newest = datetime
+		}
+	}
+	return newest
+}
+
+// anomalyLocation returns the municipality of the event, or its location if it is not in a municipality
+func anomalyLocation(event Event) string {
+	if region := event.Region(); region.Municipality != nil {
+		return region.Municipality.Name
+	}
+	return event.Location.Name
+}
+
+// poissonTail returns the probability of at least k events when rate events are expected
+func poissonTail(rate float64, k int) float64 {
+	if k <= 0 {
+		return 1
+	}
+	term := math.Exp(-rate)
+	below := term
+	for i := 1; i < k; i++ {
+		term *= rate / float64(i)
+		below += term
+	}
+	return math.Max(0, 1-below)
+}
+
+func formatWindow(window time.Duration) string {
+	if window%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", int(window.Hours()/24))
+	}
+	return window.String()
+}
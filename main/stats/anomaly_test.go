@@ -0,0 +1,41 @@
+package stats
+
+import (
+	. "project/main/event"
+	"testing"
+	"time"
+)
+
+func TestDetectAnomaliesInSwedishTime(t *testing.T) {
+	// Three fires every evening at 22:00 in Swedish summer time, 20:00 UTC
+	var events []Event
+	day := time.Date(2023, 6, 1, 22, 0, 0, 0, time.FixedZone("", 2*60*60))
+	for ; !day.After(time.Date(2023, 7, 30, 23, 0, 0, 0, time.UTC)); day = day.AddDate(0, 0, 1) {
+		for i := 0; i < 3; i++ {
+			event := Event{Id: len(events) + 1, Type: "Brand", Datetime: day.Add(time.Duration(i) * time.Minute).Format(DatetimeLayout)}
+			event.Location.Name = "Uppsala"
+			events = append(events, event)
+		}
+	}
+	options := DefaultAnomalyOptions
+	options.Window = 3 * time.Hour
+
+	// The window from 20:00 to 23:00 in Swedish time holds the usual three fires of the evening,
+	// whatever the zone of its end
+	end := time.Date(2023, 7, 30, 21, 0, 0, 0, time.UTC)
+	for _, end := range []time.Time{end, end.In(stockholm), end.In(time.FixedZone("", -5*60*60))} {
+		if anomalies := DetectAnomalies(events, end, options); len(anomalies) != 0 {
+			t.Errorf("DetectAnomalies(%v) = %v, want none", end, anomalies)
+		}
+	}
+	// Three fires in the morning are unusual
+	for i := 0; i < 3; i++ {
+		event := Event{Id: 1000 + i, Type: "Brand", Datetime: "2023-07-31 08:0" + string(rune('0'+i)) + ":00 +02:00"}
+		event.Location.Name = "Uppsala"
+		events = append(events, event)
+	}
+	anomalies := DetectAnomalies(events, time.Date(2023, 7, 31, 7, 0, 0, 0, time.UTC), options)
+	if len(anomalies) != 1 || anomalies[0].Observed != 3 {
+		t.Errorf("DetectAnomalies() = %v, want the three fires of the morning", anomalies)
+	}
+}
@@ -0,0 +1,159 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	. "project/main/event"
+	"sort"
+)
+
+// HotspotOptions are the parameters of the DBSCAN clustering
+type HotspotOptions struct {
+	// RadiusKm is the distance within which events are neighbours
+	RadiusKm float64
+	// MinEvents is the fewest events within RadiusKm of an event for it to be the core of a hotspot
+	MinEvents int
+}
+
+// MaxClusterRadiusKm is the largest RadiusKm, a hotspot is a neighbourhood and not a region
+const MaxClusterRadiusKm = 50
+
+// Validate returns an error if the radius is not between 0 and MaxClusterRadiusKm or MinEvents is less than one
+func (o HotspotOptions) Validate() error {
+	if !(o.RadiusKm > 0 && o.RadiusKm <= MaxClusterRadiusKm) {
+		return fmt.Errorf("invalid cluster radius %v km, expected more than 0 and at most %d km", o.RadiusKm, MaxClusterRadiusKm)
+	}
+	if o.MinEvents < 1 {
+		return fmt.Errorf("invalid minimum of %d events, expected at least 1", o.MinEvents)
+	}
+	return nil
+}
+
+// DefaultHotspotOptions finds groups of at least five events within two kilometres of each other
+var DefaultHotspotOptions = HotspotOptions{RadiusKm: 2, MinEvents: 5}
+
+// Hotspot is a cluster of events. Lat and Lon are the centroid of the events and RadiusKm is
+// the distance from the centroid to the furthest event.
+type Hotspot struct {
+	Lat      float64
+	Lon      float64
+	RadiusKm float64
+	Events   []Event
+}
+
+// Count returns the number of events in the hotspot
+func (h Hotspot) Count() int {
+	return len(h.Events)
+}
+
+// Hotspots clusters the events with coordinates with DBSCAN, filter the events first to find the hotspots
+// of a type or a period. Events that are not close to enough other events are not in a hotspot.
+// The hotspots with the most events come first.
+func Hotspots(events []Event, options HotspotOptions) []Hotspot {
+	// polisen.se places many events at the same point, for example the centre of a municipality, so the
+	// events are clustered by their distinct points. The events at a point are always neighbours of each other.
+	var points [][]Event
+	pointOf := make(map[[2]float64]int)
+	for _, event := range events {
+		lat, lon, ok := event.Coordinates()
+		if !ok {
+			continue
+		}
+		key := [2]float64{lat, lon}
+		i, ok := pointOf[key]
+		if !ok {
+			i = len(points)
+			pointOf[key] = i
+			points = append(points, nil)
+		}
+		points[i] = append(points[i], event)
+	}
+	first := make([]Event, len(points))
+	position := make(map[int]int, len(points))
+	for i, point := range points {
+		first[i] = point[0]
+		position[point[0].Id] = i
+	}
+	index := NewSpatialIndex(first)
+	// neighbours returns the points within the radius of point i and the number of events at them
+	neighbours := func(i int) ([]int, int) {
+		lat, lon, _ := first[i].Coordinates()
+		var result []int
+		count := 0
+		for _, event := range index.WithinRadius(Circle{Lat: lat, Lon: lon, RadiusKm: options.RadiusKm}) {
+			result = append(result, position[event.Id])
+			count += len(points[position[event.Id]])
+		}
+		return result, count
+	}
+
+	const noise = -1
+	cluster := make([]int, len(points))
+	clusters := 0
+	for i := range points {
+		if cluster[i] != 0 {
+			continue
+		}
+		seeds, count := neighbours(i)
+		if count < options.MinEvents {
+			cluster[i] = noise
+			continue
+		}
+		clusters++
+		cluster[i] = clusters
+		// A point is queued once, it joins the cluster when it is queued
+		var queue []int
+		add := func(points []int) {
+			for _, j := range points {
+				switch cluster[j] {
+				case 0:
+					cluster[j] = clusters
+					queue = append(queue, j)
+				case noise:
+					// A border point, it is reachable from a core point but has too few neighbours itself
+					cluster[j] = clusters
+				}
+			}
+		}
+		add(seeds)
+		for len(queue) > 0 {
+			j := queue[0]
+			queue = queue[1:]
+			if more, count := neighbours(j); count >= options.MinEvents {
+				add(more)
+			}
+		}
+	}
+
+	hotspots := make([]Hotspot, clusters)
+	for _, event := range events {
+		lat, lon, ok := event.Coordinates()
+		if !ok {
+			continue
+		}
+		if c := cluster[pointOf[[2]float64{lat, lon}]]; c > 0 {
+			hotspots[c-1].Events = append(hotspots[c-1].Events, event)
+		}
+	}
+	for i := range hotspots {
+		hotspots[i].locate()
+	}
+	sort.SliceStable(hotspots, func(i, j int) bool {
+		return hotspots[i].Count() > hotspots[j].Count()
+	})
+	return hotspots
+}
+
+// locate computes the centroid and the radius of the hotspot from its events
+func (h *Hotspot) locate() {
+	for _, event := range h.Events {
+		lat, lon, _ := event.Coordinates()
+		h.Lat += lat / float64(len(h.Events))
+		h.Lon += lon / float64(len(h.Events))
+	}
+	h.RadiusKm = 0
+	for _, event := range h.Events {
+		lat, lon, _ := event.Coordinates()
+		h.RadiusKm = math.Max(h.RadiusKm, Distance(h.Lat, h.Lon, lat, lon))
+	}
+}
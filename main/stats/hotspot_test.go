@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"fmt"
+	. "project/main/event"
+	"runtime"
+	"testing"
+)
+
+// eventsAt returns n events at the position, the ids start at firstID
+func eventsAt(firstID int, n int, lat, lon float64) []Event {
+	events := make([]Event, n)
+	for i := range events {
+		events[i].Id = firstID + i
+		events[i].Location.Gps = fmt.Sprintf("%.6f,%.6f", lat, lon)
+	}
+	return events
+}
+
+func TestHotspotsOfColocatedEvents(t *testing.T) {
+	// Every event of a municipality without a better position is placed at its centre
+	var events []Event
+	events = append(events, eventsAt(1, 4000, 59.858, 17.645)...)
+	events = append(events, eventsAt(5001, 10, 59.329, 18.069)...)
+	events = append(events, eventsAt(6001, 3, 63.825, 20.263)...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	hotspots := Hotspots(events, DefaultHotspotOptions)
+	runtime.ReadMemStats(&after)
+
+	if len(hotspots) != 2 || hotspots[0].Count() != 4000 || hotspots[1].Count() != 10 {
+		counts := make([]int, len(hotspots))
+		for i, hotspot := range hotspots {
+			counts[i] = hotspot.Count()
+		}
+		t.Fatalf("hotspots of %v events, want 4000 and 10", counts)
+	}
+	if hotspots[0].RadiusKm > 1e-6 || hotspots[0].Events[0].Id != 1 || hotspots[0].Events[3999].Id != 4000 {
+		t.Errorf("the first hotspot has the radius %v and the events %d...%d", hotspots[0].RadiusKm,
+			hotspots[0].Events[0].Id, hotspots[0].Events[3999].Id)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("clustering 4013 events allocated %d MB", allocated>>20)
+	}
+}
+
+func TestHotspotsReachBorderEvents(t *testing.T) {
+	// The points are 1.5 km apart along a meridian, the radius is 2 km
+	const step = 1.5 / 111.195
+	var events []Event
+	events = append(events, eventsAt(1, 5, 59.0, 17.0)...)
+	events = append(events, eventsAt(10, 1, 59.0+step, 17.0)...)
+	events = append(events, eventsAt(20, 1, 59.0+2*step, 17.0)...)
+	events = append(events, eventsAt(30, 1, 59.0+4*step, 17.0)...)
+	// Without coordinates
+	events = append(events, Event{Id: 40})
+
+	hotspots := Hotspots(events, DefaultHotspotOptions)
+	if len(hotspots) != 1 || hotspots[0].Count() != 7 {
+		t.Fatalf("%d hotspots, want one of the 5 core events, the core event 10 and the border event 20", len(hotspots))
+	}
+	for _, event := range hotspots[0].Events {
+		if event.Id == 30 || event.Id == 40 {
+			t.Errorf("the event %d is in the hotspot", event.Id)
+		}
+	}
+}
+
+func TestHotspotOptionsValidate(t *testing.T) {
+	for _, options := range []HotspotOptions{
+		{RadiusKm: 0, MinEvents: 5},
+		{RadiusKm: -1, MinEvents: 5},
+		{RadiusKm: 10000, MinEvents: 5},
+		{RadiusKm: 2, MinEvents: 0},
+	} {
+		if options.Validate() == nil {
+			t.Errorf("%+v is valid", options)
+		}
+	}
+	if err := (HotspotOptions{RadiusKm: MaxClusterRadiusKm, MinEvents: 1}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,136 @@
+// This package aggregates events into counts that are used by the
+// dashboard and by reports, for example events per type or per day
+package stats
+
+import (
+	. "project/main/event"
+	"sort"
+	"time"
+)
+
+// Count is the number of events that share a key, such as a type or a location
+type Count struct {
+	Key   string
+	Value int
+}
+
+// CountByType counts the events per type, sorted with the most common type first
+func CountByType(events []Event) []Count {
+	return countBy(events, func(event Event) string {
+		return event.Type
+	})
+}
+
+// CountByCategory counts the events per category of the taxonomy, the keys are the category labels
+func CountByCategory(events []Event) []Count {
+	return countBy(events, func(event Event) string {
+		return event.Category().Label
+	})
+}
+
+// CountByLocation counts the events per location name, sorted with the most common location first
+func CountByLocation(events []Event) []Count {
+	return countBy(events, func(event Event) string {
+		return event.Location.Name
+	})
+}
+
+// CountByCounty counts the events per län, events whose location could not be resolved are not counted
+func CountByCounty(events []Event) []Count {
+	var resolved []Event
+	for _, event := range events {
+		if event.Region().County != nil {
+			resolved = append(resolved, event)
+		}
+	}
+	return countBy(resolved, func(event Event) string {
+		return event.Region().County.Name
+	})
+}
+
+// CountByMunicipality counts the events per kommun. Events that are only known by their county are
+// not counted, events with a free-form location are counted in the kommun their coordinates fall in.
+func CountByMunicipality(events []Event) []Count {
+	var resolved []Event
+	for _, event := range events {
+		if event.Region().Municipality != nil {
+			resolved = append(resolved, event)
+		}
+	}
+	return countBy(resolved, func(event Event) string {
+		return event.Region().Municipality.Name
+	})
+}
+
+// Top returns at most n of the given counts
+func Top(counts []Count, n int) []Count {
+	if len(counts) > n {
+		return counts[:n]
+	}
+	return counts
+}
+
+// PerDay counts the events per calendar day, in the time zone of the events.
+// The days are sorted chronologically and days without events are included with a zero count.
+func PerDay(events []Event) []Count {
+	if len(events) == 0 {
+		return nil
+	}
+	days := make(map[string]int)
+	var first, last time.Time
+	for i, event := range events {
+		t := event.Time()
+		days[t.Format("2006-01-02")]++
+		// The calendar day of the event in its own offset, as a midnight in one location so that
+		// days of events on either side of a change to or from daylight saving time compare correctly
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		if i == 0 || day.Before(first) {
+			first = day
+		}
+		if i == 0 || day.After(last) {
+			last = day
+		}
+	}
+
+	var counts []Count
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		counts = append(counts, Count{Key: key, Value: days[key]})
+	}
+	return counts
+}
+
+// HourWeekday counts the events per weekday and hour of day.
+// The first index is the weekday starting with Monday, the second is the hour.
+func HourWeekday(events []Event) [7][24]int {
+	var grid [7][24]int
+	for _, event := range events {
+		t := event.Time()
+		grid[Weekday(t)][t.Hour()]++
+	}
+	return grid
+}
+
+// Weekday returns the weekday of t as an index where Monday is 0 and Sunday is 6
+func Weekday(t time.Time) int {
+	return (int(t.Weekday()) + 6) % 7
+}
+
+// countBy counts the events per key and sorts the result by descending count, ties by key
+func countBy(events []Event, key func(Event) string) []Count {
+	occurrences := make(map[string]int)
+	for _, event := range events {
+		occurrences[key(event)]++
+	}
+	counts := make([]Count, 0, len(occurrences))
+	for k, v := range occurrences {
+		counts = append(counts, Count{Key: k, Value: v})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Value != counts[j].Value {
+			return counts[i].Value > counts[j].Value
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	return counts
+}
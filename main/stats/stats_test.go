@@ -0,0 +1,32 @@
+package stats
+
+import (
+	. "project/main/event"
+	"testing"
+)
+
+func TestPerDayAcrossDaylightSavingTime(t *testing.T) {
+	// The clocks in Sweden went forward on 2023-03-26, the events are in different offsets
+	events := []Event{
+		{Id: 1, Datetime: "2023-03-25 23:30:00 +01:00"},
+		{Id: 2, Datetime: "2023-03-27 00:15:00 +02:00"},
+		{Id: 3, Datetime: "2023-03-27 23:59:00 +02:00"},
+	}
+	want := []Count{{"2023-03-25", 1}, {"2023-03-26", 0}, {"2023-03-27", 2}}
+	got := PerDay(events)
+	if len(got) != len(want) {
+		t.Fatalf("PerDay() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("PerDay()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPerDayOneDay(t *testing.T) {
+	got := PerDay([]Event{{Id: 1, Datetime: "2023-10-29 01:00:00 +02:00"}, {Id: 2, Datetime: "2023-10-29 23:00:00 +01:00"}})
+	if len(got) != 1 || got[0] != (Count{"2023-10-29", 2}) {
+		t.Errorf("PerDay() = %v, want one day with 2 events", got)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"project/main/duplicate"
+	. "project/main/event"
+	"project/main/tui"
+)
+
+// terminal runs the full-screen terminal program over the archived events matching the filter flags
+func terminal(args []string) {
+	flags := flag.NewFlagSet("terminal", flag.ExitOnError)
+	selection := addFilterFlags(flags)
+	flags.Parse(args)
+
+	cache, err := OpenSummaryCache(SummaryCachePath)
+	if err != nil {
+		fmt.Println("An error occurred while reading the extended summaries")
+		log.Fatal(err)
+	}
+	// The store rereads the archive when the watch command has added events and the duplicates when
+	// they have been reviewed, so reloading shows them
+	store := NewStore()
+	store.Exclude = duplicate.MergedRemover(duplicate.DecisionsPath)
+	filter := selection.Filter()
+	load := func() []Event {
+		return store.Query(filter)
+	}
+	if err := tui.New(load, cache).Run(); err != nil {
+		fmt.Println("An error occurred while running the terminal program")
+		log.Fatal(err)
+	}
+}
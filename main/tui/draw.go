@@ -0,0 +1,227 @@
+package tui
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	. "project/main/event"
+	"strconv"
+	"strings"
+)
+
+// Styles of the parts of the screen
+var (
+	barStyle      = tcell.StyleDefault.Reverse(true)
+	headerStyle   = tcell.StyleDefault.Bold(true).Underline(true)
+	selectedStyle = tcell.StyleDefault.Background(tcell.ColorNavy).Foreground(tcell.ColorWhite)
+	dimStyle      = tcell.StyleDefault.Foreground(tcell.ColorGray)
+	titleStyle    = tcell.StyleDefault.Bold(true)
+)
+
+// help lists the keys of the table
+const help = "↑↓ move  / filter  t l d i sort  o open  e extended summary  [ ] scroll  r reload  q quit"
+
+// columns of the table, the last column takes the remaining width
+var columns = []struct {
+	title string
+	width int
+}{{"Datetime", 16}, {"Type", 24}, {"Location", 20}, {"Name", 0}}
+
+// tableHeight is the number of rows of events, the detail pane gets the rest of the screen
+func (a *App) tableHeight() int {
+	_, height := a.screen.Size()
+	// The title, the filter bar, the column titles, the line above the detail pane and the help line
+	available := height - 5
+	return maxInt((available+1)/2, 1)
+}
+
+// draw draws the whole screen from the state of the program
+func (a *App) draw() {
+	a.screen.Clear()
+	width, height := a.screen.Size()
+
+	direction := "ascending"
+	if a.reverse {
+		direction = "descending"
+	}
+	title := fmt.Sprintf(" Police events: %d of %d, sorted by %s %s", len(a.visible), len(a.events), sorters[a.sorter].name, direction)
+	a.fill(0, barStyle, width)
+	a.text(0, 0, width, title, barStyle)
+
+	label := " Filter: "
+	a.text(0, 1, width, label, titleStyle)
+	if a.query == "" && !a.editing {
+		a.text(len(label), 1, width-len(label), "press / to filter by words in the name, type, location or summary", dimStyle)
+	} else {
+		end := a.text(len(label), 1, width-len(label), a.query, tcell.StyleDefault)
+		if a.editing {
+			a.screen.ShowCursor(end, 1)
+		}
+	}
+	if !a.editing {
+		a.screen.HideCursor()
+	}
+
+	a.drawTable(2, width)
+	detailTop := 3 + a.tableHeight()
+	for x := 0; x < width; x++ {
+		a.screen.SetContent(x, detailTop, '─', nil, dimStyle)
+	}
+	a.drawDetail(detailTop+1, height-detailTop-2, width)
+
+	a.fill(height-1, barStyle, width)
+	if a.status != "" {
+		a.text(0, height-1, width, " "+a.status, barStyle)
+	} else {
+		a.text(0, height-1, width, " "+help, barStyle)
+	}
+	a.screen.Show()
+}
+
+// drawTable draws the column titles on row top and the visible part of the table below them
+func (a *App) drawTable(top int, width int) {
+	rows := a.tableHeight()
+	// Scroll so that the selected event is shown
+	if a.selected < a.offset {
+		a.offset = a.selected
+	}
+	if a.selected >= a.offset+rows {
+		a.offset = a.selected - rows + 1
+	}
+	a.drawRow(top, width, headerStyle, columnTitles())
+	for row := 0; row < rows && a.offset+row < len(a.visible); row++ {
+		i := a.offset + row
+		event := a.visible[i]
+		style := tcell.StyleDefault
+		if i == a.selected {
+			style = selectedStyle
+			a.fill(top+1+row, style, width)
+		}
+		datetime := event.Time().Format("2006-01-02 15:04")
+		a.drawRow(top+1+row, width, style, []string{datetime, event.Type, event.Location.Name, event.Name})
+	}
+	if len(a.visible) == 0 {
+		a.text(1, top+1, width-1, "No events match the filter", dimStyle)
+	}
+}
+
+func columnTitles() []string {
+	titles := make([]string, len(columns))
+	for i, column := range columns {
+		titles[i] = column.title
+	}
+	return titles
+}
+
+// drawRow draws the cells of a row in the columns of the table
+func (a *App) drawRow(y int, width int, style tcell.Style, cells []string) {
+	x := 1
+	for i, column := range columns {
+		cellWidth := column.width
+		if cellWidth == 0 || x+cellWidth > width {
+			cellWidth = width - x
+		}
+		if cellWidth <= 0 {
+			return
+		}
+		a.text(x, y, cellWidth, cells[i], style)
+		x += cellWidth + 2
+	}
+}
+
+// drawDetail draws the selected event in the rows from top, at most height rows
+func (a *App) drawDetail(top int, height int, width int) {
+	event, ok := a.current()
+	if !ok || height <= 0 {
+		return
+	}
+	type line struct {
+		text  string
+		style tcell.Style
+	}
+	lines := []line{
+		{event.Name, titleStyle},
+		{strings.Join([]string{event.Type, event.Location.Name, event.Datetime, "id " + strconv.Itoa(event.Id)}, " · "), dimStyle},
+		{PageURL(event.Url), dimStyle},
+		{"", tcell.StyleDefault},
+	}
+	for _, text := range wrap(event.Summary, width-2) {
+		lines = append(lines, line{text, tcell.StyleDefault})
+	}
+	lines = append(lines, line{"", tcell.StyleDefault}, line{"Extended summary", headerStyle})
+	extended, cached := a.cache.Get(event)
+	switch {
+	case cached:
+		for _, paragraph := range strings.Split(extended, "\n") {
+			for _, text := range wrap(paragraph, width-2) {
+				lines = append(lines, line{text, tcell.StyleDefault})
+			}
+		}
+	case a.fetching[event.Id]:
+		lines = append(lines, line{"Fetching from polisen.se...", dimStyle})
+	case a.fetchErrs[event.Id] != nil:
+		for _, text := range wrap("Could not fetch the extended summary: "+a.fetchErrs[event.Id].Error(), width-2) {
+			lines = append(lines, line{text, dimStyle})
+		}
+	default:
+		lines = append(lines, line{"Press e to fetch it from polisen.se", dimStyle})
+	}
+
+	if a.detailOffset > len(lines)-height {
+		a.detailOffset = maxInt(len(lines)-height, 0)
+	}
+	for row := 0; row < height && a.detailOffset+row < len(lines); row++ {
+		l := lines[a.detailOffset+row]
+		a.text(1, top+row, width-2, l.text, l.style)
+	}
+}
+
+// text draws the text from x on row y, cut at width columns, and returns the column after it
+func (a *App) text(x int, y int, width int, text string, style tcell.Style) int {
+	end := x + width
+	for _, r := range text {
+		w := runewidth.RuneWidth(r)
+		if x+w > end {
+			break
+		}
+		a.screen.SetContent(x, y, r, nil, style)
+		x += w
+	}
+	return x
+}
+
+// fill paints row y in the style, for the bars and the selected row
+func (a *App) fill(y int, style tcell.Style, width int) {
+	for x := 0; x < width; x++ {
+		a.screen.SetContent(x, y, ' ', nil, style)
+	}
+}
+
+// wrap breaks the text into lines of at most width columns at the spaces
+func wrap(text string, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case runewidth.StringWidth(line)+1+runewidth.StringWidth(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+		for runewidth.StringWidth(line) > width {
+			head := runewidth.Truncate(line, width, "")
+			lines = append(lines, head)
+			line = line[len(head):]
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,319 @@
+// This package is the full-screen terminal program, a table of the archived events with a live filter
+// bar and a detail pane with the summary and the extended summary of the selected event. It only needs
+// a terminal, so it also works over SSH where the graphical application can not run.
+package tui
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/pkg/browser"
+	"io"
+	"os"
+	. "project/main/event"
+	"sort"
+	"strings"
+)
+
+// sorter is one of the orders of the table, chosen by its key
+type sorter struct {
+	key   rune
+	name  string
+	order func([]Event) sort.Interface
+}
+
+// sorters are the four orders of the event package
+var sorters = []sorter{
+	{'t', "type", func(events []Event) sort.Interface { return ByType(events) }},
+	{'l', "location", func(events []Event) sort.Interface { return ByLocation(events) }},
+	{'d', "datetime", func(events []Event) sort.Interface { return ByDatetime(events) }},
+	{'i', "id", func(events []Event) sort.Interface { return ById(events) }},
+}
+
+// App is the state of the terminal program
+type App struct {
+	screen tcell.Screen
+	load   func() []Event
+	cache  *SummaryCache
+	// clipboard is the terminal, the addresses are copied with OSC 52 escape sequences written to it
+	clipboard io.Writer
+
+	events  []Event
+	visible []Event
+	query   string
+	editing bool
+	sorter  int
+	reverse bool
+
+	selected int
+	offset   int
+	// detailOffset is the first line of the detail pane that is shown
+	detailOffset int
+	status       string
+
+	fetching  map[int]bool
+	fetchErrs map[int]error
+}
+
+// summaryFetched is posted to the event loop when an extended summary has been scraped
+type summaryFetched struct {
+	tcell.EventTime
+	id  int
+	err error
+}
+
+// New creates the program, load returns the events to show and is called again when the user reloads.
+// The extended summaries are read from the cache and scraped into it when the user asks for them.
+func New(load func() []Event, cache *SummaryCache) *App {
+	app := &App{load: load, cache: cache, clipboard: os.Stdout, sorter: 2, reverse: true, fetching: make(map[int]bool), fetchErrs: make(map[int]error)}
+	app.events = load()
+	app.refresh()
+	return app
+}
+
+// Run shows the program in the terminal until the user quits
+func (a *App) Run() error {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	if err := screen.Init(); err != nil {
+		return err
+	}
+	defer screen.Fini()
+	return a.run(screen)
+}
+
+// run is the event loop of the program on an initialised screen
+func (a *App) run(screen tcell.Screen) error {
+	a.screen = screen
+	// The browser prints the output of the command that opens the page, which would break the screen
+	browser.Stdout, browser.Stderr = io.Discard, io.Discard
+	for {
+		a.draw()
+		switch ev := screen.PollEvent().(type) {
+		case nil:
+			return nil
+		case *tcell.EventResize:
+			screen.Sync()
+		case *summaryFetched:
+			delete(a.fetching, ev.id)
+			if ev.err != nil {
+				a.fetchErrs[ev.id] = ev.err
+			}
+		case *tcell.EventKey:
+			if ev.Key() == tcell.KeyCtrlC {
+				return nil
+			}
+			if a.editing {
+				a.editFilter(ev)
+			} else if quit := a.handleKey(ev); quit {
+				return nil
+			}
+		}
+	}
+}
+
+// editFilter handles a key while the filter bar has the focus, the table follows every change
+func (a *App) editFilter(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEnter, tcell.KeyDown, tcell.KeyTab:
+		a.editing = false
+	case tcell.KeyEscape:
+		a.editing = false
+		a.query = ""
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if runes := []rune(a.query); len(runes) > 0 {
+			a.query = string(runes[:len(runes)-1])
+		}
+	case tcell.KeyCtrlU:
+		a.query = ""
+	case tcell.KeyRune:
+		a.query += string(ev.Rune())
+	default:
+		return
+	}
+	a.refresh()
+}
+
+// handleKey handles a key while the table has the focus, quit is true when the user quits
+func (a *App) handleKey(ev *tcell.EventKey) (quit bool) {
+	a.status = ""
+	switch ev.Key() {
+	case tcell.KeyUp:
+		a.move(-1)
+	case tcell.KeyDown:
+		a.move(1)
+	case tcell.KeyPgUp:
+		a.move(-a.tableHeight())
+	case tcell.KeyPgDn:
+		a.move(a.tableHeight())
+	case tcell.KeyHome:
+		a.move(-len(a.visible))
+	case tcell.KeyEnd:
+		a.move(len(a.visible))
+	case tcell.KeyEnter:
+		a.open()
+	case tcell.KeyEscape:
+		if a.query != "" {
+			a.query = ""
+			a.refresh()
+		}
+	case tcell.KeyRune:
+		switch r := ev.Rune(); r {
+		case 'q':
+			return true
+		case 'k':
+			a.move(-1)
+		case 'j':
+			a.move(1)
+		case 'g':
+			a.move(-len(a.visible))
+		case 'G':
+			a.move(len(a.visible))
+		case '/':
+			a.editing = true
+		case 'o':
+			a.open()
+		case 'e':
+			a.fetch()
+		case 'r':
+			a.events = a.load()
+			a.refresh()
+			a.status = fmt.Sprintf("Reloaded %d events", len(a.events))
+		case '[':
+			a.detailOffset = maxInt(a.detailOffset-1, 0)
+		case ']':
+			a.detailOffset++
+		default:
+			for i, s := range sorters {
+				if s.key == r {
+					// Choosing the current order again reverses it
+					a.reverse = i == a.sorter && !a.reverse
+					a.sorter = i
+					a.refresh()
+				}
+			}
+		}
+	}
+	return false
+}
+
+// refresh applies the filter and the order to the events and keeps the selected event selected if it is still shown
+func (a *App) refresh() {
+	id := -1
+	if current, ok := a.current(); ok {
+		id = current.Id
+	}
+	a.visible = filterEvents(a.events, a.query)
+	order := sorters[a.sorter].order(a.visible)
+	if a.reverse {
+		order = sort.Reverse(order)
+	}
+	sort.Stable(order)
+	a.selected = 0
+	for i, event := range a.visible {
+		if event.Id == id {
+			a.selected = i
+		}
+	}
+	a.detailOffset = 0
+}
+
+// current returns the selected event
+func (a *App) current() (Event, bool) {
+	if a.selected < 0 || a.selected >= len(a.visible) {
+		return Event{}, false
+	}
+	return a.visible[a.selected], true
+}
+
+// move moves the selection by delta rows, within the table
+func (a *App) move(delta int) {
+	a.selected += delta
+	if a.selected >= len(a.visible) {
+		a.selected = len(a.visible) - 1
+	}
+	if a.selected < 0 {
+		a.selected = 0
+	}
+	a.detailOffset = 0
+}
+
+// open opens the page of the selected event in the browser. Over SSH a browser would open on the server,
+// if there is one, so the address is instead copied to the clipboard of the terminal and shown in the status line.
+func (a *App) open() {
+	event, ok := a.current()
+	if !ok {
+		return
+	}
+	url := PageURL(event.Url)
+	if !overSSH() {
+		if err := browser.OpenURL(url); err == nil {
+			a.status = "Opened " + url
+			return
+		}
+	}
+	if _, err := io.WriteString(a.clipboard, osc52(url)); err != nil {
+		a.status = "The page is " + url
+		return
+	}
+	a.status = "Copied to the clipboard: " + url
+}
+
+// overSSH reports whether the program runs in an SSH session
+func overSSH() bool {
+	return os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != ""
+}
+
+// osc52 returns the escape sequence that asks the terminal to put the text on its clipboard. Most terminals
+// support it, also when the program runs on another machine over SSH.
+func osc52(text string) string {
+	return "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+}
+
+// fetch scrapes the extended summary of the selected event in the background
+func (a *App) fetch() {
+	event, ok := a.current()
+	if !ok || a.fetching[event.Id] {
+		return
+	}
+	if _, ok := a.cache.Get(event); ok {
+		return
+	}
+	a.fetching[event.Id] = true
+	delete(a.fetchErrs, event.Id)
+	go func() {
+		_, err := a.cache.Fetch(event)
+		message := &summaryFetched{id: event.Id, err: err}
+		message.SetEventNow()
+		a.screen.PostEvent(message)
+	}()
+}
+
+// filterEvents returns the events that contain every word of the query in their name, type, location or summary
+func filterEvents(events []Event, query string) []Event {
+	words := strings.Fields(strings.ToLower(query))
+	var matching []Event
+	for _, event := range events {
+		text := strings.ToLower(strings.Join([]string{event.Name, event.Type, event.Location.Name, event.Summary}, " "))
+		matches := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			matching = append(matching, event)
+		}
+	}
+	return matching
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
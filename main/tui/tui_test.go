@@ -0,0 +1,39 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	. "project/main/event"
+	"strings"
+	"testing"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("no terminal")
+}
+
+func TestOpenOverSSHCopiesTheAddress(t *testing.T) {
+	t.Setenv("SSH_CONNECTION", "192.0.2.1 50000 192.0.2.2 22")
+	event := Event{Id: 1, Name: "25 april 21:57, Brand, Lessebo", Url: "/aktuellt/handelser/2023/april/25/25-april-2157-brand-lessebo/"}
+	app := New(func() []Event { return []Event{event} }, nil)
+	var clipboard bytes.Buffer
+	app.clipboard = &clipboard
+
+	app.open()
+	url := PageURL(event.Url)
+	if want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(url)) + "\a"; clipboard.String() != want {
+		t.Errorf("the terminal got %q, want %q", clipboard.String(), want)
+	}
+	if !strings.Contains(app.status, "clipboard") || !strings.HasSuffix(app.status, url) {
+		t.Errorf("status %q, want the copied address", app.status)
+	}
+
+	app.clipboard = failingWriter{}
+	app.open()
+	if app.status != "The page is "+url {
+		t.Errorf("status %q, want the address", app.status)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	. "project/main/alert"
+	. "project/main/event"
+	"project/main/metrics"
+	"time"
+)
+
+// watch runs until the program is stopped. Every interval it fetches the events from the API, extracts
+// their facts, saves the merged events in the archive, evaluates the new events against the alert rules
+// and checks the archive for anomalous event rates. With -mqtt the new events are also published to a broker.
+// With -metrics the fetches and the archive are exposed to Prometheus on /metrics, and /healthz fails when
+// no fetch has succeeded within -max-fetch-age.
+func watch(args []string) {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	interval := flags.Duration("interval", 5*time.Minute, "time between two fetches")
+	alertsPath := flags.String("alerts", DefaultConfigPath, "alert configuration file")
+	broker := addMQTTFlags(flags)
+	metricsAddr := flags.String("metrics", "", "address /metrics and /healthz are served on, for example :9100")
+	maxFetchAge := flags.Duration("max-fetch-age", 0, "age of the last successful fetch at which /healthz fails, 3 intervals if 0")
+	flags.Parse(args)
+
+	engine := loadAlertEngine(*alertsPath)
+	summaries, err := OpenSummaryCache(SummaryCachePath)
+	if err != nil {
+		log.Println("The cached summaries are not used for the facts of the events:", err)
+	}
+	publisher := broker.Publisher()
+	poller := metrics.NewPoller(summaries)
+	if *metricsAddr != "" {
+		if *maxFetchAge == 0 {
+			*maxFetchAge = 3 * *interval
+		}
+		go func() {
+			if err := poller.Serve(*metricsAddr, *maxFetchAge); err != nil {
+				fmt.Println("An error occurred while serving the metrics")
+				log.Fatal(err)
+			}
+		}()
+	}
+	eventsInArchive := GetArchive()
+	for {
+		engine.Retry()
+		start := time.Now()
+		fetchedEvents, err := FetchNewEvents()
+		poller.ObserveFetch(time.Since(start), len(fetchedEvents), err)
+		if err != nil {
+			log.Println("Fetching events failed:", err)
+		} else {
+			EnrichFacts(fetchedEvents, summaries)
+			newEvents := NewEvents(eventsInArchive, fetchedEvents)
+			logNewKeys(eventsInArchive, newEvents)
+			var duplicates int
+			eventsInArchive, duplicates = MergeEvents(eventsInArchive, fetchedEvents)
+			poller.ObserveMerge(len(newEvents), duplicates, eventsInArchive)
+			if len(newEvents) > 0 {
+				SaveInArchive(eventsInArchive)
+				engine.Evaluate(newEvents)
+				engine.EvaluateAnomalies(eventsInArchive)
+				if publisher != nil {
+					if err := publisher.Publish(newEvents); err != nil {
+						log.Println("Publishing the new events to MQTT failed:", err)
+					}
+				}
+			}
+			log.Println("Fetched", len(fetchedEvents), "events,", len(newEvents), "new")
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// logNewKeys logs the types and locations that appear for the first time in the archive
+// and are missing from the curated TypeKeys or the gazetteer
+func logNewKeys(eventsInArchive []Event, newEvents []Event) {
+	types, locations := NewKeys(eventsInArchive, newEvents)
+	for _, eventType := range types {
+		if !IsKnownType(eventType) {
+			log.Println("Discovered an unknown event type:", eventType)
+		}
+	}
+	for _, location := range locations {
+		if !IsKnownLocation(location) {
+			log.Println("Discovered a location that is not a kommun or län:", location)
+		}
+	}
+}
+
+// loadAlertEngine creates the alert engine from the configuration file, the program
+// runs without alerts if the file does not exist
+func loadAlertEngine(path string) *Engine {
+	config, err := LoadConfig(path)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Println("An error occurred while reading the alert rules")
+		log.Fatal(err)
+	}
+	engine, err := NewEngine(config, nil)
+	if err != nil {
+		fmt.Println("An error occurred while reading the alert rules")
+		log.Fatal(err)
+	}
+	return engine
+}